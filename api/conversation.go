@@ -0,0 +1,179 @@
+package api
+
+import (
+	"ai-studio/orchestrator/llm"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultChatModel  = "mistral:7b-instruct-v0.2-q4_K_M"
+	chatContextTokens = 8192 // Context window requested for chat turns (Ollama num_ctx)
+	chatReplyTokens   = 1024 // Part of the window kept free for the reply
+	conversationsDir  = "./conversations"
+	discoverDir       = "./discovery" // Discovery sessions, one JSON file each
+)
+
+// Conversation represents a chat conversation
+// The full message history is kept and persisted; only the prompt sent to the
+// model is trimmed to fit the context window.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Messages  []Message `json:"messages"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	mu sync.Mutex // Serializes turns, so each reply sees the previous one
+}
+
+// Message represents a chat message
+type Message struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"` // Model that wrote an assistant message
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ConversationSummary is a conversation without its messages, for listings
+type ConversationSummary struct {
+	ID           string    `json:"id"`
+	Model        string    `json:"model"`
+	MessageCount int       `json:"message_count"`
+	Preview      string    `json:"preview"` // Start of the first user message
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// summary returns the listing entry for a conversation
+func (c *Conversation) summary() ConversationSummary {
+	preview := ""
+	for _, msg := range c.Messages {
+		if msg.Role == llm.RoleUser {
+			preview = msg.Content
+			break
+		}
+	}
+	if len(preview) > 80 {
+		preview = preview[:80] + "..."
+	}
+
+	return ConversationSummary{
+		ID:           c.ID,
+		Model:        c.Model,
+		MessageCount: len(c.Messages),
+		Preview:      preview,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+}
+
+// chatHistory builds the messages for the next model call: the system prompt
+// followed by as much recent history as fits the context window
+func (c *Conversation) chatHistory(systemPrompt string) []llm.ChatMessage {
+	budget := chatContextTokens - chatReplyTokens - estimateTokens(systemPrompt)
+	recent := trimHistory(c.Messages, budget)
+	if dropped := len(c.Messages) - len(recent); dropped > 0 {
+		log.Printf("Chat: conversation %s trimmed %d older messages to fit the context window", c.ID, dropped)
+	}
+
+	history := make([]llm.ChatMessage, 0, len(recent)+1)
+	history = append(history, llm.ChatMessage{Role: llm.RoleSystem, Content: systemPrompt})
+	for _, msg := range recent {
+		history = append(history, llm.ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return history
+}
+
+// trimHistory returns the most recent messages whose estimated size fits budget
+// The latest message is always kept, and the result never starts with an
+// assistant reply whose question was trimmed away.
+func trimHistory(messages []Message, budget int) []Message {
+	start := len(messages)
+	used := 0
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		used += estimateTokens(messages[i].Content)
+		if used > budget && i < len(messages)-1 {
+			break
+		}
+		start = i
+	}
+
+	for start < len(messages)-1 && messages[start].Role != llm.RoleUser {
+		start++
+	}
+
+	return messages[start:]
+}
+
+// estimateTokens approximates a message's token count (about 4 characters per
+// token for English, plus a few tokens of per-message overhead)
+func estimateTokens(text string) int {
+	return len(text)/4 + 4
+}
+
+// saveConversation writes a conversation to dir atomically
+func saveConversation(dir string, conv *Conversation) error {
+	data, err := json.MarshalIndent(conv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+
+	path := filepath.Join(dir, conv.ID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to commit conversation: %w", err)
+	}
+
+	return nil
+}
+
+// loadConversations reads every conversation saved in dir
+// Unreadable files are logged and skipped, so one bad file doesn't lose the rest
+func loadConversations(dir string) map[string]*Conversation {
+	conversations := make(map[string]*Conversation)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return conversations
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Chat: failed to read conversation %s: %v", file, err)
+			continue
+		}
+
+		var conv Conversation
+		if err := json.Unmarshal(data, &conv); err != nil || conv.ID == "" {
+			log.Printf("Chat: failed to parse conversation %s: %v", file, err)
+			continue
+		}
+		if conv.Model == "" {
+			conv.Model = defaultChatModel
+		}
+
+		conversations[conv.ID] = &conv
+	}
+
+	if len(conversations) > 0 {
+		log.Printf("Chat: loaded %d conversations from %s", len(conversations), dir)
+	}
+
+	return conversations
+}
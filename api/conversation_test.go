@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-studio/orchestrator/llm"
+	ws "ai-studio/orchestrator/websocket"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestEstimateTokens tests the per-message token estimate
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 4},
+		{"abc", 4},
+		{"abcd", 5},
+		{strings.Repeat("x", 400), 104},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%d chars) = %d, want %d", len(tt.text), got, tt.want)
+		}
+	}
+}
+
+// TestTrimHistory tests budget edges and that trimmed history starts with a user message
+func TestTrimHistory(t *testing.T) {
+	// Each message is 36 characters, an estimated 13 tokens
+	msg := func(role string) Message { return Message{Role: role, Content: strings.Repeat("m", 36)} }
+	user, assistant := msg(llm.RoleUser), msg(llm.RoleAssistant)
+	history := []Message{user, assistant, user, assistant, user}
+
+	tests := []struct {
+		name     string
+		messages []Message
+		budget   int
+		want     int // Messages kept, counted from the end
+	}{
+		{"empty", nil, 100, 0},
+		{"everything fits", history, 65, 5},
+		{"one token short", history, 64, 3}, // Four fit, but that would start with a reply
+		{"exactly three", history, 39, 3},
+		{"latest always kept", history, 0, 1},
+		{"reply without its question dropped", []Message{assistant, user}, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimHistory(tt.messages, tt.budget)
+			if len(got) != tt.want {
+				t.Fatalf("kept %d messages, want %d", len(got), tt.want)
+			}
+			if len(got) > 1 && got[0].Role != llm.RoleUser {
+				t.Errorf("trimmed history starts with %s", got[0].Role)
+			}
+		})
+	}
+}
+
+// TestChatHistoryKeepsSystemPrompt tests that trimming never drops the system prompt
+func TestChatHistoryKeepsSystemPrompt(t *testing.T) {
+	conv := &Conversation{ID: "c"}
+	for i := 0; i < 200; i++ {
+		conv.Messages = append(conv.Messages,
+			Message{Role: llm.RoleUser, Content: strings.Repeat("q", 400)},
+			Message{Role: llm.RoleAssistant, Content: strings.Repeat("a", 400)})
+	}
+
+	history := conv.chatHistory("system prompt")
+	if history[0].Role != llm.RoleSystem || history[0].Content != "system prompt" {
+		t.Fatalf("first message = %+v, want the system prompt", history[0])
+	}
+	if len(history) >= len(conv.Messages)+1 {
+		t.Errorf("history was not trimmed (%d messages)", len(history))
+	}
+	if history[1].Role != llm.RoleUser {
+		t.Errorf("history after the system prompt starts with %s", history[1].Role)
+	}
+	if len(conv.Messages) != 400 {
+		t.Errorf("trimming changed the stored conversation (%d messages)", len(conv.Messages))
+	}
+}
+
+// chatProvider answers chat turns, failing for model "broken"
+type chatProvider struct{}
+
+func (chatProvider) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	if req.Model == "broken" {
+		return nil, errors.New("model not found")
+	}
+	text := "reply from " + req.Model
+	if req.OnToken != nil {
+		req.OnToken("reply from ")
+		req.OnToken(req.Model)
+	}
+	return &llm.Response{Text: text, Model: req.Model}, nil
+}
+func (chatProvider) ListModels() ([]string, error) { return nil, nil }
+func (chatProvider) Ping() error                   { return nil }
+
+// chatTaskManager is a fakeTaskManager whose client answers chat turns
+type chatTaskManager struct{ fakeTaskManager }
+
+func (chatTaskManager) GetClient() llm.Provider { return chatProvider{} }
+
+// TestChatModelSwitch tests that a requested model is only kept after it answers
+func TestChatModelSwitch(t *testing.T) {
+	s := NewServer(chatTaskManager{}, 0)
+	s.conversationsDir = t.TempDir()
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	c := jobClient{t: t, url: server.URL}
+
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+		Model          string `json:"model"`
+	}
+	if code := c.post("/chat", map[string]string{"message": "hi"}, &resp); code != http.StatusOK || resp.Model != defaultChatModel {
+		t.Fatalf("first turn: status %d, model %s", code, resp.Model)
+	}
+	id := resp.ConversationID
+
+	// A failed turn leaves the conversation on its model, without the message
+	if code := c.post("/chat", map[string]string{"conversation_id": id, "message": "hi", "model": "broken"}, nil); code != http.StatusInternalServerError {
+		t.Fatalf("turn with a broken model: status %d", code)
+	}
+	conv := s.conversations[id]
+	if conv.Model != defaultChatModel || len(conv.Messages) != 2 {
+		t.Errorf("after failed switch: model %s, %d messages", conv.Model, len(conv.Messages))
+	}
+
+	if code := c.post("/chat", map[string]string{"conversation_id": id, "message": "hi", "model": "other"}, &resp); code != http.StatusOK || resp.Model != "other" {
+		t.Fatalf("switching turn: status %d, model %s", code, resp.Model)
+	}
+	if conv.Model != "other" || conv.Messages[3].Model != "other" {
+		t.Errorf("after switch: model %s, reply by %s", conv.Model, conv.Messages[3].Model)
+	}
+}
+
+// TestChatStreamsTokens tests that chat replies reach WebSocket clients as token events, in order
+func TestChatStreamsTokens(t *testing.T) {
+	s := NewServer(chatTaskManager{}, 0)
+	s.conversationsDir = t.TempDir()
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// The hub registers the client asynchronously; broadcast until it is listening
+	registered := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-registered:
+				return
+			case <-time.After(10 * time.Millisecond):
+				s.wsHub.Broadcast(&ws.Event{Type: "sync"})
+			}
+		}
+	}()
+	var event ws.Event
+	for event.Type != "sync" {
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(registered)
+
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	c := jobClient{t: t, url: server.URL}
+	if code := c.post("/chat", map[string]string{"message": "hi", "model": "m"}, &resp); code != http.StatusOK {
+		t.Fatalf("chat: status %d", code)
+	}
+
+	var tokens []string
+	for len(tokens) < 2 {
+		var event ws.Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("after tokens %q: %v", tokens, err)
+		}
+		if event.Type != "token" {
+			continue
+		}
+		if event.ConversationID != resp.ConversationID || event.Phase != "chat" {
+			t.Errorf("token event tagged %s/%s, want %s/chat", event.ConversationID, event.Phase, resp.ConversationID)
+		}
+		tokens = append(tokens, event.Data)
+	}
+	if strings.Join(tokens, "|") != "reply from |m" {
+		t.Errorf("tokens = %q", tokens)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHistoryQueryDates tests that a date-only until covers that whole day
+func TestHistoryQueryDates(t *testing.T) {
+	tests := []struct {
+		query string
+		since time.Time
+		until time.Time
+	}{
+		{"since=2026-10-17&until=2026-10-17", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"until=2026-10-17T15:04:05Z", time.Time{}, time.Date(2026, 10, 17, 15, 4, 5, 0, time.UTC)},
+		{"", time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		q, err := historyQuery(httptest.NewRequest("GET", "/history?"+tt.query, nil))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		if !q.Since.Equal(tt.since) || !q.Until.Equal(tt.until) {
+			t.Errorf("%q: since %v, until %v; want %v, %v", tt.query, q.Since, q.Until, tt.since, tt.until)
+		}
+	}
+
+	if _, err := historyQuery(httptest.NewRequest("GET", "/history?until=yesterday", nil)); err == nil {
+		t.Error("invalid until accepted")
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"ai-studio/orchestrator/task"
+)
+
+// maxFinishedJobs bounds how many finished jobs are kept for /task/status and /task/result
+const maxFinishedJobs = 500
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a task submitted through /task/submit, run in the background
+type Job struct {
+	ID         string     `json:"job_id"`
+	TaskType   string     `json:"task_type"`
+	Status     string     `json:"status"`
+	Stage      string     `json:"stage,omitempty"`  // Current stage: requirements, generating, qa, ...
+	Detail     string     `json:"detail,omitempty"` // Stage detail, e.g. the model generating
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	result interface{} // *task.Result or *supervisor.SupervisedResult, once finished
+}
+
+// snapshot returns a copy of the job's public fields; the caller must hold jobsMux
+func (j *Job) snapshot() Job {
+	return Job{
+		ID:         j.ID,
+		TaskType:   j.TaskType,
+		Status:     j.Status,
+		Stage:      j.Stage,
+		Detail:     j.Detail,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+// handleTaskSubmit starts a task in the background and returns its job ID immediately
+func (s *Server) handleTaskSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := readTaskRequest(r)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := taskContext(req)
+	if err := s.registerTask(req.TaskID, cancel); err != nil {
+		cancel()
+		s.respondError(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	job := &Job{
+		ID:        req.TaskID,
+		TaskType:  req.TaskType,
+		Status:    JobRunning,
+		CreatedAt: time.Now(),
+	}
+	s.jobsMux.Lock()
+	if _, exists := s.jobs[job.ID]; exists {
+		// A finished job keeps its ID until pruned, so its result can't be overwritten
+		s.jobsMux.Unlock()
+		s.unregisterTask(req.TaskID)
+		cancel()
+		s.respondError(w, fmt.Sprintf("Job %s already exists", job.ID), http.StatusConflict)
+		return
+	}
+	s.jobs[job.ID] = job
+	snapshot := job.snapshot()
+	s.jobsMux.Unlock()
+
+	log.Printf("Job %s submitted: type=%s, input_length=%d", job.ID, req.TaskType, len(req.Input))
+	s.wsHub.BroadcastJSON("job_started", snapshot)
+
+	go s.runJob(ctx, cancel, job, req)
+
+	s.respondJSON(w, snapshot)
+}
+
+// runJob executes a submitted task, broadcasting its progress and outcome over WebSocket
+func (s *Server) runJob(ctx context.Context, cancel context.CancelFunc, job *Job, req *TaskRequest) {
+	defer cancel()
+	defer s.unregisterTask(job.ID)
+
+	ctx = task.WithProgress(ctx, func(stage, detail string) {
+		s.jobsMux.Lock()
+		job.Stage = stage
+		job.Detail = detail
+		snapshot := job.snapshot()
+		s.jobsMux.Unlock()
+		s.wsHub.BroadcastJSON("job_progress", snapshot)
+	})
+
+	result, err := s.taskMgr.ExecuteTask(ctx, req.TaskType, req.Input)
+
+	s.jobsMux.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Stage = ""
+	job.Detail = ""
+	job.result = result
+	switch {
+	case err == nil:
+		job.Status = JobCompleted
+	case errors.Is(err, context.Canceled):
+		job.Status = JobCancelled
+		job.Error = "Task cancelled"
+	default:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+	snapshot := job.snapshot()
+	s.pruneJobsLocked()
+	s.jobsMux.Unlock()
+
+	log.Printf("Job %s %s", snapshot.ID, snapshot.Status)
+	s.wsHub.BroadcastJSON("job_"+snapshot.Status, snapshot)
+}
+
+// pruneJobsLocked drops the oldest finished jobs beyond maxFinishedJobs; the caller must hold jobsMux
+func (s *Server) pruneJobsLocked() {
+	var finished []*Job
+	for _, job := range s.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(s.jobs, job.ID)
+	}
+}
+
+// lookupJob finds the job named by the "id" query parameter, responding with an error if there is none
+func (s *Server) lookupJob(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		s.respondError(w, "id is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	s.jobsMux.Lock()
+	job, ok := s.jobs[jobID]
+	s.jobsMux.Unlock()
+	if !ok {
+		s.respondError(w, fmt.Sprintf("Job %s not found", jobID), http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
+
+// handleTaskStatus reports a job's state and current stage
+func (s *Server) handleTaskStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	s.jobsMux.Lock()
+	snapshot := job.snapshot()
+	s.jobsMux.Unlock()
+
+	s.respondJSON(w, snapshot)
+}
+
+// handleTaskResult returns a finished job's result, as POST /task would have
+func (s *Server) handleTaskResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	s.jobsMux.Lock()
+	status, jobErr, result := job.Status, job.Error, job.result
+	s.jobsMux.Unlock()
+
+	switch status {
+	case JobRunning:
+		s.respondError(w, fmt.Sprintf("Job %s is still running", job.ID), http.StatusConflict)
+	case JobCancelled:
+		s.respondError(w, jobErr, http.StatusConflict)
+	case JobFailed:
+		s.respondError(w, jobErr, http.StatusInternalServerError)
+	default:
+		s.respondJSON(w, result)
+	}
+}
+
+// handleTaskRunning lists the jobs still running, oldest first
+func (s *Server) handleTaskRunning(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.jobsMux.Lock()
+	running := []Job{}
+	for _, job := range s.jobs {
+		if job.Status == JobRunning {
+			running = append(running, job.snapshot())
+		}
+	}
+	s.jobsMux.Unlock()
+
+	sort.Slice(running, func(i, j int) bool { return running[i].CreatedAt.Before(running[j].CreatedAt) })
+
+	s.respondJSON(w, map[string]interface{}{
+		"jobs":  running,
+		"count": len(running),
+	})
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/task"
+)
+
+// fakeTaskManager completes tasks at once, except input "block", which runs until cancelled
+type fakeTaskManager struct{}
+
+func (fakeTaskManager) ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error) {
+	if input == "block" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &task.Result{TaskType: taskType, Input: input, Output: "done: " + input}, nil
+}
+
+func (fakeTaskManager) Ping() error { return nil }
+func (fakeTaskManager) QueryHistory(q task.HistoryQuery) (task.HistoryPage, error) {
+	return task.HistoryPage{}, nil
+}
+func (fakeTaskManager) QueryArtifacts(q task.ArtifactQuery) []task.Artifact { return nil }
+func (fakeTaskManager) GetClient() llm.Provider                             { return nil }
+func (fakeTaskManager) GetWebSocketHub() interface{}                        { return nil }
+
+// jobClient drives the job endpoints of a test server
+type jobClient struct {
+	t   *testing.T
+	url string
+}
+
+// post sends a JSON body and returns the status code, decoding the response into out
+func (c jobClient) post(path string, body, out interface{}) int {
+	data, _ := json.Marshal(body)
+	resp, err := http.Post(c.url+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// get fetches a path and returns the status code, decoding the response into out
+func (c jobClient) get(path string, out interface{}) int {
+	resp, err := http.Get(c.url + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// wait polls a job's status until it leaves the running state
+func (c jobClient) wait(id string) Job {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		var job Job
+		c.get("/task/status?id="+id, &job)
+		if job.Status != JobRunning {
+			return job
+		}
+	}
+	c.t.Fatalf("job %s still running", id)
+	return Job{}
+}
+
+// TestJobLifecycle tests submit, status and result, cancellation, reused IDs and pruning of finished jobs
+func TestJobLifecycle(t *testing.T) {
+	s := NewServer(fakeTaskManager{}, 0)
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	c := jobClient{t: t, url: server.URL}
+
+	// Submit, then poll until the result is ready
+	var submitted Job
+	if code := c.post("/task/submit", TaskRequest{TaskID: "job-1", TaskType: "validate", Input: "idea"}, &submitted); code != http.StatusOK || submitted.Status != JobRunning {
+		t.Fatalf("submit = %d %+v", code, submitted)
+	}
+	if job := c.wait("job-1"); job.Status != JobCompleted || job.FinishedAt == nil {
+		t.Fatalf("job = %+v, want completed", job)
+	}
+	var result task.Result
+	if code := c.get("/task/result?id=job-1", &result); code != http.StatusOK || result.Output != "done: idea" {
+		t.Errorf("result = %d %+v", code, result)
+	}
+
+	// A finished job's ID can't be reused
+	if code := c.post("/task/submit", TaskRequest{TaskID: "job-1", TaskType: "validate", Input: "other"}, nil); code != http.StatusConflict {
+		t.Errorf("resubmitting a finished ID = %d, want %d", code, http.StatusConflict)
+	}
+	c.get("/task/result?id=job-1", &result)
+	if result.Output != "done: idea" {
+		t.Errorf("finished job's result was overwritten: %+v", result)
+	}
+
+	// Cancel a running job
+	c.post("/task/submit", TaskRequest{TaskID: "job-2", TaskType: "validate", Input: "block"}, nil)
+	if code := c.get("/task/result?id=job-2", nil); code != http.StatusConflict {
+		t.Errorf("result of a running job = %d, want %d", code, http.StatusConflict)
+	}
+	if code := c.post("/task/cancel", map[string]string{"task_id": "job-2"}, nil); code != http.StatusOK {
+		t.Errorf("cancel = %d", code)
+	}
+	if job := c.wait("job-2"); job.Status != JobCancelled {
+		t.Errorf("cancelled job = %+v", job)
+	}
+
+	// Only the newest maxFinishedJobs finished jobs are kept
+	for i := 0; i < maxFinishedJobs; i++ {
+		id := fmt.Sprintf("bulk-%d", i)
+		c.post("/task/submit", TaskRequest{TaskID: id, TaskType: "validate", Input: "x"}, nil)
+		c.wait(id)
+	}
+	if code := c.get("/task/status?id=job-1", nil); code != http.StatusNotFound {
+		t.Errorf("oldest job status = %d, want it pruned", code)
+	}
+	if code := c.get(fmt.Sprintf("/task/status?id=bulk-%d", maxFinishedJobs-1), nil); code != http.StatusOK {
+		t.Errorf("newest job status = %d", code)
+	}
+	s.jobsMux.Lock()
+	kept := len(s.jobs)
+	s.jobsMux.Unlock()
+	if kept != maxFinishedJobs {
+		t.Errorf("kept %d jobs, want %d", kept, maxFinishedJobs)
+	}
+}
+
+// TestTaskCancel tests cancelling a running task, then one that is unknown or already finished
+func TestTaskCancel(t *testing.T) {
+	s := NewServer(fakeTaskManager{}, 0)
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	c := jobClient{t: t, url: server.URL}
+
+	var resp struct {
+		TaskID string `json:"task_id"`
+		Status string `json:"status"`
+	}
+	if code := c.post("/task/cancel", map[string]string{"task_id": "nope"}, nil); code != http.StatusNotFound {
+		t.Errorf("cancelling an unknown task = %d, want %d", code, http.StatusNotFound)
+	}
+
+	c.post("/task/submit", TaskRequest{TaskID: "slow", TaskType: "validate", Input: "block"}, nil)
+	if code := c.post("/task/cancel", map[string]string{"task_id": "slow"}, &resp); code != http.StatusOK || resp.Status != "cancelling" || resp.TaskID != "slow" {
+		t.Fatalf("cancel = %d %+v, want cancelling", code, resp)
+	}
+	c.wait("slow")
+
+	// The finished task leaves the running registry (just after its status is set)
+	running := true
+	for deadline := time.Now().Add(5 * time.Second); running && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		s.tasksMux.Lock()
+		_, running = s.runningTasks["slow"]
+		s.tasksMux.Unlock()
+	}
+	if running {
+		t.Fatal("cancelled task is still registered as running")
+	}
+	if code := c.post("/task/cancel", map[string]string{"task_id": "slow"}, nil); code != http.StatusNotFound {
+		t.Errorf("cancelling a finished task = %d, want %d", code, http.StatusNotFound)
+	}
+}
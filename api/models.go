@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/project"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+)
+
+// pullEventInterval limits how often download progress is broadcast over WebSocket
+const pullEventInterval = time.Second
+
+// ModelUsage maps each referenced model to what uses it, e.g. "task:review", "agent:qa", "chat"
+type ModelUsage map[string][]string
+
+// CollectModelUsage lists every model the orchestrator may call and what calls it:
+// task models and their fallbacks, thinking-mode models, enabled supervisor agents, the Lead Agent,
+// discovery and chat
+func CollectModelUsage(cfg *config.Config, supervisorCfg *supervisor.SupervisorConfig) ModelUsage {
+	usage := make(ModelUsage)
+
+	for taskType := range cfg.Models {
+		model, _ := cfg.TaskModel(taskType)
+		usage.add(model, "task:"+taskType)
+	}
+	for taskType, tt := range cfg.TaskTypes {
+		if _, builtIn := cfg.Models[taskType]; !builtIn {
+			usage.add(tt.Model, "task:"+taskType)
+		}
+	}
+	for taskType, models := range cfg.ModelFallbacks {
+		for _, model := range models {
+			usage.add(model, "fallback:"+taskType)
+		}
+	}
+	for mode, mc := range cfg.ThinkingModes {
+		usage.add(mc.Model, "thinking:"+mode)
+	}
+
+	if supervisorCfg != nil && supervisorCfg.Enabled {
+		agents := supervisorCfg.Agents
+		for name, agent := range map[string]supervisor.AgentConfig{
+			"requirements":  agents.Requirements,
+			"qa":            agents.QA,
+			"testing":       agents.Testing,
+			"documentation": agents.Documentation,
+		} {
+			if agent.Enabled {
+				usage.add(agent.Model, "agent:"+name)
+			}
+		}
+		usage.add(supervisor.TechStackModel, "agent:techstack")
+		usage.add(supervisor.ScopeModel, "agent:scope")
+
+		if cfg.ProjectOrchestrator.Enabled {
+			usage.add(project.LeadAgentModel(cfg), "lead_agent")
+		}
+	}
+
+	usage.add(task.DiscoveryModel, "discovery")
+	usage.add(defaultChatModel, "chat")
+
+	for _, users := range usage {
+		sort.Strings(users)
+	}
+	return usage
+}
+
+// add records that user calls model
+func (u ModelUsage) add(model, user string) {
+	if model == "" {
+		return
+	}
+	u[model] = append(u[model], user)
+}
+
+// Models returns the referenced model names, sorted
+func (u ModelUsage) Models() []string {
+	models := make([]string, 0, len(u))
+	for model := range u {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// UsedBy returns what uses an installed model, matching untagged references to ":latest"
+func (u ModelUsage) UsedBy(installed string) []string {
+	var users []string
+	for model, modelUsers := range u {
+		if llm.HasModel([]string{installed}, model) {
+			users = append(users, modelUsers...)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// VerifyModels checks that every referenced model is installed, logging each missing
+// one with what uses it, and returns the missing models
+func VerifyModels(p llm.Provider, usage ModelUsage) ([]string, error) {
+	installed, err := p.ListModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed models: %w", err)
+	}
+
+	missing := llm.MissingModels(installed, usage.Models())
+	for _, model := range missing {
+		log.Printf("Warning: model %s is not installed (used by %s)", model, strings.Join(usage[model], ", "))
+	}
+	if len(missing) == 0 {
+		log.Printf("✓ All %d referenced models are installed", len(usage))
+	}
+
+	return missing, nil
+}
+
+// PullModel downloads model through p, logging each new download stage
+func PullModel(ctx context.Context, p llm.Provider, model string, onProgress llm.PullHandler) error {
+	puller, ok := p.(llm.ModelPuller)
+	if !ok {
+		return fmt.Errorf("provider cannot pull models")
+	}
+
+	log.Printf("Models: pulling %s", model)
+	lastStatus := ""
+	err := puller.PullModel(ctx, model, func(progress llm.PullProgress) {
+		if progress.Status != lastStatus {
+			lastStatus = progress.Status
+			log.Printf("Models: %s: %s", model, progress.Status)
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✓ Model %s pulled", model)
+	return nil
+}
+
+// ModelInfo describes an installed or referenced model in /models
+type ModelInfo struct {
+	Name      string   `json:"name"`
+	Digest    string   `json:"digest,omitempty"`
+	Installed bool     `json:"installed"`
+	UsedBy    []string `json:"used_by"`
+	Pulling   bool     `json:"pulling,omitempty"`
+}
+
+// SetModelUsage tells the server which models the orchestrator references, for /models
+func (s *Server) SetModelUsage(usage ModelUsage) {
+	s.pullsMux.Lock()
+	defer s.pullsMux.Unlock()
+	s.modelUsage = usage
+}
+
+// PullModels starts downloading models in the background, broadcasting
+// "model_pull" progress events over WebSocket; models already being pulled are skipped.
+// It returns the models whose pull was started.
+func (s *Server) PullModels(models []string) []string {
+	s.pullsMux.Lock()
+	var started []string
+	for _, model := range models {
+		if !s.pulls[model] {
+			s.pulls[model] = true
+			started = append(started, model)
+		}
+	}
+	s.pullsMux.Unlock()
+
+	for _, model := range started {
+		go s.pullModel(model)
+	}
+	return started
+}
+
+// pullModel downloads one model, reporting progress and the outcome over WebSocket
+func (s *Server) pullModel(model string) {
+	defer func() {
+		s.pullsMux.Lock()
+		delete(s.pulls, model)
+		s.pullsMux.Unlock()
+	}()
+
+	lastStatus := ""
+	var lastSent time.Time
+	err := PullModel(context.Background(), s.taskMgr.GetClient(), model, func(progress llm.PullProgress) {
+		if progress.Status == lastStatus && time.Since(lastSent) < pullEventInterval {
+			return
+		}
+		lastStatus = progress.Status
+		lastSent = time.Now()
+		s.wsHub.BroadcastJSON("model_pull", progress)
+	})
+
+	if err != nil {
+		log.Printf("Models: failed to pull %s: %v", model, err)
+		s.wsHub.BroadcastJSON("model_pull_failed", map[string]string{"model": model, "error": err.Error()})
+		return
+	}
+	s.wsHub.BroadcastJSON("model_pull_complete", map[string]string{"model": model})
+}
+
+// handleModels lists installed models with their digests and users, plus referenced models that are missing
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	client := s.taskMgr.GetClient()
+	installed, err := client.ListModels()
+	if err != nil {
+		s.respondError(w, fmt.Sprintf("Failed to list models: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	s.pullsMux.Lock()
+	usage := s.modelUsage
+	pulling := make(map[string]bool, len(s.pulls))
+	for model := range s.pulls {
+		pulling[model] = true
+	}
+	s.pullsMux.Unlock()
+
+	digests, _ := client.(llm.DigestProvider)
+	models := make([]ModelInfo, 0, len(installed))
+	for _, name := range installed {
+		info := ModelInfo{Name: name, Installed: true, UsedBy: usage.UsedBy(name)}
+		if info.UsedBy == nil {
+			info.UsedBy = []string{}
+		}
+		if digests != nil {
+			info.Digest, _ = digests.ModelDigest(name)
+		}
+		models = append(models, info)
+	}
+
+	missing := llm.MissingModels(installed, usage.Models())
+	for _, name := range missing {
+		models = append(models, ModelInfo{Name: name, UsedBy: usage[name], Pulling: pulling[name]})
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+
+	s.respondJSON(w, map[string]interface{}{
+		"models":  models,
+		"missing": missing,
+	})
+}
+
+// handleModelPull starts pulling a model, or every missing referenced model when none is given
+func (s *Server) handleModelPull(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	client := s.taskMgr.GetClient()
+	if _, ok := client.(llm.ModelPuller); !ok {
+		s.respondError(w, "Model provider cannot pull models", http.StatusNotImplemented)
+		return
+	}
+
+	models := []string{req.Model}
+	if req.Model == "" {
+		installed, err := client.ListModels()
+		if err != nil {
+			s.respondError(w, fmt.Sprintf("Failed to list models: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		s.pullsMux.Lock()
+		usage := s.modelUsage
+		s.pullsMux.Unlock()
+		models = llm.MissingModels(installed, usage.Models())
+	}
+
+	started := s.PullModels(models)
+	if started == nil {
+		started = []string{}
+	}
+
+	s.respondJSON(w, map[string]interface{}{
+		"pulling": started,
+	})
+}
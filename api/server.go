@@ -1,7 +1,8 @@
 package api
 
 import (
-	"archive/zip"
+	"context"
+	"errors"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -55,12 +57,16 @@ When discussing addictive mechanics, emphasize ethical design that creates compe
 
 Respond conversationally but with expertise. Keep answers focused and practical.`
 
+// queueEventInterval is how often LLM queue stats are checked for WebSocket broadcast
+const queueEventInterval = 2 * time.Second
+
 // TaskManager interface for both standard and supervised managers
 type TaskManager interface {
-	ExecuteTask(taskType, input string) (interface{}, error)
+	ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error)
 	Ping() error
-	GetHistory(taskType string) []task.Result
-	GetClient() *llm.Client
+	QueryHistory(q task.HistoryQuery) (task.HistoryPage, error)
+	QueryArtifacts(q task.ArtifactQuery) []task.Artifact
+	GetClient() llm.Provider
 	GetWebSocketHub() interface{} // For real-time updates
 }
 
@@ -70,32 +76,27 @@ type Server struct {
 	port             int
 	mux              *http.ServeMux
 	conversations    map[string]*Conversation
+	conversationsDir string // Conversations are persisted here and reloaded on start
 	convMux          sync.RWMutex
 	discoverSessions *task.DiscoverManager
 	wsHub            *ws.Hub            // WebSocket hub for real-time updates
 	imageStore       *storage.ImageStore // Image upload storage
-}
-
-// Conversation represents a chat conversation
-type Conversation struct {
-	ID        string
-	Messages  []Message
-	Context   []int
-	Model     string
-	CreatedAt time.Time
-}
-
-// Message represents a chat message
-type Message struct {
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	runningTasks     map[string]context.CancelFunc // task ID -> cancel for /task/cancel
+	tasksMux         sync.Mutex
+	modelUsage       ModelUsage                    // Referenced models, for /models
+	pulls            map[string]bool               // Models being pulled
+	pullsMux         sync.Mutex
+	jobs             map[string]*Job               // Tasks submitted via /task/submit, by job ID
+	jobsMux          sync.Mutex
 }
 
 // TaskRequest represents an incoming task request
 type TaskRequest struct {
-	TaskType string `json:"task_type"`
-	Input    string `json:"input"`
+	TaskID   string            `json:"task_id,omitempty"` // Optional caller-chosen ID for /task/cancel
+	TaskType string            `json:"task_type"`
+	Input    string            `json:"input"`
+	NoCache  bool              `json:"no_cache,omitempty"` // Bypass the LLM response cache
+	Vars     map[string]string `json:"vars,omitempty"`     // Prompt template variables ({{.Vars.name}})
 }
 
 // ErrorResponse represents an error response
@@ -116,13 +117,18 @@ func NewServer(taskMgr TaskManager, port int) *Server {
 		taskMgr:          taskMgr,
 		port:             port,
 		mux:              http.NewServeMux(),
-		conversations:    make(map[string]*Conversation),
-		discoverSessions: task.NewDiscoverManager(taskMgr.GetClient()),
+		conversations:    loadConversations(conversationsDir),
+		conversationsDir: conversationsDir,
+		discoverSessions: task.NewDiscoverManager(taskMgr.GetClient(), discoverDir),
 		wsHub:            hub,
 		imageStore:       imageStore,
+		runningTasks:     make(map[string]context.CancelFunc),
+		pulls:            make(map[string]bool),
+		jobs:             make(map[string]*Job),
 	}
 
 	s.registerRoutes()
+	go s.broadcastQueueStats()
 	return s
 }
 
@@ -136,17 +142,28 @@ func (s *Server) registerRoutes() {
 
 	// Protected endpoints (wrap with middleware)
 	s.mux.HandleFunc("/task", s.wrapMiddleware(s.handleTask))
+	s.mux.HandleFunc("/task/cancel", s.wrapMiddleware(s.handleTaskCancel))
+	s.mux.HandleFunc("/task/submit", s.wrapMiddleware(s.handleTaskSubmit))
+	s.mux.HandleFunc("/task/status", s.wrapMiddleware(s.handleTaskStatus))
+	s.mux.HandleFunc("/task/result", s.wrapMiddleware(s.handleTaskResult))
+	s.mux.HandleFunc("/task/running", s.wrapMiddleware(s.handleTaskRunning))
 	s.mux.HandleFunc("/history", s.wrapMiddleware(s.handleHistory))
 	s.mux.HandleFunc("/export", s.wrapMiddleware(s.handleExport))
 	s.mux.HandleFunc("/chat", s.wrapMiddleware(s.handleChat))
+	s.mux.HandleFunc("/chat/history", s.wrapMiddleware(s.handleChatHistory))
+	s.mux.HandleFunc("/chat/conversation", s.wrapMiddleware(s.handleGetConversation))
+	s.mux.HandleFunc("/models", s.wrapMiddleware(s.handleModels))
+	s.mux.HandleFunc("/models/pull", s.wrapMiddleware(s.handleModelPull))
 	s.mux.HandleFunc("/discover", s.wrapMiddleware(s.handleDiscover))
 	s.mux.HandleFunc("/discover/history", s.wrapMiddleware(s.handleDiscoverHistory))
 	s.mux.HandleFunc("/discover/session", s.wrapMiddleware(s.handleGetDiscoverSession))
+	s.mux.HandleFunc("/discover/archive", s.wrapMiddleware(s.handleArchiveDiscoverSession))
 
 	// Project endpoints (all protected)
 	s.mux.HandleFunc("/project", s.wrapMiddleware(s.handleProject))
 	s.mux.HandleFunc("/project/list", s.wrapMiddleware(s.handleProjectList))
 	s.mux.HandleFunc("/project/phase", s.wrapMiddleware(s.handleProjectPhase))
+	s.mux.HandleFunc("/project/phase/cancel", s.wrapMiddleware(s.handleProjectPhaseCancel))
 	s.mux.HandleFunc("/project/transition", s.wrapMiddleware(s.handleProjectTransition))
 	s.mux.HandleFunc("/project/approve", s.wrapMiddleware(s.handleProjectApprove))
 	s.mux.HandleFunc("/project/reject", s.wrapMiddleware(s.handleProjectReject))
@@ -157,6 +174,7 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/project/export", s.wrapMiddleware(s.handleExportProject))
 	s.mux.HandleFunc("/project/download", s.wrapMiddleware(s.handleDownloadProject))
 	s.mux.HandleFunc("/artifact/view", s.wrapMiddleware(s.handleViewArtifact))
+	s.mux.HandleFunc("/artifacts", s.wrapMiddleware(s.handleArtifacts))
 	s.mux.HandleFunc("/project/validate_schema", s.wrapMiddleware(s.handleProjectValidateSchema))
 
 	// Image upload endpoint
@@ -173,6 +191,7 @@ func (s *Server) Start() error {
 	log.Printf("  GET  /       - Web UI")
 	log.Printf("  GET  /health - Health check")
 	log.Printf("  POST /task   - Execute task")
+	log.Printf("  POST /task/cancel - Cancel a running task")
 	
 	// Log project status if orchestrator is enabled
 	if orchestrator, ok := s.taskMgr.(*project.ProjectOrchestrator); ok {
@@ -207,6 +226,20 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		health["ollama_status"] = "healthy"
 	}
 
+	// Per-host state when Ollama runs as a pool
+	if reporter, ok := s.taskMgr.GetClient().(llm.HostReporter); ok {
+		if hosts := reporter.HostStatus(); len(hosts) > 0 {
+			health["ollama_hosts"] = hosts
+		}
+	}
+
+	// Queue depth and wait times of the LLM request scheduler
+	if reporter, ok := s.taskMgr.GetClient().(llm.QueueReporter); ok {
+		if queues := reporter.QueueStats(); len(queues) > 0 {
+			health["llm_queues"] = queues
+		}
+	}
+
 	// Check Claude API key
 	if os.Getenv("ANTHROPIC_API_KEY") != "" {
 		health["claude_api_key"] = "configured"
@@ -230,6 +263,28 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, health)
 }
 
+// broadcastQueueStats sends "llm_queue" events over WebSocket whenever the scheduler queues change
+func (s *Server) broadcastQueueStats() {
+	reporter, ok := s.taskMgr.GetClient().(llm.QueueReporter)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(queueEventInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for range ticker.C {
+		queues := reporter.QueueStats()
+		data, err := json.Marshal(queues)
+		if err != nil || string(data) == string(last) {
+			continue
+		}
+		last = data
+		s.wsHub.BroadcastJSON("llm_queue", queues)
+	}
+}
+
 // handleTask processes task execution requests
 func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -237,35 +292,38 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request
-	body, err := io.ReadAll(r.Body)
+	req, err := readTaskRequest(r)
 	if err != nil {
-		s.respondError(w, "Failed to read request body", http.StatusBadRequest)
+		s.respondError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer r.Body.Close()
 
-	var req TaskRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		s.respondError(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
+	// Register task so it can be cancelled via /task/cancel
+	ctx, cancel := taskContext(req)
+	defer cancel()
 
-	// Validate request
-	if req.TaskType == "" {
-		s.respondError(w, "task_type is required", http.StatusBadRequest)
-		return
-	}
-	if req.Input == "" {
-		s.respondError(w, "input is required", http.StatusBadRequest)
+	if err := s.registerTask(req.TaskID, cancel); err != nil {
+		s.respondError(w, err.Error(), http.StatusConflict)
 		return
 	}
+	defer s.unregisterTask(req.TaskID)
+
+	s.wsHub.Broadcast(&ws.Event{
+		Type:      "task_started",
+		Data:      req.TaskID,
+		Timestamp: time.Now(),
+	})
 
 	// Execute task
-	log.Printf("Executing task: type=%s, input_length=%d", req.TaskType, len(req.Input))
-	result, err := s.taskMgr.ExecuteTask(req.TaskType, req.Input)
+	log.Printf("Executing task %s: type=%s, input_length=%d", req.TaskID, req.TaskType, len(req.Input))
+	result, err := s.taskMgr.ExecuteTask(ctx, req.TaskType, req.Input)
 
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Printf("Task %s cancelled", req.TaskID)
+			s.respondError(w, "Task cancelled", http.StatusConflict)
+			return
+		}
 		log.Printf("Task execution failed: %v", err)
 		s.respondError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -281,6 +339,96 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, result)
 }
 
+// readTaskRequest parses and validates a task request, assigning a task ID if the caller gave none
+func readTaskRequest(r *http.Request) (*TaskRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.New("Failed to read request body")
+	}
+	defer r.Body.Close()
+
+	var req TaskRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.New("Invalid JSON")
+	}
+
+	if req.TaskType == "" {
+		return nil, errors.New("task_type is required")
+	}
+	if req.Input == "" {
+		return nil, errors.New("input is required")
+	}
+
+	if req.TaskID == "" {
+		req.TaskID = fmt.Sprintf("task_%d", time.Now().UnixNano())
+	}
+	return &req, nil
+}
+
+// taskContext returns the context a task runs in; it outlives the HTTP request and stops only when cancelled
+func taskContext(req *TaskRequest) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(llm.WithPriority(context.Background(), llm.PriorityPhase))
+	if req.NoCache {
+		ctx = llm.WithoutCache(ctx)
+	}
+	return task.WithPromptVars(ctx, req.Vars), cancel
+}
+
+// handleTaskCancel cancels a running task by ID
+// The in-flight LLM request is aborted and remaining retries are skipped
+func (s *Server) handleTaskCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TaskID string `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.tasksMux.Lock()
+	cancel, ok := s.runningTasks[req.TaskID]
+	s.tasksMux.Unlock()
+
+	if !ok {
+		s.respondError(w, "Task not found or already finished", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Cancelling task %s", req.TaskID)
+	cancel()
+
+	s.respondJSON(w, map[string]interface{}{
+		"task_id": req.TaskID,
+		"status":  "cancelling",
+	})
+}
+
+// registerTask records the cancel function of a running task
+func (s *Server) registerTask(taskID string, cancel context.CancelFunc) error {
+	s.tasksMux.Lock()
+	defer s.tasksMux.Unlock()
+
+	if _, exists := s.runningTasks[taskID]; exists {
+		return fmt.Errorf("task %s is already running", taskID)
+	}
+	s.runningTasks[taskID] = cancel
+	return nil
+}
+
+// unregisterTask removes a finished task from the running set
+func (s *Server) unregisterTask(taskID string) {
+	s.tasksMux.Lock()
+	defer s.tasksMux.Unlock()
+
+	delete(s.runningTasks, taskID)
+}
+
 // handleRoot serves the web UI
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -308,6 +456,11 @@ func (s *Server) GetWebSocketHub() *ws.Hub {
 	return s.wsHub
 }
 
+// SetDiscoverOptions sets the question bounds of discovery sessions
+func (s *Server) SetDiscoverOptions(opts task.DiscoverOptions) {
+	s.discoverSessions.SetOptions(opts)
+}
+
 // handleAPIInfo provides basic API info
 func (s *Server) handleAPIInfo(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, map[string]interface{}{
@@ -318,6 +471,20 @@ func (s *Server) handleAPIInfo(w http.ResponseWriter, r *http.Request) {
 			"GET  /api   - API info",
 			"GET  /health - Health check",
 			"POST /task   - Execute task",
+			"POST /task/cancel - Cancel a running task",
+			"POST /task/submit - Start a task in the background, returning its job ID",
+			"GET  /task/status?id= - Job status and current stage",
+			"GET  /task/result?id= - Result of a finished job",
+			"GET  /task/running - Jobs still running",
+			"GET  /history - Task history (task_type, model, since, until, errors, q, cursor, limit)",
+			"GET  /export - Export filtered task history (format=md|json)",
+			"GET  /artifacts - Artifact index (id, project_id, task_type, since, limit)",
+			"GET  /discover/history - Discovery sessions (archived=include|only, status, cursor, limit)",
+			"GET|DELETE /discover/session?discover_id= - Get or delete a discovery session",
+			"POST /discover/archive - Archive or restore a discovery session",
+			"GET  /models - Installed and referenced models",
+			"POST /models/pull - Pull a model, or every missing one",
+			"POST /project/phase/cancel - Cancel a running project phase",
 		},
 	})
 }
@@ -344,23 +511,114 @@ func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	taskType := r.URL.Query().Get("task_type")
-	if taskType == "" {
-		taskType = "all"
+	q, err := historyQuery(r)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = task.DefaultHistoryLimit
+	}
+	if q.Limit > task.MaxHistoryLimit {
+		q.Limit = task.MaxHistoryLimit
+	}
+
+	page, err := s.taskMgr.QueryHistory(q)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.respondJSON(w, page)
+}
+
+// historyQuery reads history filters from the query string:
+// task_type, model, since and until (RFC 3339 or YYYY-MM-DD), errors (only|none),
+// q (full-text search), cursor and limit
+func historyQuery(r *http.Request) (task.HistoryQuery, error) {
+	params := r.URL.Query()
+	q := task.HistoryQuery{
+		TaskType: params.Get("task_type"),
+		Model:    params.Get("model"),
+		Errors:   params.Get("errors"),
+		Search:   params.Get("q"),
+		Cursor:   params.Get("cursor"),
+	}
+
+	switch q.Errors {
+	case "", "only", "none":
+	default:
+		return q, fmt.Errorf("errors must be 'only' or 'none'")
+	}
+
+	var err error
+	if q.Since, err = parseHistoryTime(params.Get("since")); err != nil {
+		return q, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.Until, err = parseHistoryUntil(params.Get("until")); err != nil {
+		return q, fmt.Errorf("invalid until: %w", err)
 	}
 
-	history := s.taskMgr.GetHistory(taskType)
-	s.respondJSON(w, history)
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			return q, fmt.Errorf("limit must be a positive number")
+		}
+	}
+
+	return q, nil
 }
 
-// handleExport exports history as markdown
+// parseHistoryTime parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC)
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseHistoryUntil parses an exclusive upper bound; a YYYY-MM-DD date includes that whole day
+func parseHistoryUntil(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.Add(24 * time.Hour), nil
+}
+
+// handleExport exports the history matching the /history filters as markdown, or JSON with format=json
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	history := s.taskMgr.GetHistory("all")
+	q, err := historyQuery(r)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := s.taskMgr.QueryHistory(q)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	history := page.Results
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"history_%d.json\"", time.Now().Unix()))
+		s.respondJSON(w, history)
+		return
+	}
 
 	// Generate markdown
 	var md strings.Builder
@@ -373,7 +631,11 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 		md.WriteString(fmt.Sprintf("## Task %d: %s\n\n", i+1, task.TaskType))
 		md.WriteString(fmt.Sprintf("**Timestamp:** %s\n", task.Timestamp.Format(time.RFC3339)))
 		md.WriteString(fmt.Sprintf("**Model:** %s\n", task.Model))
-		md.WriteString(fmt.Sprintf("**Duration:** %.2fs\n\n", task.Duration))
+		md.WriteString(fmt.Sprintf("**Duration:** %.2fs\n", task.Duration))
+		if task.Error != "" {
+			md.WriteString(fmt.Sprintf("**Error:** %s\n", task.Error))
+		}
+		md.WriteString("\n")
 		md.WriteString("### Input\n\n")
 		md.WriteString(task.Input)
 		md.WriteString("\n\n### Output\n\n")
@@ -388,6 +650,8 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleChat handles chat conversation requests
+// The conversation's message history is sent with every turn, so it survives
+// restarts and a "model" field switches models mid-conversation.
 func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -397,6 +661,7 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ConversationID string `json:"conversation_id"`
 		Message        string `json:"message"`
+		Model          string `json:"model,omitempty"` // Optional: switch the conversation to this model
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -416,44 +681,87 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		conv = &Conversation{
 			ID:        generateID(),
 			Messages:  []Message{},
-			Context:   nil,
-			Model:     "mistral:7b-instruct-v0.2-q4_K_M",
+			Model:     defaultChatModel,
 			CreatedAt: time.Now(),
 		}
 		s.conversations[conv.ID] = conv
 	}
 	s.convMux.Unlock()
 
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+
+	// A requested model is only kept once it has answered a turn
+	model := conv.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+
 	// Add user message
 	userMsg := Message{
-		Role:      "user",
+		Role:      llm.RoleUser,
 		Content:   req.Message,
 		Timestamp: time.Now(),
 	}
 	conv.Messages = append(conv.Messages, userMsg)
 
-	// Build prompt - prepend system prompt on first message
-	prompt := req.Message
-	if conv.Context == nil {
-		prompt = gameDesignSystemPrompt + "\n\n---\n\nUser: " + req.Message
+	// Generate response from the history, streaming tokens to WebSocket clients
+	var onToken llm.TokenHandler
+	if s.wsHub != nil {
+		convID := conv.ID
+		onToken = func(chunk string) {
+			s.wsHub.Broadcast(&ws.Event{
+				Type:           "token",
+				ConversationID: convID,
+				Phase:          "chat",
+				Data:           chunk,
+				Timestamp:      time.Now(),
+			})
+		}
 	}
 
-	// Generate response with context
 	client := s.taskMgr.GetClient()
-	response, newContext, err := client.GenerateWithContext(conv.Model, prompt, conv.Context)
+	options := map[string]interface{}{"num_ctx": chatContextTokens}
+	ctx := llm.WithPriority(r.Context(), llm.PriorityInteractive)
+	if s.wsHub != nil {
+		// A pool host failing mid-reply restarts it on another; clients drop what they got
+		convID := conv.ID
+		ctx = llm.WithTokenReset(ctx, func(model string) {
+			s.wsHub.Broadcast(&ws.Event{
+				Type:           "token_reset",
+				ConversationID: convID,
+				Phase:          "chat",
+				Data:           model,
+				Timestamp:      time.Now(),
+			})
+		})
+	}
+	response, err := llm.ChatStream(ctx, client, model, conv.chatHistory(gameDesignSystemPrompt), options, onToken)
 	if err != nil {
+		// Drop the unanswered message so a retry doesn't send it twice
+		conv.Messages = conv.Messages[:len(conv.Messages)-1]
 		s.respondError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if model != conv.Model {
+		log.Printf("Chat: conversation %s switched from %s to %s", conv.ID, conv.Model, model)
+		conv.Model = model
+	}
+
 	// Add assistant message
 	assistantMsg := Message{
-		Role:      "assistant",
+		Role:      llm.RoleAssistant,
 		Content:   response,
+		Model:     conv.Model,
 		Timestamp: time.Now(),
 	}
 	conv.Messages = append(conv.Messages, assistantMsg)
-	conv.Context = newContext
+	conv.UpdatedAt = assistantMsg.Timestamp
+
+	if err := saveConversation(s.conversationsDir, conv); err != nil {
+		log.Printf("Chat: failed to save conversation %s: %v", conv.ID, err)
+	}
 
 	// Save to artifact if conversation is substantial (>= 6 messages)
 	if len(conv.Messages) >= 6 {
@@ -463,13 +771,72 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, map[string]interface{}{
 		"conversation_id": conv.ID,
 		"response":        response,
+		"model":           conv.Model,
 		"timestamp":       assistantMsg.Timestamp,
 	})
 }
 
+// handleChatHistory lists conversations, most recently active first
+func (s *Server) handleChatHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.convMux.RLock()
+	conversations := make([]*Conversation, 0, len(s.conversations))
+	for _, conv := range s.conversations {
+		conversations = append(conversations, conv)
+	}
+	s.convMux.RUnlock()
+
+	summaries := make([]ConversationSummary, 0, len(conversations))
+	for _, conv := range conversations {
+		conv.mu.Lock()
+		summaries = append(summaries, conv.summary())
+		conv.mu.Unlock()
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+
+	s.respondJSON(w, map[string]interface{}{
+		"conversations": summaries,
+		"count":         len(summaries),
+	})
+}
+
+// handleGetConversation returns a conversation with its full message history
+func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	convID := r.URL.Query().Get("conversation_id")
+	if convID == "" {
+		s.respondError(w, "conversation_id parameter required", http.StatusBadRequest)
+		return
+	}
+
+	s.convMux.RLock()
+	conv := s.conversations[convID]
+	s.convMux.RUnlock()
+
+	if conv == nil {
+		s.respondError(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+	s.respondJSON(w, conv)
+}
+
 // generateID creates a unique conversation ID
 func generateID() string {
-	return fmt.Sprintf("chat_%d", time.Now().Unix())
+	return fmt.Sprintf("chat_%d", time.Now().UnixNano())
 }
 
 // saveConversationArtifact saves the conversation to a file
@@ -485,7 +852,7 @@ func (s *Server) saveConversationArtifact(conv *Conversation) {
 
 	for _, msg := range conv.Messages {
 		role := "User"
-		if msg.Role == "assistant" {
+		if msg.Role == llm.RoleAssistant {
 			role = "AI"
 		}
 		content.WriteString(fmt.Sprintf("### %s (%s)\n\n", role, msg.Timestamp.Format("15:04:05")))
@@ -498,7 +865,7 @@ func (s *Server) saveConversationArtifact(conv *Conversation) {
 
 // saveDiscoveryArtifact saves the discovery session to a markdown file
 func (s *Server) saveDiscoveryArtifact(session *task.DiscoverSession) {
-	artifactPath := fmt.Sprintf("artifacts/discover_%d.md", time.Now().Unix())
+	artifactPath := filepath.Join("artifacts", session.ID+".md")
 
 	var content strings.Builder
 	content.WriteString("# Discovery Session\n\n")
@@ -562,7 +929,7 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 
 	// Handle "start" action - create new session
 	if req.Action == "start" {
-		session := s.discoverSessions.StartSession(req.Input)
+		session := s.discoverSessions.StartSession(llm.WithPriority(r.Context(), llm.PriorityInteractive), req.Input)
 		questionNum, question := s.discoverSessions.GetCurrentQuestion(session)
 
 		log.Printf("Started discovery session: id=%s", session.ID)
@@ -590,7 +957,7 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		session, err := s.discoverSessions.AddAnswer(req.DiscoverID, req.Input)
+		session, err := s.discoverSessions.AddAnswer(llm.WithPriority(r.Context(), llm.PriorityInteractive), req.DiscoverID, req.Input)
 		if err != nil {
 			s.respondError(w, err.Error(), http.StatusBadRequest)
 			return
@@ -613,6 +980,7 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 				"verdict":          session.Verdict,
 				"reasoning":        session.Reasoning,
 				"timestamp":        session.UpdatedAt,
+				"confidence":       session.Confidence,
 				// Full session data for enriched prompts
 				"raw_idea":         session.RawIdea,
 				"idea_type":        session.IdeaType,
@@ -633,6 +1001,7 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 			"verdict":          nil,
 			"reasoning":        nil,
 			"timestamp":        session.UpdatedAt,
+			"confidence":       session.Confidence, // How close the session is to stopping early
 			// Include session context
 			"idea_type":        session.IdeaType,
 			"questions":        session.Questions,
@@ -642,29 +1011,50 @@ func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleDiscoverHistory returns all discovery sessions
+// handleDiscoverHistory returns discovery sessions, newest first, one page at a time
+// Query parameters: archived (include|only), status, cursor and limit
 func (s *Server) handleDiscoverHistory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	sessions := s.discoverSessions.GetAllSessions()
+	params := r.URL.Query()
+	q := task.DiscoverQuery{
+		Archived: params.Get("archived"),
+		Status:   params.Get("status"),
+		Cursor:   params.Get("cursor"),
+		Limit:    task.DefaultHistoryLimit,
+	}
 
-	// Sort by most recent first
-	sort.Slice(sessions, func(i, j int) bool {
-		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
-	})
+	switch q.Archived {
+	case "", "include", "only":
+	default:
+		s.respondError(w, "archived must be 'include' or 'only'", http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			s.respondError(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	if q.Limit > task.MaxHistoryLimit {
+		q.Limit = task.MaxHistoryLimit
+	}
 
-	s.respondJSON(w, map[string]interface{}{
-		"sessions": sessions,
-		"count":    len(sessions),
-	})
+	page, err := s.discoverSessions.ListSessions(q)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.respondJSON(w, page)
 }
 
-// handleGetDiscoverSession retrieves a specific discovery session by ID
+// handleGetDiscoverSession retrieves (GET) or deletes (DELETE) a discovery session by ID
 func (s *Server) handleGetDiscoverSession(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
 		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -675,6 +1065,23 @@ func (s *Server) handleGetDiscoverSession(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if r.Method == http.MethodDelete {
+		if err := s.discoverSessions.DeleteSession(sessionID); err != nil {
+			status := http.StatusInternalServerError
+			if s.discoverSessions.GetSession(sessionID) == nil {
+				status = http.StatusNotFound
+			}
+			s.respondError(w, err.Error(), status)
+			return
+		}
+		log.Printf("Deleted discovery session: id=%s", sessionID)
+		s.respondJSON(w, map[string]interface{}{
+			"success":     true,
+			"discover_id": sessionID,
+		})
+		return
+	}
+
 	session := s.discoverSessions.GetSession(sessionID)
 	if session == nil {
 		s.respondError(w, "Session not found", http.StatusNotFound)
@@ -684,6 +1091,40 @@ func (s *Server) handleGetDiscoverSession(w http.ResponseWriter, r *http.Request
 	s.respondJSON(w, session)
 }
 
+// handleArchiveDiscoverSession archives a discovery session, or restores it with "archived": false
+func (s *Server) handleArchiveDiscoverSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		DiscoverID string `json:"discover_id"`
+		Archived   *bool  `json:"archived"` // Defaults to true
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DiscoverID == "" {
+		s.respondError(w, "discover_id is required", http.StatusBadRequest)
+		return
+	}
+
+	archived := req.Archived == nil || *req.Archived
+	session, err := s.discoverSessions.ArchiveSession(req.DiscoverID, archived)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if s.discoverSessions.GetSession(req.DiscoverID) == nil {
+			status = http.StatusNotFound
+		}
+		s.respondError(w, err.Error(), status)
+		return
+	}
+
+	s.respondJSON(w, session)
+}
+
 // Project Orchestrator Handlers
 
 // handleProject handles creating and getting projects
@@ -779,6 +1220,7 @@ func (s *Server) handleProjectPhase(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ProjectID string         `json:"project_id"`
 		Phase     project.Phase  `json:"phase"`
+		NoCache   bool           `json:"no_cache"` // Bypass the LLM response cache
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -791,8 +1233,18 @@ func (s *Server) handleProjectPhase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := orchestrator.ExecuteProjectPhase(req.ProjectID, req.Phase)
+	// Phases outlive the HTTP request; they stop only via /project/phase/cancel
+	ctx := context.Background()
+	if req.NoCache {
+		ctx = llm.WithoutCache(ctx)
+	}
+
+	result, err := orchestrator.ExecuteProjectPhase(ctx, req.ProjectID, req.Phase)
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			s.respondError(w, "Phase cancelled", http.StatusConflict)
+			return
+		}
 		s.respondError(w, fmt.Sprintf("Failed to execute phase: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -800,6 +1252,44 @@ func (s *Server) handleProjectPhase(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, result)
 }
 
+// handleProjectPhaseCancel cancels the phase currently running for a project
+func (s *Server) handleProjectPhaseCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orchestrator, ok := s.taskMgr.(*project.ProjectOrchestrator)
+	if !ok {
+		s.respondError(w, "Project orchestrator not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		ProjectID string `json:"project_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID == "" {
+		s.respondError(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := orchestrator.CancelPhase(req.ProjectID); err != nil {
+		s.respondError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	s.respondJSON(w, map[string]interface{}{
+		"project_id": req.ProjectID,
+		"status":     "cancelling",
+	})
+}
+
 // handleProjectTransition transitions a project to a new phase
 func (s *Server) handleProjectTransition(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -970,7 +1460,7 @@ func (s *Server) handleProjectRevert(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleProjectMetrics gets completion metrics for a project
+// handleProjectMetrics gets completion metrics and the LLM usage ledger for a project
 func (s *Server) handleProjectMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -989,13 +1479,23 @@ func (s *Server) handleProjectMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metrics, err := orchestrator.GetCompletionMetrics(projectID)
+	metrics, err := orchestrator.GetCompletionMetrics(r.Context(), projectID)
 	if err != nil {
 		s.respondError(w, fmt.Sprintf("Failed to get metrics: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	s.respondJSON(w, metrics)
+	usage, err := orchestrator.GetUsageLedger(projectID)
+	if err != nil {
+		s.respondError(w, fmt.Sprintf("Failed to get usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Completion metrics stay at the top level; the LLM usage ledger is added alongside
+	s.respondJSON(w, struct {
+		*project.CompletionMetrics
+		Usage *project.UsageLedger `json:"usage"`
+	}{metrics, usage})
 }
 
 // handleProjectQuality gets quality guarantee report for a project
@@ -1025,7 +1525,7 @@ func (s *Server) handleProjectQuality(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get completion metrics
-	metrics, err := orchestrator.GetCompletionMetrics(projectID)
+	metrics, err := orchestrator.GetCompletionMetrics(r.Context(), projectID)
 	if err != nil {
 		s.respondError(w, fmt.Sprintf("Failed to get metrics: %v", err), http.StatusInternalServerError)
 		return
@@ -1071,6 +1571,48 @@ func (s *Server) handleViewArtifact(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleArtifacts returns artifact metadata from the artifact index, newest first
+func (s *Server) handleArtifacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := task.ArtifactQuery{
+		ID:        params.Get("id"),
+		ProjectID: params.Get("project_id"),
+		TaskType:  params.Get("task_type"),
+		Limit:     task.DefaultHistoryLimit,
+	}
+
+	var err error
+	if q.Since, err = parseHistoryTime(params.Get("since")); err != nil {
+		s.respondError(w, fmt.Sprintf("invalid since: %v", err), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			s.respondError(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	if q.Limit > task.MaxHistoryLimit {
+		q.Limit = task.MaxHistoryLimit
+	}
+
+	artifacts := s.taskMgr.QueryArtifacts(q)
+	if q.ID != "" && len(artifacts) == 0 {
+		s.respondError(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+
+	s.respondJSON(w, map[string]interface{}{
+		"artifacts": artifacts,
+		"count":     len(artifacts),
+	})
+}
+
 // handleDeleteProject deletes a project
 func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -1232,26 +1774,9 @@ func (s *Server) handleDownloadProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract project directory from artifact paths
-	projectDir := ""
-	for _, artifactPath := range proj.ArtifactPaths {
-		if strings.Contains(artifactPath, "(project: ") {
-			start := strings.Index(artifactPath, "(project: ") + len("(project: ")
-			end := strings.Index(artifactPath[start:], ")")
-			if end > 0 {
-				projectDir = artifactPath[start : start+end]
-				break
-			}
-		}
-	}
-
-	if projectDir == "" || !strings.HasPrefix(projectDir, "projects/") {
-		s.respondError(w, "No valid project directory found", http.StatusNotFound)
-		return
-	}
-
-	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
-		s.respondError(w, "Project directory does not exist", http.StatusNotFound)
+	projectDir, err := orchestrator.ProjectDir(projectID)
+	if err != nil {
+		s.respondError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
@@ -1260,35 +1785,10 @@ func (s *Server) handleDownloadProject(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", zipFilename))
 
-	// Create ZIP writer
-	zipWriter := zip.NewWriter(w)
-	defer zipWriter.Close()
-
-	// Walk directory and add all files to ZIP
-	filepath.Walk(projectDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return err
-		}
-
-		relPath, err := filepath.Rel(projectDir, path)
-		if err != nil {
-			return err
-		}
-
-		zipFile, err := zipWriter.Create(relPath)
-		if err != nil {
-			return err
-		}
-
-		sourceFile, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer sourceFile.Close()
-
-		_, err = io.Copy(zipFile, sourceFile)
-		return err
-	})
+	if err := project.WriteZip(w, projectDir); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
 
 	log.Printf("Project %s downloaded as ZIP", proj.ID)
 }
@@ -0,0 +1,474 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+)
+
+// DefaultParallel is how many requests run at once when Options.Parallel is not set
+const DefaultParallel = 2
+
+// Result states
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusPending   = "pending" // Not run yet, or interrupted
+)
+
+// Request is one line of a batch file, in the shape of customer_request.json
+type Request struct {
+	ID        string            `json:"id,omitempty"` // Defaults to a hash of task_type and input
+	RequestID string            `json:"request_id,omitempty"`
+	TaskType  string            `json:"task_type"`
+	Input     string            `json:"input"`
+	Vars      map[string]string `json:"vars,omitempty"` // Prompt template vars, as with POST /task
+
+	line int
+}
+
+// Result is the outcome of one request
+type Result struct {
+	ID           string            `json:"id"`
+	Line         int               `json:"line"`
+	TaskType     string            `json:"task_type"`
+	Status       string            `json:"status"`
+	ArtifactPath string            `json:"artifact_path,omitempty"`
+	ProjectDir   string            `json:"project_dir,omitempty"` // Project generated by a code task
+	Route        string            `json:"route,omitempty"`       // Execution route of supervised tasks: ollama, claude_code, ...
+	Complexity   int               `json:"complexity,omitempty"`
+	Duration     float64           `json:"duration_seconds"`
+	Validation   string            `json:"validation,omitempty"` // passed, warning or failed; empty when nothing was checked
+	Checks       map[string]string `json:"checks,omitempty"`     // Status of each check: qa, testing, files, ...
+	Usage        *llm.Usage        `json:"usage,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	FinishedAt   *time.Time        `json:"finished_at,omitempty"`
+}
+
+// Summary reports every request of a batch file, including those completed by earlier runs
+type Summary struct {
+	Source      string    `json:"source"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Total       int       `json:"total"`
+	Completed   int       `json:"completed"`
+	Failed      int       `json:"failed"`
+	Pending     int       `json:"pending"`
+	Duration    float64   `json:"duration_seconds"` // Sum of the task durations
+	Results     []Result  `json:"results"`          // In file order
+}
+
+// Executor runs a task; every task manager implements it
+type Executor interface {
+	ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error)
+}
+
+// Options configure a batch run
+type Options struct {
+	Parallel int    // Requests run at once; 0 = DefaultParallel
+	OutDir   string // Where results.jsonl, summary.json and summary.md are written
+}
+
+// Runner executes batch files
+type Runner struct {
+	exec Executor
+	opts Options
+}
+
+// NewRunner creates a batch runner
+func NewRunner(exec Executor, opts Options) *Runner {
+	if opts.Parallel <= 0 {
+		opts.Parallel = DefaultParallel
+	}
+	return &Runner{exec: exec, opts: opts}
+}
+
+// Run executes the requests of the JSONL file at path and writes the summary
+// Every finished request is appended to <out>/results.jsonl as it completes, so an
+// interrupted run resumes where it stopped: completed requests are skipped and
+// failed ones retried. Cancelling ctx stops the run; unfinished requests stay pending.
+func (r *Runner) Run(ctx context.Context, path string) (*Summary, error) {
+	requests, invalid, err := ReadRequests(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(r.opts.OutDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create batch output directory: %w", err)
+	}
+	resultsPath := filepath.Join(r.opts.OutDir, "results.jsonl")
+	previous, err := loadResults(resultsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]Result)
+	for _, res := range invalid {
+		results[res.ID] = res
+	}
+
+	var todo []Request
+	for _, req := range requests {
+		if res, ok := previous[req.ID]; ok && res.Status == StatusCompleted {
+			res.Line = req.line
+			results[req.ID] = res
+			continue
+		}
+		todo = append(todo, req)
+	}
+	log.Printf("Batch: %d request(s) in %s, %d already completed, %d to run (parallel: %d)",
+		len(requests), path, len(requests)-len(todo), len(todo), r.opts.Parallel)
+
+	file, err := os.OpenFile(resultsPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch results: %w", err)
+	}
+	defer file.Close()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, r.opts.Parallel)
+	done := 0
+
+	for _, req := range todo {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(req Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res := r.runRequest(ctx, req)
+			if res == nil {
+				return // Cancelled; left pending for the next run
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			results[req.ID] = *res
+			if err := appendResult(file, res); err != nil {
+				log.Printf("Warning: failed to record batch result %s: %v", req.ID, err)
+			}
+			mark := "✓"
+			if res.Status != StatusCompleted {
+				mark = "✗"
+			}
+			log.Printf("Batch: [%d/%d] %s %s (%s, %.1fs)", done, len(todo), mark, req.ID, req.TaskType, res.Duration)
+		}(req)
+	}
+	wg.Wait()
+
+	summary := buildSummary(path, requests, invalid, results)
+	if err := writeSummary(r.opts.OutDir, summary); err != nil {
+		return summary, err
+	}
+	if ctx.Err() != nil {
+		return summary, ctx.Err()
+	}
+	return summary, nil
+}
+
+// runRequest executes one request, returning nil if it was cancelled
+func (r *Runner) runRequest(ctx context.Context, req Request) *Result {
+	start := time.Now()
+	taskCtx := task.WithPromptVars(ctx, req.Vars)
+
+	output, err := r.exec.ExecuteTask(taskCtx, req.TaskType, req.Input)
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	finished := time.Now()
+	res := &Result{
+		ID:         req.ID,
+		Line:       req.line,
+		TaskType:   req.TaskType,
+		Status:     StatusCompleted,
+		Duration:   finished.Sub(start).Seconds(),
+		FinishedAt: &finished,
+	}
+	describeResult(res, output)
+	if err != nil {
+		res.Status = StatusFailed
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// describeResult copies the artifact, route, usage and validation checks of a task result
+func describeResult(res *Result, output interface{}) {
+	checks := make(map[string]string)
+
+	var base *task.Result
+	switch r := output.(type) {
+	case *supervisor.SupervisedResult:
+		base = r.Result
+		res.Route = r.ExecutionRoute
+		res.Complexity = r.ComplexityScore
+		res.Usage = r.TotalUsage
+		agents := map[string]*supervisor.AgentOutput{
+			"requirements":  r.RequirementsAnalysis,
+			"techstack":     r.TechStackApproval,
+			"scope":         r.ScopeValidation,
+			"qa":            r.QAReview,
+			"testing":       r.TestPlan,
+			"documentation": r.Documentation,
+		}
+		for name, agent := range agents {
+			if agent != nil && agent.Status != "" {
+				checks[name] = agent.Status
+			}
+		}
+	case *task.Result:
+		base = r
+		res.Usage = r.Usage
+	}
+
+	if base != nil {
+		res.ArtifactPath = base.ArtifactPath
+		res.ProjectDir = base.ProjectDir
+		if base.Extraction != nil {
+			checks["files"] = "passed"
+			if len(base.Extraction.Files) == 0 {
+				checks["files"] = "failed"
+			}
+		}
+		if base.Coverage != nil && !base.Coverage.Complete() {
+			checks["files"] = "warning"
+		}
+	}
+
+	if len(checks) == 0 {
+		return
+	}
+	res.Checks = checks
+	res.Validation = "passed"
+	for _, status := range checks {
+		if status == "failed" {
+			res.Validation = "failed"
+			break
+		}
+		if status == "warning" {
+			res.Validation = "warning"
+		}
+	}
+}
+
+// ReadRequests parses a JSONL batch file
+// Blank lines are skipped; lines that aren't valid requests come back as failed results.
+// Requests with the same ID run once.
+func ReadRequests(path string) ([]Request, []Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer file.Close()
+
+	var requests []Request
+	var invalid []Result
+	seen := make(map[string]bool)
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("failed to read batch file: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			req, parseErr := parseRequest(text)
+			switch {
+			case parseErr != nil:
+				invalid = append(invalid, Result{
+					ID:     fmt.Sprintf("line-%d", line),
+					Line:   line,
+					Status: StatusFailed,
+					Error:  fmt.Sprintf("invalid request: %v", parseErr),
+				})
+			case seen[req.ID]:
+				log.Printf("Warning: batch line %d repeats request %s; it runs once", line, req.ID)
+			default:
+				req.line = line
+				seen[req.ID] = true
+				requests = append(requests, req)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return requests, invalid, nil
+}
+
+// parseRequest parses and checks one batch line
+func parseRequest(text string) (Request, error) {
+	var req Request
+	if err := json.Unmarshal([]byte(text), &req); err != nil {
+		return req, err
+	}
+	if req.TaskType == "" || strings.TrimSpace(req.Input) == "" {
+		return req, errors.New("task_type and input are required")
+	}
+	if req.ID == "" {
+		req.ID = req.RequestID
+	}
+	if req.ID == "" {
+		sum := sha256.Sum256([]byte(req.TaskType + "\x00" + req.Input))
+		req.ID = "req-" + hex.EncodeToString(sum[:6])
+	}
+	return req, nil
+}
+
+// loadResults reads the results of earlier runs, keeping the latest per request
+func loadResults(path string) (map[string]Result, error) {
+	results := make(map[string]Result)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		var res Result
+		if strings.TrimSpace(line) == "" || json.Unmarshal([]byte(line), &res) != nil {
+			continue // A line cut short by a crash; the request runs again
+		}
+		results[res.ID] = res
+	}
+	return results, nil
+}
+
+// appendResult records a finished request
+func appendResult(file *os.File, res *Result) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// buildSummary reports every request in file order
+func buildSummary(source string, requests []Request, invalid []Result, results map[string]Result) *Summary {
+	summary := &Summary{Source: source, GeneratedAt: time.Now(), Results: []Result{}}
+
+	all := append([]Result{}, invalid...)
+	for _, req := range requests {
+		res, ok := results[req.ID]
+		if !ok {
+			res = Result{ID: req.ID, Line: req.line, TaskType: req.TaskType, Status: StatusPending}
+		}
+		all = append(all, res)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Line < all[j].Line })
+
+	for _, res := range all {
+		switch res.Status {
+		case StatusCompleted:
+			summary.Completed++
+		case StatusFailed:
+			summary.Failed++
+		default:
+			summary.Pending++
+		}
+		summary.Duration += res.Duration
+	}
+	summary.Total = len(all)
+	summary.Results = all
+	return summary
+}
+
+// writeSummary writes summary.json and summary.md to dir
+func writeSummary(dir string, summary *Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write batch summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.md"), []byte(summaryMarkdown(summary)), 0644); err != nil {
+		return fmt.Errorf("failed to write batch summary: %w", err)
+	}
+	return nil
+}
+
+// summaryMarkdown renders a summary as a Markdown report
+func summaryMarkdown(s *Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Batch Summary: %s\n\n", filepath.Base(s.Source))
+	fmt.Fprintf(&b, "**Generated:** %s\n", s.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "**Requests:** %d | **Completed:** %d | **Failed:** %d | **Pending:** %d\n", s.Total, s.Completed, s.Failed, s.Pending)
+	fmt.Fprintf(&b, "**Total task time:** %.1fs\n\n", s.Duration)
+
+	b.WriteString("| Line | ID | Task | Status | Route | Duration | Validation | Artifact |\n")
+	b.WriteString("|------|----|------|--------|-------|----------|------------|----------|\n")
+	for _, res := range s.Results {
+		route := res.Route
+		if route != "" && res.Complexity > 0 {
+			route = fmt.Sprintf("%s (%d)", route, res.Complexity)
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %.1fs | %s | %s |\n",
+			res.Line, res.ID, res.TaskType, res.Status, route, res.Duration, res.Validation, markdownCell(res.ArtifactPath))
+	}
+
+	var failures []Result
+	for _, res := range s.Results {
+		if res.Status == StatusFailed || res.Validation == "failed" {
+			failures = append(failures, res)
+		}
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n## Failures\n")
+		for _, res := range failures {
+			fmt.Fprintf(&b, "\n### %s (line %d)\n", res.ID, res.Line)
+			if res.Error != "" {
+				fmt.Fprintf(&b, "**Error:** %s\n", res.Error)
+			}
+			for _, name := range sortedChecks(res.Checks) {
+				if res.Checks[name] != "passed" {
+					fmt.Fprintf(&b, "- %s: %s\n", name, res.Checks[name])
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownCell escapes a value for a Markdown table
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// sortedChecks returns the check names of a result in a stable order
+func sortedChecks(checks map[string]string) []string {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,81 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"ai-studio/orchestrator/task"
+)
+
+// fakeExecutor fails inputs containing "fail" until fixed, counting runs per input
+type fakeExecutor struct {
+	mu    sync.Mutex
+	runs  map[string]int
+	fixed bool
+}
+
+func (e *fakeExecutor) ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runs[input]++
+	if strings.Contains(input, "fail") && !e.fixed {
+		return nil, errors.New("model unavailable")
+	}
+	return &task.Result{TaskType: taskType, ArtifactPath: "artifacts/" + input + ".md"}, nil
+}
+
+// TestRunResume tests that a rerun skips completed requests and retries failed ones
+func TestRunResume(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "requests.jsonl")
+	lines := []string{
+		`{"id": "one", "task_type": "validate", "input": "first"}`,
+		``,
+		`{"task_type": "review", "input": "will fail"}`,
+		`not json`,
+		`{"id": "one", "task_type": "validate", "input": "duplicate"}`,
+		`{"task_type": "code"}`,
+	}
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exec := &fakeExecutor{runs: make(map[string]int)}
+	runner := NewRunner(exec, Options{Parallel: 2, OutDir: filepath.Join(dir, "out")})
+
+	summary, err := runner.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if summary.Total != 4 || summary.Completed != 1 || summary.Failed != 3 {
+		t.Fatalf("first run summary = %+v", summary)
+	}
+	if res := summary.Results[0]; res.ID != "one" || res.ArtifactPath != "artifacts/first.md" || res.Line != 1 {
+		t.Errorf("first result = %+v", res)
+	}
+	if res := summary.Results[2]; res.ID != "line-4" || !strings.Contains(res.Error, "invalid request") {
+		t.Errorf("invalid line result = %+v", res)
+	}
+
+	exec.fixed = true
+	summary, err = runner.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if summary.Completed != 2 || summary.Failed != 2 {
+		t.Errorf("second run summary = %+v", summary)
+	}
+	if exec.runs["first"] != 1 || exec.runs["will fail"] != 2 || exec.runs["duplicate"] != 0 {
+		t.Errorf("runs = %v, want completed requests skipped and failed ones retried", exec.runs)
+	}
+
+	report, err := os.ReadFile(filepath.Join(dir, "out", "summary.md"))
+	if err != nil || !strings.Contains(string(report), "| 1 | one | validate | completed |") {
+		t.Errorf("summary.md = %s (%v)", report, err)
+	}
+}
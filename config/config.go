@@ -5,31 +5,233 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config holds the orchestrator configuration
 type Config struct {
-	OllamaURL            string                    `json:"ollama_url"`
-	Models               map[string]string         `json:"models"`        // task_type -> model_name
-	ArtifactsDir         string                    `json:"artifacts_dir"`
-	MaxRetries           int                       `json:"max_retries"`
-	Timeout              int                       `json:"timeout_seconds"`
-	ProjectOrchestrator  ProjectOrchestratorConfig `json:"project_orchestrator"`
+	OllamaURL           string                    `json:"ollama_url"`
+	OllamaHosts         []string                  `json:"ollama_hosts,omitempty"`        // Pool of Ollama hosts; replaces ollama_url when set
+	HealthCheckSeconds  int                       `json:"health_check_interval_seconds"` // How often pool hosts are re-checked
+	Models              map[string]string         `json:"models"`                        // task_type -> model_name
+	ModelFallbacks      map[string][]string       `json:"model_fallbacks,omitempty"`     // task_type -> models to try, in order, when the primary is missing or failing
+	TaskTypes           map[string]TaskTypeConfig `json:"task_types,omitempty"`          // User-defined task types; may also override built-in ones
+	PromptsDir          string                    `json:"prompts_dir,omitempty"`         // <task_type>.tmpl files here override the built-in prompts
+	ArtifactsDir        string                    `json:"artifacts_dir"`
+	HistoryPath         string                    `json:"history_path,omitempty"` // Task history JSONL file (default DefaultHistoryPath)
+	MaxRetries          int                       `json:"max_retries"`
+	Timeout             int                       `json:"timeout_seconds"`
+	ProjectOrchestrator ProjectOrchestratorConfig `json:"project_orchestrator"`
+
+	// Additional LLM backends (name -> settings); "ollama" at OllamaURL is always available
+	Providers      map[string]ProviderConfig `json:"providers,omitempty"`
+	ModelProviders map[string]string         `json:"model_providers,omitempty"` // model_name -> provider name (default: ollama)
+
+	// Sampling and context-window settings; the "default" entry applies to every task and agent
+	GenerationOptions map[string]GenerationOptions `json:"generation_options,omitempty"` // task_type -> options
+	AgentOptions      map[string]GenerationOptions `json:"agent_options,omitempty"`      // agent name -> options
+
+	// Model controls per thinking mode ("fast", "normal", "extended"); unset modes use built-in defaults
+	ThinkingModes map[string]ThinkingModeConfig `json:"thinking_modes,omitempty"`
+
+	// Pull referenced models that are not installed when the orchestrator starts
+	AutoPullModels bool `json:"auto_pull_models,omitempty"`
+
+	Scheduler SchedulerConfig `json:"scheduler"`
+
+	Discovery DiscoveryConfig `json:"discovery"`
+
+	Cache    CacheConfig    `json:"cache"`
+	Cassette CassetteConfig `json:"cassette"`
+}
+
+// GenerationOptions are model sampling settings passed to the backend
+// Unset fields keep the model's defaults
+type GenerationOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`     // Context window in tokens
+	NumPredict  int      `json:"num_predict,omitempty"` // Maximum tokens to generate (-1 = no limit)
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// TaskTypeConfig declares a task type backed by a prompt template file
+// The template uses text/template with {{.Input}}, {{.TaskType}} and {{.Vars.name}}.
+type TaskTypeConfig struct {
+	Model       string            `json:"model"`    // Falls back to models[task_type] when empty
+	Template    string            `json:"template"` // Path to the prompt template file (default: prompts_dir, then the built-in prompt)
+	Description string            `json:"description,omitempty"`
+	Vars        map[string]string `json:"vars,omitempty"` // Default template variables; request vars override them
+}
+
+// TaskModel returns the model for a task type, from task_types first, then models
+func (c *Config) TaskModel(taskType string) (string, bool) {
+	if tt, ok := c.TaskTypes[taskType]; ok && tt.Model != "" {
+		return tt.Model, true
+	}
+	model, ok := c.Models[taskType]
+	return model, ok
+}
+
+// ThinkingModeConfig maps a thinking mode onto model controls
+type ThinkingModeConfig struct {
+	Think   bool              `json:"think"`             // Ask reasoning models for a separate reasoning trace
+	Model   string            `json:"model,omitempty"`   // Model to try first in this mode, before the task's own
+	Options GenerationOptions `json:"options,omitempty"` // e.g. larger num_predict and num_ctx; task and agent settings win
+}
+
+// DefaultOptionsKey is the generation_options / agent_options entry applied before the specific one
+const DefaultOptionsKey = "default"
+
+// Merge returns o with every field set in override replacing its own
+func (o GenerationOptions) Merge(override GenerationOptions) GenerationOptions {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.NumCtx != 0 {
+		o.NumCtx = override.NumCtx
+	}
+	if override.NumPredict != 0 {
+		o.NumPredict = override.NumPredict
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if override.Stop != nil {
+		o.Stop = override.Stop
+	}
+	return o
+}
+
+// Map converts the options to the backend's options object, or nil if none are set
+func (o GenerationOptions) Map() map[string]interface{} {
+	options := make(map[string]interface{})
+	if o.Temperature != nil {
+		options["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		options["top_p"] = *o.TopP
+	}
+	if o.NumCtx != 0 {
+		options["num_ctx"] = o.NumCtx
+	}
+	if o.NumPredict != 0 {
+		options["num_predict"] = o.NumPredict
+	}
+	if o.Seed != nil {
+		options["seed"] = *o.Seed
+	}
+	if len(o.Stop) > 0 {
+		options["stop"] = o.Stop
+	}
+
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
+
+// TaskOptions returns the effective generation options for a task type
+func (c *Config) TaskOptions(taskType string) map[string]interface{} {
+	return c.GenerationOptions[DefaultOptionsKey].Merge(c.GenerationOptions[taskType]).Map()
+}
+
+// AgentGenerationOptions returns the effective generation options for an agent
+// ("requirements", "techstack", "scope", "qa", "testing", "documentation", "lead_agent", "planner")
+// Agent entries build on generation_options["default"], then agent_options["default"]
+func (c *Config) AgentGenerationOptions(agent string) map[string]interface{} {
+	return c.GenerationOptions[DefaultOptionsKey].
+		Merge(c.AgentOptions[DefaultOptionsKey]).
+		Merge(c.AgentOptions[agent]).
+		Map()
+}
+
+// SchedulerConfig limits concurrent LLM requests per Ollama host
+type SchedulerConfig struct {
+	MaxConcurrentPerHost int `json:"max_concurrent_per_host"` // 0 = DefaultMaxConcurrentPerHost
+}
+
+// DiscoveryConfig bounds the adaptive question flow of /discover sessions
+// Sessions stop early, once the model is confident in a verdict, only after MinQuestions answers
+type DiscoveryConfig struct {
+	MinQuestions int `json:"min_questions"` // 0 = task.DefaultMinDiscoverQuestions
+	MaxQuestions int `json:"max_questions"` // 0 = task.DefaultMaxDiscoverQuestions
+}
+
+// DefaultHistoryPath is where task results are persisted when HistoryPath is unset
+const DefaultHistoryPath = "./history/tasks.jsonl"
+
+// DefaultMaxConcurrentPerHost keeps one generation per GPU box, so requests queue by priority instead of thrashing
+const DefaultMaxConcurrentPerHost = 1
+
+// HostConcurrency returns the configured per-host request limit
+func (c *Config) HostConcurrency() int {
+	if c.Scheduler.MaxConcurrentPerHost > 0 {
+		return c.Scheduler.MaxConcurrentPerHost
+	}
+	return DefaultMaxConcurrentPerHost
+}
+
+// CassetteConfig holds LLM record/replay configuration
+// In record mode every LLM request and response is saved to Path; in replay mode
+// responses are served from Path and no model backend is contacted
+type CassetteConfig struct {
+	Mode string `json:"mode"` // "", "record" or "replay"
+	Path string `json:"path"`
+}
+
+// CacheConfig holds LLM response cache configuration
+type CacheConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Dir       string `json:"dir"`
+	TTLHours  int    `json:"ttl_hours"`   // 0 = entries never expire
+	MaxSizeMB int    `json:"max_size_mb"` // 0 = unlimited
+}
+
+// ProviderConfig describes an LLM backend
+type ProviderConfig struct {
+	Type      string `json:"type"` // "ollama" or "openai" (OpenAI-compatible /v1/chat/completions)
+	BaseURL   string `json:"base_url"`
+	APIKey    string `json:"api_key,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"` // Environment variable holding the API key
 }
 
 // ProjectOrchestratorConfig holds project orchestrator configuration
 type ProjectOrchestratorConfig struct {
-	Enabled              bool   `json:"enabled"`
-	ProjectsDir          string `json:"projects_dir"`
-	AutoTransition       bool   `json:"auto_transition"`
-	RequireHumanApproval bool   `json:"require_human_approval"`
-	LeadAgentModel       string `json:"lead_agent_model"`
+	Enabled              bool         `json:"enabled"`
+	ProjectsDir          string       `json:"projects_dir"`
+	AutoTransition       bool         `json:"auto_transition"`
+	RequireHumanApproval bool         `json:"require_human_approval"`
+	LeadAgentModel       string       `json:"lead_agent_model"`
+	FullRegeneration     bool         `json:"full_regeneration,omitempty"` // Regenerate every file on each CodeGen run instead of patching the existing project
+	CodeGenStrategy      string       `json:"codegen_strategy,omitempty"`  // CodeGenSingle (default) or CodeGenPerFile
+	Repair               RepairConfig `json:"repair"`
 }
 
+// RepairConfig bounds the CodeGen repair loop, which feeds build, runtime and test failures back to the code model
+type RepairConfig struct {
+	MaxIterations     int `json:"max_iterations"`      // 0 disables the loop
+	TimeBudgetSeconds int `json:"time_budget_seconds"` // Across all iterations of a CodeGen run; 0 = no limit
+}
+
+// Code generation strategies of the CodeGen phase
+const (
+	CodeGenSingle  = "single"   // The whole project from one prompt
+	CodeGenPerFile = "per_file" // One call per file of the approved plan, each shown the interfaces of the files before it
+)
+
+// DefaultProvider is the name of the built-in Ollama provider at OllamaURL (or the OllamaHosts pool)
+const DefaultProvider = "ollama"
+
 // Default configuration
 func defaultConfig() *Config {
 	return &Config{
-		OllamaURL: "http://localhost:11434",
+		OllamaURL:          "http://localhost:11434",
+		HealthCheckSeconds: 30,
 		Models: map[string]string{
 			"validate": "mistral:7b-instruct-v0.2-q4_K_M", // Idea validation
 			"review":   "llama3:8b",                       // Architecture review
@@ -38,12 +240,25 @@ func defaultConfig() *Config {
 		ArtifactsDir: "./artifacts",
 		MaxRetries:   2,
 		Timeout:      600, // 10 minutes per task
+		Cache: CacheConfig{
+			Enabled:   false,
+			Dir:       "./cache/llm",
+			TTLHours:  24 * 7,
+			MaxSizeMB: 512,
+		},
+		Cassette: CassetteConfig{
+			Path: "./cassettes/llm.json",
+		},
 		ProjectOrchestrator: ProjectOrchestratorConfig{
 			Enabled:              false,
 			ProjectsDir:          "./projects",
 			AutoTransition:       false,
 			RequireHumanApproval: true,
 			LeadAgentModel:       "llama3:8b",
+			Repair: RepairConfig{
+				MaxIterations:     3,
+				TimeBudgetSeconds: 900,
+			},
 		},
 	}
 }
@@ -86,6 +301,20 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.OllamaURL = ollamaURL
 	}
 
+	// Comma-separated list of Ollama hosts, e.g. "http://gpu1:11434,http://gpu2:11434"
+	if ollamaHosts := os.Getenv("OLLAMA_HOSTS"); ollamaHosts != "" {
+		cfg.OllamaHosts = nil
+		for _, host := range strings.Split(ollamaHosts, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				cfg.OllamaHosts = append(cfg.OllamaHosts, host)
+			}
+		}
+	}
+
+	if autoPull := os.Getenv("OLLAMA_AUTO_PULL"); autoPull != "" {
+		cfg.AutoPullModels = autoPull == "1" || strings.EqualFold(autoPull, "true")
+	}
+
 	if projectsDir := os.Getenv("PROJECTS_DIR"); projectsDir != "" {
 		cfg.ProjectOrchestrator.ProjectsDir = projectsDir
 	}
@@ -94,6 +323,18 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.ArtifactsDir = artifactsDir
 	}
 
+	if historyPath := os.Getenv("TASK_HISTORY_PATH"); historyPath != "" {
+		cfg.HistoryPath = historyPath
+	}
+
+	if mode := os.Getenv("LLM_CASSETTE_MODE"); mode != "" {
+		cfg.Cassette.Mode = mode
+	}
+
+	if cassettePath := os.Getenv("LLM_CASSETTE_PATH"); cassettePath != "" {
+		cfg.Cassette.Path = cassettePath
+	}
+
 	// Ensure directories exist
 	os.MkdirAll(cfg.ArtifactsDir, 0755)
 	os.MkdirAll(cfg.ProjectOrchestrator.ProjectsDir, 0755)
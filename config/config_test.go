@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func float(f float64) *float64 { return &f }
+func integer(i int) *int       { return &i }
+
+// TestGenerationOptionsMergeMap tests that overrides replace only the fields they set
+func TestGenerationOptionsMergeMap(t *testing.T) {
+	if got := (GenerationOptions{}).Map(); got != nil {
+		t.Errorf("empty options map to %v, want nil", got)
+	}
+
+	base := GenerationOptions{Temperature: float(0.7), NumCtx: 4096, Stop: []string{"###"}}
+	override := GenerationOptions{Temperature: float(0), Seed: integer(42), NumPredict: -1}
+	got := base.Merge(override).Map()
+	want := map[string]interface{}{
+		"temperature": 0.0, // A zero override still applies
+		"num_ctx":     4096,
+		"num_predict": -1,
+		"seed":        42,
+		"stop":        []string{"###"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+}
+
+// TestOptionResolution tests the default, task and agent layers of generation options
+func TestOptionResolution(t *testing.T) {
+	cfg := &Config{
+		GenerationOptions: map[string]GenerationOptions{
+			DefaultOptionsKey: {Temperature: float(0.5), NumCtx: 4096},
+			"code":            {Temperature: float(0.1)},
+		},
+		AgentOptions: map[string]GenerationOptions{
+			DefaultOptionsKey: {NumPredict: 512},
+			"qa":              {NumCtx: 16384},
+		},
+	}
+
+	tests := []struct {
+		name string
+		got  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{"task with options", cfg.TaskOptions("code"), map[string]interface{}{"temperature": 0.1, "num_ctx": 4096}},
+		{"task without options", cfg.TaskOptions("review"), map[string]interface{}{"temperature": 0.5, "num_ctx": 4096}},
+		{"agent with options", cfg.AgentGenerationOptions("qa"), map[string]interface{}{"temperature": 0.5, "num_ctx": 16384, "num_predict": 512}},
+		{"agent without options", cfg.AgentGenerationOptions("scope"), map[string]interface{}{"temperature": 0.5, "num_ctx": 4096, "num_predict": 512}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if got := (&Config{}).TaskOptions("code"); got != nil {
+		t.Errorf("no configured options should map to nil, got %v", got)
+	}
+}
@@ -0,0 +1,391 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheConfig configures the on-disk response cache
+type CacheConfig struct {
+	Dir      string        // Root directory for cache entries
+	TTL      time.Duration // Entries older than this are ignored and removed (0 = never expire)
+	MaxBytes int64         // Oldest entries are evicted once the store exceeds this size (0 = unlimited)
+}
+
+// CachedProvider wraps a provider with a content-addressed on-disk cache.
+// Entries are keyed by model, model digest, options, conversation context and
+// prompt, so a re-pulled model or changed options never serve stale answers.
+type CachedProvider struct {
+	inner Provider
+	cfg   CacheConfig
+
+	mu        sync.Mutex
+	sizeBytes int64
+
+	digestMu  sync.Mutex
+	digests   map[string]cachedDigest // model -> digest lookup
+	digestTTL time.Duration
+}
+
+// cachedDigest memoizes a model digest lookup
+type cachedDigest struct {
+	digest    string
+	fetchedAt time.Time
+}
+
+// cacheEntry is the JSON document stored per key
+type cacheEntry struct {
+	Key       string    `json:"key"`
+	Model     string    `json:"model"`
+	Digest    string    `json:"digest,omitempty"`
+	Text      string    `json:"text"`
+	Thinking  string    `json:"thinking,omitempty"`
+	Context   []int     `json:"context,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewCachedProvider creates a caching wrapper around inner
+func NewCachedProvider(inner Provider, cfg CacheConfig) (*CachedProvider, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	cp := &CachedProvider{
+		inner:     inner,
+		cfg:       cfg,
+		digests:   make(map[string]cachedDigest),
+		digestTTL: time.Minute,
+	}
+
+	// Measure the existing store so the size cap holds across restarts
+	files, err := cp.entries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+	for _, f := range files {
+		cp.sizeBytes += f.size
+	}
+
+	return cp, nil
+}
+
+// Complete serves the request from cache when possible, otherwise forwards it and stores the result
+// A cache hit is delivered to OnToken as a single chunk so streaming clients still see the output
+func (cp *CachedProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if CacheBypassed(ctx) {
+		return cp.inner.Complete(ctx, req)
+	}
+
+	digest := cp.modelDigest(req.Model)
+	key := cacheKey(req, digest)
+
+	if entry := cp.load(key); entry != nil {
+		recordCache(ctx, true)
+		if req.OnToken != nil {
+			req.OnToken(entry.Text)
+		}
+		return &Response{Text: entry.Text, Thinking: entry.Thinking, Model: entry.Model, Context: entry.Context}, nil
+	}
+
+	recordCache(ctx, false)
+
+	resp, err := cp.inner.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.store(&cacheEntry{
+		Key:       key,
+		Model:     req.Model,
+		Digest:    digest,
+		Text:      resp.Text,
+		Thinking:  resp.Thinking,
+		Context:   resp.Context,
+		CreatedAt: time.Now(),
+	})
+
+	return resp, nil
+}
+
+// ListModels delegates to the wrapped provider
+func (cp *CachedProvider) ListModels() ([]string, error) {
+	return cp.inner.ListModels()
+}
+
+// Ping delegates to the wrapped provider
+func (cp *CachedProvider) Ping() error {
+	return cp.inner.Ping()
+}
+
+// ModelDigest delegates to the wrapped provider when it reports digests
+func (cp *CachedProvider) ModelDigest(model string) (string, error) {
+	dp, ok := cp.inner.(DigestProvider)
+	if !ok {
+		return "", fmt.Errorf("provider does not report model digests")
+	}
+	return dp.ModelDigest(model)
+}
+
+// HostStatus delegates to the wrapped provider when it reports hosts
+func (cp *CachedProvider) HostStatus() []HostStatus {
+	if hr, ok := cp.inner.(HostReporter); ok {
+		return hr.HostStatus()
+	}
+	return nil
+}
+
+// cacheKey hashes everything that influences the model output
+func cacheKey(req *Request, digest string) string {
+	promptHash := sha256.Sum256([]byte(req.Prompt))
+
+	// json.Marshal sorts map keys, so equal options always hash the same
+	keyData, _ := json.Marshal(struct {
+		Model    string                 `json:"model"`
+		Digest   string                 `json:"digest"`
+		Options  map[string]interface{} `json:"options,omitempty"`
+		Context  []int                  `json:"context,omitempty"`
+		Format   json.RawMessage        `json:"format,omitempty"`
+		Messages []ChatMessage          `json:"messages,omitempty"`
+		Think    bool                   `json:"think,omitempty"`
+		Prompt   string                 `json:"prompt"`
+	}{
+		Model:    req.Model,
+		Digest:   digest,
+		Options:  req.Options,
+		Context:  req.Context,
+		Format:   req.Format,
+		Messages: req.Messages,
+		Think:    req.Think,
+		Prompt:   hex.EncodeToString(promptHash[:]),
+	})
+
+	sum := sha256.Sum256(keyData)
+	return hex.EncodeToString(sum[:])
+}
+
+// modelDigest looks up (and briefly memoizes) the digest of a model
+// Providers without digests key on the model name alone
+func (cp *CachedProvider) modelDigest(model string) string {
+	cp.digestMu.Lock()
+	cached, ok := cp.digests[model]
+	cp.digestMu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < cp.digestTTL {
+		return cached.digest
+	}
+
+	digest, err := cp.ModelDigest(model)
+	if err != nil {
+		digest = ""
+	}
+
+	cp.digestMu.Lock()
+	cp.digests[model] = cachedDigest{digest: digest, fetchedAt: time.Now()}
+	cp.digestMu.Unlock()
+
+	return digest
+}
+
+// entryPath returns the file for a key, sharded by its first two hex digits
+func (cp *CachedProvider) entryPath(key string) string {
+	return filepath.Join(cp.cfg.Dir, key[:2], key+".json")
+}
+
+// load reads a live entry, removing it if it has expired
+func (cp *CachedProvider) load(key string) *cacheEntry {
+	path := cp.entryPath(key)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		cp.remove(path)
+		return nil
+	}
+
+	if cp.cfg.TTL > 0 && time.Since(entry.CreatedAt) > cp.cfg.TTL {
+		cp.remove(path)
+		return nil
+	}
+
+	// Touch so size-cap eviction drops least recently used entries first
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return &entry
+}
+
+// store writes an entry atomically and enforces the size cap
+func (cp *CachedProvider) store(entry *cacheEntry) {
+	path := cp.entryPath(entry.Key)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("LLM cache: failed to encode entry: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("LLM cache: failed to create shard directory: %v", err)
+		return
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	var previous int64
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Printf("LLM cache: failed to write entry: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		log.Printf("LLM cache: failed to commit entry: %v", err)
+		return
+	}
+
+	cp.sizeBytes += int64(len(data)) - previous
+	if cp.cfg.MaxBytes > 0 && cp.sizeBytes > cp.cfg.MaxBytes {
+		cp.evictLocked()
+	}
+}
+
+// remove deletes an entry file and updates the size accounting
+func (cp *CachedProvider) remove(path string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if info, err := os.Stat(path); err == nil {
+		if os.Remove(path) == nil {
+			cp.sizeBytes -= info.Size()
+		}
+	}
+}
+
+// cacheFile is an entry on disk, used for size accounting and eviction
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists all entry files in the store
+func (cp *CachedProvider) entries() ([]cacheFile, error) {
+	var files []cacheFile
+
+	err := filepath.Walk(cp.cfg.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".json" {
+			files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// evictLocked removes least recently used entries until the store is back under
+// 90% of the cap, leaving headroom so every write doesn't trigger a rescan
+func (cp *CachedProvider) evictLocked() {
+	files, err := cp.entries()
+	if err != nil {
+		log.Printf("LLM cache: eviction scan failed: %v", err)
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	target := cp.cfg.MaxBytes * 9 / 10
+	evicted := 0
+	for _, f := range files {
+		if total <= target {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+			evicted++
+		}
+	}
+
+	cp.sizeBytes = total
+	log.Printf("LLM cache: evicted %d entries (size now %d bytes)", evicted, total)
+}
+
+// CacheStats counts cache hits and misses for the requests made under a context
+type CacheStats struct {
+	hits   int64
+	misses int64
+	parent *CacheStats
+}
+
+type cacheStatsKey struct{}
+type cacheBypassKey struct{}
+
+// WithCacheStats returns a context whose LLM requests are counted in stats.
+// Stats nest: a request counts towards every CacheStats attached up the context chain.
+func WithCacheStats(ctx context.Context, stats *CacheStats) context.Context {
+	if parent, ok := ctx.Value(cacheStatsKey{}).(*CacheStats); ok && parent != stats {
+		stats.parent = parent
+	}
+	return context.WithValue(ctx, cacheStatsKey{}, stats)
+}
+
+// WithoutCache returns a context whose LLM requests skip the cache (neither read nor written)
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether requests under ctx skip the cache
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// Hits returns the number of cache hits recorded so far
+func (s *CacheStats) Hits() int64 {
+	return atomic.LoadInt64(&s.hits)
+}
+
+// Misses returns the number of cache misses recorded so far
+func (s *CacheStats) Misses() int64 {
+	return atomic.LoadInt64(&s.misses)
+}
+
+// recordCache adds a hit or miss to every CacheStats attached to ctx
+func recordCache(ctx context.Context, hit bool) {
+	stats, _ := ctx.Value(cacheStatsKey{}).(*CacheStats)
+	for ; stats != nil; stats = stats.parent {
+		if hit {
+			atomic.AddInt64(&stats.hits, 1)
+		} else {
+			atomic.AddInt64(&stats.misses, 1)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingProvider returns a fixed response and counts calls
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	p.calls++
+	return &Response{Text: "answer to " + req.Prompt, Model: req.Model}, nil
+}
+
+func (p *countingProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *countingProvider) Ping() error                   { return nil }
+
+// TestCachedProviderHitMiss tests that identical requests are served from disk
+func TestCachedProviderHitMiss(t *testing.T) {
+	inner := &countingProvider{}
+	cache, err := NewCachedProvider(inner, CacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewCachedProvider() error = %v", err)
+	}
+
+	stats := &CacheStats{}
+	ctx := WithCacheStats(context.Background(), stats)
+
+	for i := 0; i < 2; i++ {
+		text, err := Generate(ctx, cache, "llama3:8b", "hello")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if text != "answer to hello" {
+			t.Errorf("Generate() = %q, want %q", text, "answer to hello")
+		}
+	}
+
+	if inner.calls != 1 {
+		t.Errorf("inner provider called %d times, want 1", inner.calls)
+	}
+	if stats.Hits() != 1 || stats.Misses() != 1 {
+		t.Errorf("stats = %d hits / %d misses, want 1 / 1", stats.Hits(), stats.Misses())
+	}
+
+	// Different options must not share an entry
+	cache.Complete(ctx, &Request{Model: "llama3:8b", Prompt: "hello", Options: map[string]interface{}{"temperature": 0.1}})
+	if inner.calls != 2 {
+		t.Errorf("request with options should miss, inner calls = %d", inner.calls)
+	}
+}
+
+// TestCachedProviderBypassAndTTL tests per-request bypass and expiry
+func TestCachedProviderBypassAndTTL(t *testing.T) {
+	inner := &countingProvider{}
+	cache, err := NewCachedProvider(inner, CacheConfig{Dir: t.TempDir(), TTL: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewCachedProvider() error = %v", err)
+	}
+
+	ctx := context.Background()
+	Generate(ctx, cache, "m", "p")
+	Generate(WithoutCache(ctx), cache, "m", "p")
+	if inner.calls != 2 {
+		t.Errorf("bypassed request should reach the provider, inner calls = %d", inner.calls)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	Generate(ctx, cache, "m", "p")
+	if inner.calls != 3 {
+		t.Errorf("expired entry should miss, inner calls = %d", inner.calls)
+	}
+}
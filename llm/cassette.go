@@ -0,0 +1,380 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrCassetteMismatch is returned by a Replayer when a request has no recorded response
+var ErrCassetteMismatch = errors.New("no recorded response matches request")
+
+// Cassette is a recorded sequence of LLM interactions, stored as JSON
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and the response (or error) it produced
+type Interaction struct {
+	Model           string                 `json:"model"`
+	Prompt          string                 `json:"prompt"`
+	Messages        []ChatMessage          `json:"messages,omitempty"`
+	Context         []int                  `json:"context,omitempty"`
+	Options         map[string]interface{} `json:"options,omitempty"`
+	Format          json.RawMessage        `json:"format,omitempty"`
+	Think           bool                   `json:"think,omitempty"`
+	Response        string                 `json:"response"`
+	Thinking        string                 `json:"thinking,omitempty"`
+	ResponseContext []int                  `json:"response_context,omitempty"`
+	Usage           *Usage                 `json:"usage,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the cassette atomically so an interrupted run never leaves a truncated file
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to commit cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Recorder wraps a provider and appends every request and response to a cassette file
+// The file is rewritten after each interaction, so a crashed run keeps what it recorded
+type Recorder struct {
+	inner    Provider
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder that writes to path, replacing any existing cassette
+func NewRecorder(inner Provider, path string) *Recorder {
+	return &Recorder{inner: inner, path: path}
+}
+
+// Complete forwards the request and records the outcome
+// Requests cancelled by the caller are not recorded, since they are not reproducible
+func (r *Recorder) Complete(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := r.inner.Complete(ctx, req)
+	if ctx.Err() != nil {
+		return resp, err
+	}
+
+	interaction := Interaction{
+		Model:    req.Model,
+		Prompt:   req.Prompt,
+		Messages: req.Messages,
+		Context:  req.Context,
+		Options:  req.Options,
+		Format:   req.Format,
+		Think:    req.Think,
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.Response = resp.Text
+		interaction.Thinking = resp.Thinking
+		interaction.ResponseContext = resp.Context
+		if !resp.Usage.IsZero() {
+			usage := resp.Usage
+			interaction.Usage = &usage
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	saveErr := r.cassette.Save(r.path)
+	r.mu.Unlock()
+
+	if saveErr != nil {
+		return nil, fmt.Errorf("cassette %s: %w", r.path, saveErr)
+	}
+
+	return resp, err
+}
+
+// ListModels delegates to the wrapped provider
+func (r *Recorder) ListModels() ([]string, error) {
+	return r.inner.ListModels()
+}
+
+// Ping delegates to the wrapped provider
+func (r *Recorder) Ping() error {
+	return r.inner.Ping()
+}
+
+// HostStatus delegates to the wrapped provider when it reports hosts
+func (r *Recorder) HostStatus() []HostStatus {
+	if hr, ok := r.inner.(HostReporter); ok {
+		return hr.HostStatus()
+	}
+	return nil
+}
+
+// Replayer serves responses from a cassette instead of calling a model.
+// A request matches a recorded interaction with the same model, prompt or messages,
+// options, format, think flag and context; identical requests are answered in recording order, each
+// interaction at most once. Anything else fails with ErrCassetteMismatch.
+type Replayer struct {
+	path         string
+	interactions []Interaction
+	used         []bool
+	mu           sync.Mutex
+}
+
+// NewReplayer loads the cassette at path for replay
+func NewReplayer(path string) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replayer{
+		path:         path,
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// Complete returns the next unused recorded response for an identical request
+// The recorded text is delivered to OnToken as a single chunk
+func (rp *Replayer) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key := requestKey(req)
+
+	rp.mu.Lock()
+	index := -1
+	for i, in := range rp.interactions {
+		if !rp.used[i] && in.key() == key {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		err := rp.mismatchError(req)
+		rp.mu.Unlock()
+		return nil, err
+	}
+	rp.used[index] = true
+	in := rp.interactions[index]
+	rp.mu.Unlock()
+
+	if in.Error != "" {
+		return nil, fmt.Errorf("%s (replayed from cassette)", in.Error)
+	}
+
+	if req.OnToken != nil {
+		req.OnToken(in.Response)
+	}
+
+	resp := &Response{Text: in.Response, Thinking: in.Thinking, Model: in.Model, Context: in.ResponseContext}
+	if in.Usage != nil {
+		resp.Usage = *in.Usage
+	}
+	return resp, nil
+}
+
+// ListModels returns the models that appear in the cassette
+func (rp *Replayer) ListModels() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, in := range rp.interactions {
+		seen[in.Model] = true
+	}
+
+	models := make([]string, 0, len(seen))
+	for m := range seen {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Ping always succeeds; a replayer needs no backend
+func (rp *Replayer) Ping() error {
+	return nil
+}
+
+// Remaining returns the number of recorded interactions not replayed yet
+func (rp *Replayer) Remaining() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	remaining := 0
+	for _, used := range rp.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// mismatchError explains why req has no recorded response, comparing it with
+// the next unused interaction for the same model (the most likely intended match)
+// Callers must hold rp.mu
+func (rp *Replayer) mismatchError(req *Request) error {
+	for i, in := range rp.interactions {
+		if rp.used[i] || in.Model != req.Model {
+			continue
+		}
+
+		var diff string
+		switch {
+		case in.Prompt != req.Prompt:
+			diff = promptDiff(in.Prompt, req.Prompt)
+		case messagesKey(in.Messages) != messagesKey(req.Messages):
+			diff = messagesDiff(in.Messages, req.Messages)
+		case optionsKey(in.Options) != optionsKey(req.Options):
+			diff = "prompt matches but options differ"
+		case string(compactJSON(in.Format)) != string(compactJSON(req.Format)):
+			diff = "prompt matches but response format differs"
+		case in.Think != req.Think:
+			diff = fmt.Sprintf("prompt matches but think flag differs (recorded %t)", in.Think)
+		default:
+			diff = "prompt matches but conversation context differs"
+		}
+
+		return fmt.Errorf("cassette %s: %w (model %s, compared with interaction #%d: %s)",
+			rp.path, ErrCassetteMismatch, req.Model, i+1, diff)
+	}
+
+	return fmt.Errorf("cassette %s: %w (model %s has no unused interactions left; prompt starts %q)",
+		rp.path, ErrCassetteMismatch, req.Model, firstLine(req.Prompt))
+}
+
+// key identifies a recorded interaction for matching
+func (in *Interaction) key() string {
+	return interactionKey(in.Model, in.Prompt, in.Messages, in.Options, in.Format, in.Think, in.Context)
+}
+
+// requestKey identifies a request for matching
+func requestKey(req *Request) string {
+	return interactionKey(req.Model, req.Prompt, req.Messages, req.Options, req.Format, req.Think, req.Context)
+}
+
+// interactionKey builds a matching key from everything that influences the response
+// json.Marshal sorts map keys, so equal options always produce the same key
+// Formats are compacted when marshalled, so a re-indented cassette still matches
+func interactionKey(model, prompt string, messages []ChatMessage, options map[string]interface{}, format json.RawMessage, think bool, context []int) string {
+	data, _ := json.Marshal(struct {
+		Model    string                 `json:"model"`
+		Prompt   string                 `json:"prompt"`
+		Messages []ChatMessage          `json:"messages,omitempty"`
+		Options  map[string]interface{} `json:"options,omitempty"`
+		Format   json.RawMessage        `json:"format,omitempty"`
+		Think    bool                   `json:"think,omitempty"`
+		Context  []int                  `json:"context,omitempty"`
+	}{model, prompt, messages, options, format, think, context})
+	return string(data)
+}
+
+// messagesKey encodes a chat history for comparison
+func messagesKey(messages []ChatMessage) string {
+	data, _ := json.Marshal(messages)
+	return string(data)
+}
+
+// optionsKey encodes generation options for comparison
+func optionsKey(options map[string]interface{}) string {
+	data, _ := json.Marshal(options)
+	return string(data)
+}
+
+// compactJSON strips insignificant whitespace so formats compare by content
+func compactJSON(data json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// messagesDiff describes the first chat message at which two histories differ
+func messagesDiff(recorded, actual []ChatMessage) string {
+	for i := 0; i < len(recorded) && i < len(actual); i++ {
+		if recorded[i] == actual[i] {
+			continue
+		}
+		if recorded[i].Role != actual[i].Role {
+			return fmt.Sprintf("message %d role differs: recorded %s, got %s", i+1, recorded[i].Role, actual[i].Role)
+		}
+		return fmt.Sprintf("message %d (%s): %s", i+1, actual[i].Role, promptDiff(recorded[i].Content, actual[i].Content))
+	}
+	return fmt.Sprintf("recorded %d messages, got %d", len(recorded), len(actual))
+}
+
+// promptDiff describes the first line at which two prompts differ
+func promptDiff(recorded, actual string) string {
+	recordedLines := strings.Split(recorded, "\n")
+	actualLines := strings.Split(actual, "\n")
+
+	for i := 0; i < len(recordedLines) || i < len(actualLines); i++ {
+		var want, got string
+		if i < len(recordedLines) {
+			want = recordedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want != got || i >= len(recordedLines) || i >= len(actualLines) {
+			return fmt.Sprintf("prompt differs at line %d: recorded %q, got %q", i+1, truncate(want, 120), truncate(got, 120))
+		}
+	}
+
+	return "prompts are identical"
+}
+
+// firstLine returns the first line of s, truncated for error messages
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return truncate(s, 120)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -2,10 +2,14 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,6 +17,7 @@ import (
 type Client struct {
 	baseURL string
 	client  *http.Client
+	noThink sync.Map // Models that rejected the think flag
 }
 
 // NewClient creates a new Ollama client
@@ -27,59 +32,157 @@ func NewClient(baseURL string, timeoutSeconds int) *Client {
 
 // GenerateRequest represents an Ollama generation request
 type GenerateRequest struct {
-	Model   string `json:"model"`
-	Prompt  string `json:"prompt"`
-	Stream  bool   `json:"stream"`
-	Context []int  `json:"context,omitempty"`
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	Stream  bool                   `json:"stream"`
+	Context []int                  `json:"context,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+	Format  json.RawMessage        `json:"format,omitempty"`
+	Think   bool                   `json:"think,omitempty"`
 }
 
 // GenerateResponse represents an Ollama generation response
+// When streaming, one of these is received per NDJSON line
 type GenerateResponse struct {
-	Model     string `json:"model"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
-	Context   []int  `json:"context,omitempty"`
-	TotalDuration int64 `json:"total_duration,omitempty"`
+	Model           string `json:"model"`
+	Response        string `json:"response"`
+	Thinking        string `json:"thinking,omitempty"` // Reasoning trace when think is set
+	Done            bool   `json:"done"`
+	Context         []int  `json:"context,omitempty"`
+	TotalDuration   int64  `json:"total_duration,omitempty"` // Nanoseconds
+	LoadDuration    int64  `json:"load_duration,omitempty"`  // Nanoseconds
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
+	Error           string `json:"error,omitempty"`
 }
 
-// Generate sends a prompt to Ollama and returns the response
-func (c *Client) Generate(model, prompt string) (string, error) {
-	req := GenerateRequest{
-		Model:  model,
-		Prompt: prompt,
-		Stream: false, // Non-streaming for simplicity in Phase 1
+// ChatRequest represents an Ollama /api/chat request
+type ChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ChatMessage          `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+	Format   json.RawMessage        `json:"format,omitempty"`
+	Think    bool                   `json:"think,omitempty"`
+}
+
+// ChatResponse represents an Ollama /api/chat response
+// When streaming, one of these is received per NDJSON line
+type ChatResponse struct {
+	Model           string              `json:"model"`
+	Message         ChatResponseMessage `json:"message"`
+	Done            bool                `json:"done"`
+	TotalDuration   int64               `json:"total_duration,omitempty"` // Nanoseconds
+	LoadDuration    int64               `json:"load_duration,omitempty"`  // Nanoseconds
+	PromptEvalCount int                 `json:"prompt_eval_count,omitempty"`
+	EvalCount       int                 `json:"eval_count,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// ChatResponseMessage is the assistant message of a /api/chat response
+type ChatResponseMessage struct {
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Thinking string `json:"thinking,omitempty"` // Reasoning trace when think is set
+}
+
+// Complete runs a request against Ollama's /api/chat endpoint when it carries
+// a message history, and against /api/generate otherwise
+// A model that rejects the think flag is retried without it, and the flag is
+// dropped for that model from then on.
+func (c *Client) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if _, cannotThink := c.noThink.Load(req.Model); req.Think && cannotThink {
+		req = withoutThink(req)
 	}
 
-	jsonData, err := json.Marshal(req)
+	resp, err := c.complete(ctx, req)
+	if err != nil && req.Think && strings.Contains(err.Error(), "does not support thinking") {
+		log.Printf("LLM: %s does not support thinking, continuing without it", req.Model)
+		c.noThink.Store(req.Model, true)
+		return c.complete(ctx, withoutThink(req))
+	}
+	return resp, err
+}
+
+// withoutThink returns a copy of req with the think flag cleared
+func withoutThink(req *Request) *Request {
+	plain := *req
+	plain.Think = false
+	return &plain
+}
+
+// complete runs one request on the endpoint matching its shape
+func (c *Client) complete(ctx context.Context, req *Request) (*Response, error) {
+	if len(req.Messages) > 0 {
+		return c.chat(ctx, req)
+	}
+
+	genResp, err := c.generate(ctx, GenerateRequest{
+		Model:   req.Model,
+		Prompt:  req.Prompt,
+		Context: req.Context,
+		Options: req.Options,
+		Format:  req.Format,
+		Think:   req.Think,
+	}, req.OnToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	resp, err := c.client.Post(
-		c.baseURL+"/api/generate",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	return &Response{
+		Text:     genResp.Response,
+		Thinking: genResp.Thinking,
+		Model:    genResp.Model,
+		Context:  genResp.Context,
+		Usage: Usage{
+			PromptTokens:     genResp.PromptEvalCount,
+			CompletionTokens: genResp.EvalCount,
+			TotalSeconds:     time.Duration(genResp.TotalDuration).Seconds(),
+			LoadSeconds:      time.Duration(genResp.LoadDuration).Seconds(),
+		},
+	}, nil
+}
+
+// ListModels returns available models from Ollama
+func (c *Client) ListModels() ([]string, error) {
+	tags, err := c.tags()
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+	models := make([]string, len(tags))
+	for i, m := range tags {
+		models[i] = m.Name
 	}
 
-	var genResp GenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	return models, nil
+}
+
+// ModelDigest returns the digest of an installed model
+// A model name without a tag matches ":latest", as in Ollama itself
+func (c *Client) ModelDigest(model string) (string, error) {
+	tags, err := c.tags()
+	if err != nil {
+		return "", err
 	}
 
-	return genResp.Response, nil
+	for _, m := range tags {
+		if m.Name == model || m.Name == model+":latest" {
+			return m.Digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("model %s not found", model)
 }
 
-// ListModels returns available models from Ollama
-func (c *Client) ListModels() ([]string, error) {
+// modelTag is one entry of Ollama's /api/tags listing
+type modelTag struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
+
+// tags fetches the installed model list from /api/tags
+func (c *Client) tags() ([]modelTag, error) {
 	resp, err := c.client.Get(c.baseURL + "/api/tags")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list models: %w", err)
@@ -87,116 +190,185 @@ func (c *Client) ListModels() ([]string, error) {
 	defer resp.Body.Close()
 
 	var result struct {
-		Models []struct {
-			Name string `json:"name"`
-		} `json:"models"`
+		Models []modelTag `json:"models"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode models: %w", err)
 	}
 
-	models := make([]string, len(result.Models))
-	for i, m := range result.Models {
-		models[i] = m.Name
+	return result.Models, nil
+}
+
+// generate posts a request to /api/generate
+// With a token handler the request is streamed and the NDJSON chunks are
+// accumulated into a single response; otherwise one JSON object is decoded.
+func (c *Client) generate(ctx context.Context, req GenerateRequest, onToken TokenHandler) (*GenerateResponse, error) {
+	req.Stream = onToken != nil
+
+	resp, err := c.post(ctx, "/api/generate", req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return models, nil
+	if !req.Stream {
+		var genResp GenerateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &genResp, nil
+	}
+
+	return readStream(resp.Body, onToken)
 }
 
-// GenerateWithContext sends a prompt to Ollama with conversation context and returns both response and new context
-func (c *Client) GenerateWithContext(model, prompt string, context []int) (string, []int, error) {
-	req := GenerateRequest{
-		Model:   model,
-		Prompt:  prompt,
-		Stream:  false,
-		Context: context,
+// readStream decodes Ollama's NDJSON stream, forwarding each chunk to onToken
+func readStream(body io.Reader, onToken TokenHandler) (*GenerateResponse, error) {
+	var full, thinking strings.Builder
+	decoder := json.NewDecoder(body)
+
+	for {
+		var chunk GenerateResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("stream ended before model finished")
+			}
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+
+		thinking.WriteString(chunk.Thinking)
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			onToken(chunk.Response)
+		}
+
+		if chunk.Done {
+			// The final chunk carries context and timings but no text
+			chunk.Response = full.String()
+			chunk.Thinking = thinking.String()
+			return &chunk, nil
+		}
 	}
+}
 
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+// chat posts a message history to /api/chat, streaming like generate
+func (c *Client) chat(ctx context.Context, req *Request) (*Response, error) {
+	chatReq := ChatRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   req.OnToken != nil,
+		Options:  req.Options,
+		Format:   req.Format,
+		Think:    req.Think,
 	}
 
-	resp, err := c.client.Post(
-		c.baseURL+"/api/generate",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.post(ctx, "/api/chat", chatReq)
 	if err != nil {
-		return "", nil, fmt.Errorf("ollama request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", nil, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+	var chatResp *ChatResponse
+	if chatReq.Stream {
+		chatResp, err = readChatStream(resp.Body, req.OnToken)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		chatResp = &ChatResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(chatResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
 	}
 
-	var genResp GenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
-		return "", nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return &Response{
+		Text:     chatResp.Message.Content,
+		Thinking: chatResp.Message.Thinking,
+		Model:    chatResp.Model,
+		Usage: Usage{
+			PromptTokens:     chatResp.PromptEvalCount,
+			CompletionTokens: chatResp.EvalCount,
+			TotalSeconds:     time.Duration(chatResp.TotalDuration).Seconds(),
+			LoadSeconds:      time.Duration(chatResp.LoadDuration).Seconds(),
+		},
+	}, nil
+}
 
-	return genResp.Response, genResp.Context, nil
+// readChatStream decodes /api/chat's NDJSON stream, forwarding each chunk to onToken
+func readChatStream(body io.Reader, onToken TokenHandler) (*ChatResponse, error) {
+	var full, thinking strings.Builder
+	decoder := json.NewDecoder(body)
+
+	for {
+		var chunk ChatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("stream ended before model finished")
+			}
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+
+		thinking.WriteString(chunk.Message.Thinking)
+		if chunk.Message.Content != "" {
+			full.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+
+		if chunk.Done {
+			chunk.Message.Content = full.String()
+			chunk.Message.Thinking = thinking.String()
+			return &chunk, nil
+		}
+	}
 }
 
-// GenerateWithThinking sends a prompt to Ollama with specified thinking mode
-// Thinking mode affects the system prompt to control reasoning depth
-func (c *Client) GenerateWithThinking(model, prompt, thinkingMode string) (string, error) {
-	// Add thinking mode system prompt prefix
-	enhancedPrompt := addThinkingModePrefix(prompt, thinkingMode)
+// post sends a JSON request to an Ollama endpoint and checks the status
+// A 404 means the model is not installed and is reported as ErrModelNotFound.
+// The caller must close the response body.
+func (c *Client) post(ctx context.Context, path string, payload interface{}) (*http.Response, error) {
+	return c.postWith(ctx, c.client, path, payload)
+}
 
-	req := GenerateRequest{
-		Model:  model,
-		Prompt: enhancedPrompt,
-		Stream: false,
+// postWith is post using the given HTTP client
+func (c *Client) postWith(ctx context.Context, client *http.Client, path string, payload interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	jsonData, err := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Post(
-		c.baseURL+"/api/generate",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return nil, fmt.Errorf("ollama request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusNotFound {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: ollama: %s", ErrModelNotFound, strings.TrimSpace(string(body)))
 	}
 
-	var genResp GenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return genResp.Response, nil
-}
-
-// addThinkingModePrefix adds appropriate system prompt based on thinking mode
-func addThinkingModePrefix(prompt, thinkingMode string) string {
-	var prefix string
-
-	switch thinkingMode {
-	case "fast":
-		prefix = "[FAST MODE: Provide direct, concise responses. Skip detailed reasoning.]\n\n"
-	case "extended":
-		prefix = "[EXTENDED THINKING MODE: Think step-by-step. Consider edge cases, alternatives, and potential issues. Provide thorough reasoning before answering.]\n\n"
-	case "normal":
-		fallthrough
-	default:
-		prefix = "[NORMAL MODE: Provide balanced responses with clear reasoning.]\n\n"
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return prefix + prompt
+	return resp, nil
 }
 
 // Ping checks if Ollama is accessible
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOllamaStream serves /api/generate and /api/chat as NDJSON streams
+// Model "broken" fails mid-stream and model "cut" ends before the done chunk.
+func fakeOllamaStream(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Errorf("%s request with a token handler was not streamed", r.URL.Path)
+		}
+
+		chunk := func(text string) string {
+			if r.URL.Path == "/api/chat" {
+				return fmt.Sprintf(`{"model":%q,"message":{"role":"assistant","content":%q},"done":false}`, req.Model, text)
+			}
+			return fmt.Sprintf(`{"model":%q,"response":%q,"done":false}`, req.Model, text)
+		}
+
+		for _, text := range []string{"Hel", "lo", " world"} {
+			fmt.Fprintln(w, chunk(text))
+		}
+		switch req.Model {
+		case "broken":
+			fmt.Fprintln(w, `{"error":"model runner has unexpectedly stopped"}`)
+		case "cut":
+		default:
+			fmt.Fprintf(w, `{"model":%q,"done":true,"prompt_eval_count":7,"eval_count":3,"total_duration":2000000000,"load_duration":500000000}`+"\n", req.Model)
+		}
+	}))
+}
+
+// TestClientStreaming tests the /api/generate and /api/chat stream readers
+func TestClientStreaming(t *testing.T) {
+	server := fakeOllamaStream(t)
+	defer server.Close()
+	client := NewClient(server.URL, 5)
+
+	for _, endpoint := range []string{"generate", "chat"} {
+		request := func(model string, onToken TokenHandler) *Request {
+			req := &Request{Model: model, Prompt: "hi", OnToken: onToken}
+			if endpoint == "chat" {
+				req.Prompt = ""
+				req.Messages = []ChatMessage{{Role: RoleUser, Content: "hi"}}
+			}
+			return req
+		}
+
+		t.Run(endpoint, func(t *testing.T) {
+			var tokens []string
+			resp, err := client.Complete(context.Background(), request("llama3", func(chunk string) { tokens = append(tokens, chunk) }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"Hel", "lo", " world"}; !reflect.DeepEqual(tokens, want) {
+				t.Errorf("tokens = %q, want %q", tokens, want)
+			}
+			if resp.Text != "Hello world" || resp.Model != "llama3" {
+				t.Errorf("response = %q from %s", resp.Text, resp.Model)
+			}
+			want := Usage{PromptTokens: 7, CompletionTokens: 3, TotalSeconds: 2, LoadSeconds: 0.5}
+			if resp.Usage != want {
+				t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+			}
+
+			noop := func(string) {}
+			if _, err := client.Complete(context.Background(), request("broken", noop)); err == nil || !strings.Contains(err.Error(), "unexpectedly stopped") {
+				t.Errorf("mid-stream error = %v", err)
+			}
+			if _, err := client.Complete(context.Background(), request("cut", noop)); err == nil || !strings.Contains(err.Error(), "stream ended") {
+				t.Errorf("truncated stream error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// PullProgress is one status update while a model downloads
+type PullProgress struct {
+	Model     string `json:"model"`
+	Host      string `json:"host,omitempty"` // Pool host being pulled to
+	Status    string `json:"status"`         // e.g. "pulling manifest", "downloading", "success"
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`     // Bytes of the layer being downloaded
+	Completed int64  `json:"completed,omitempty"` // Bytes downloaded so far
+}
+
+// PullHandler receives progress updates while a model downloads
+type PullHandler func(progress PullProgress)
+
+// ModelPuller is implemented by providers that can download models on demand
+type ModelPuller interface {
+	PullModel(ctx context.Context, model string, onProgress PullHandler) error
+}
+
+// HasModel reports whether model is in installed
+// A model name without a tag matches ":latest", as in Ollama itself
+func HasModel(installed []string, model string) bool {
+	for _, m := range installed {
+		if m == model || (!strings.Contains(model, ":") && m == model+":latest") {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingModels returns the models that are not in installed (deduplicated, sorted)
+func MissingModels(installed, models []string) []string {
+	seen := make(map[string]bool)
+	var missing []string
+	for _, model := range models {
+		if model == "" || seen[model] {
+			continue
+		}
+		seen[model] = true
+		if !HasModel(installed, model) {
+			missing = append(missing, model)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// pullChunk is one NDJSON line of Ollama's /api/pull stream
+type pullChunk struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// PullModel downloads a model through Ollama's /api/pull, reporting each status line
+// Downloads can take much longer than a generation, so the client timeout does not apply;
+// cancel ctx to abort.
+func (c *Client) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	resp, err := c.postWith(ctx, &http.Client{Transport: c.client.Transport}, "/api/pull", map[string]interface{}{
+		"model":  model,
+		"stream": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", model, err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk pullChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", model, chunk.Error)
+		}
+
+		if onProgress != nil {
+			onProgress(PullProgress{
+				Model:     model,
+				Status:    chunk.Status,
+				Digest:    chunk.Digest,
+				Total:     chunk.Total,
+				Completed: chunk.Completed,
+			})
+		}
+		if chunk.Status == "success" {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read pull progress: %w", err)
+	}
+	return fmt.Errorf("pull of %s ended without success", model)
+}
+
+// PullModel downloads the model on every reachable host that doesn't have it yet,
+// one host at a time, then refreshes those hosts so requests can be routed to them
+func (p *Pool) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	p.mu.Lock()
+	var targets []*poolHost
+	for _, host := range p.hosts {
+		if host.state == HostStateUnhealthy || (host.state == HostStateHealthy && host.canServe(model)) {
+			continue
+		}
+		targets = append(targets, host)
+	}
+	p.mu.Unlock()
+
+	for _, host := range targets {
+		puller, ok := host.provider.(ModelPuller)
+		if !ok {
+			continue
+		}
+
+		hostName := host.name
+		err := puller.PullModel(ctx, model, func(progress PullProgress) {
+			progress.Host = hostName
+			if onProgress != nil {
+				onProgress(progress)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("host %s: %w", host.name, err)
+		}
+		p.checkHost(host)
+	}
+
+	return nil
+}
+
+// PullModel asks the provider serving model to download it
+func (r *Router) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	name, p := r.ProviderFor(model)
+	puller, ok := p.(ModelPuller)
+	if !ok {
+		return fmt.Errorf("provider %s cannot pull models", name)
+	}
+	return puller.PullModel(ctx, model, onProgress)
+}
+
+// PullModel delegates to the wrapped provider when it can pull models
+func (cp *CachedProvider) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	puller, ok := cp.inner.(ModelPuller)
+	if !ok {
+		return fmt.Errorf("provider cannot pull models")
+	}
+	return puller.PullModel(ctx, model, onProgress)
+}
+
+// PullModel delegates to the wrapped provider when it can pull models
+func (r *Recorder) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	puller, ok := r.inner.(ModelPuller)
+	if !ok {
+		return fmt.Errorf("provider cannot pull models")
+	}
+	return puller.PullModel(ctx, model, onProgress)
+}
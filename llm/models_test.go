@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestMissingModels tests that untagged references match ":latest"
+func TestMissingModels(t *testing.T) {
+	installed := []string{"llama3:8b", "mistral:latest"}
+	refs := []string{"llama3:8b", "mistral", "mistral:7b-instruct", "deepseek-coder:6.7b-instruct", "llama3:8b"}
+
+	want := []string{"deepseek-coder:6.7b-instruct", "mistral:7b-instruct"}
+	if got := MissingModels(installed, refs); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingModels() = %v, want %v", got, want)
+	}
+}
+
+// TestClientPullModel tests that pull progress is streamed and an error line fails the pull
+func TestClientPullModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		if req.Model == "nope:1b" {
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":40}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 5)
+	var statuses []string
+	err := client.PullModel(context.Background(), "llama3:8b", func(p PullProgress) {
+		statuses = append(statuses, p.Status)
+		if p.Status == "downloading" && (p.Completed != 40 || p.Total != 100) {
+			t.Errorf("progress = %d/%d, want 40/100", p.Completed, p.Total)
+		}
+	})
+	if err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+	if want := []string{"pulling manifest", "downloading", "success"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+
+	if err := client.PullModel(context.Background(), "nope:1b", nil); err == nil {
+		t.Errorf("PullModel() of an unknown model should fail")
+	}
+}
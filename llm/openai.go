@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIClient talks to OpenAI-compatible /v1/chat/completions servers
+// (llama.cpp server, vLLM, LM Studio, ...)
+type OpenAIClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewOpenAIClient creates a client for an OpenAI-compatible server
+// baseURL is the server root (e.g. http://localhost:1234); apiKey may be empty
+func NewOpenAIClient(baseURL, apiKey string, timeoutSeconds int) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL: strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1"),
+		apiKey:  apiKey,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSeconds) * time.Second,
+		},
+	}
+}
+
+// chatCompletionRequest represents a /v1/chat/completions request
+type chatCompletionRequest struct {
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"` // Set when streaming
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+
+	// Sampling settings, from the Ollama-style request options
+	Temperature interface{} `json:"temperature,omitempty"`
+	TopP        interface{} `json:"top_p,omitempty"`
+	Seed        interface{} `json:"seed,omitempty"`
+	Stop        interface{} `json:"stop,omitempty"`
+	MaxTokens   int         `json:"max_tokens,omitempty"`
+}
+
+// streamOptions asks the server to send token usage on the last streamed chunk,
+// which OpenAI, vLLM and llama.cpp leave out otherwise
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// applyOptions maps Ollama-style generation options onto the chat completion fields
+// num_predict becomes max_tokens (-1, no limit, is left unset); num_ctx has no equivalent
+// and is set on the server instead
+func (r *chatCompletionRequest) applyOptions(options map[string]interface{}) {
+	r.Temperature = options["temperature"]
+	r.TopP = options["top_p"]
+	r.Seed = options["seed"]
+	r.Stop = options["stop"]
+
+	switch n := options["num_predict"].(type) {
+	case int:
+		r.MaxTokens = n
+	case float64: // Options decoded from JSON
+		r.MaxTokens = int(n)
+	}
+	if r.MaxTokens < 0 {
+		r.MaxTokens = 0
+	}
+}
+
+// responseFormat constrains a chat completion to JSON, optionally to a schema
+type responseFormat struct {
+	Type       string            `json:"type"` // "json_object" or "json_schema"
+	JSONSchema *jsonSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// jsonSchemaFormat names the schema of a json_schema response format
+type jsonSchemaFormat struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// toResponseFormat maps an Ollama-style format ("json" or a schema) onto response_format
+func toResponseFormat(format json.RawMessage) *responseFormat {
+	if len(format) == 0 {
+		return nil
+	}
+	if string(format) == `"json"` {
+		return &responseFormat{Type: "json_object"}
+	}
+	return &responseFormat{
+		Type:       "json_schema",
+		JSONSchema: &jsonSchemaFormat{Name: "response", Schema: format},
+	}
+}
+
+// chatCompletionResponse covers both full responses and streamed chunks
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      chatCompletionMessage `json:"message"`
+		Delta        chatCompletionMessage `json:"delta"`
+		FinishReason *string               `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage,omitempty"` // Sent on full responses, and on the last chunk by servers that support it
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// chatCompletionMessage is a response message or streamed delta
+// Reasoning servers (DeepSeek, vLLM) return the trace in reasoning_content
+type chatCompletionMessage struct {
+	Role             string `json:"role"`
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// chatUsage is the token accounting of a chat completion
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// toUsage converts reported token counts; the server reports no timings
+func (u *chatUsage) toUsage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
+
+// Complete sends the chat history, or the prompt as a single user message
+// Ollama-style conversation context is not supported and is ignored
+func (c *OpenAIClient) Complete(ctx context.Context, req *Request) (*Response, error) {
+	messages := req.Messages
+	if len(messages) == 0 {
+		messages = []ChatMessage{{Role: "user", Content: req.Prompt}}
+	}
+
+	chatReq := chatCompletionRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Stream:         req.OnToken != nil,
+		ResponseFormat: toResponseFormat(req.Format),
+	}
+	if chatReq.Stream {
+		chatReq.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	chatReq.applyOptions(req.Options)
+
+	jsonData, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.authorize(httpReq)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-compatible request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("openai-compatible server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if !chatReq.Stream {
+		var chatResp chatCompletionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chatResp.Error != nil {
+			return nil, fmt.Errorf("openai-compatible server error: %s", chatResp.Error.Message)
+		}
+		if len(chatResp.Choices) == 0 {
+			return nil, fmt.Errorf("openai-compatible server returned no choices")
+		}
+		return &Response{
+			Text:     chatResp.Choices[0].Message.Content,
+			Thinking: chatResp.Choices[0].Message.ReasoningContent,
+			Model:    chatResp.Model,
+			Usage:    chatResp.Usage.toUsage(),
+		}, nil
+	}
+
+	return readSSEStream(resp.Body, req.OnToken)
+}
+
+// readSSEStream decodes a server-sent events stream of chat completion chunks
+func readSSEStream(body io.Reader, onToken TokenHandler) (*Response, error) {
+	var full, thinking strings.Builder
+	var model string
+	var usage *chatUsage
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return &Response{Text: full.String(), Thinking: thinking.String(), Model: model, Usage: usage.toUsage()}, nil
+		}
+
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("openai-compatible stream error: %s", chunk.Error.Message)
+		}
+
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			thinking.WriteString(choice.Delta.ReasoningContent)
+			if choice.Delta.Content != "" {
+				full.WriteString(choice.Delta.Content)
+				onToken(choice.Delta.Content)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return nil, fmt.Errorf("stream ended before model finished")
+}
+
+// ListModels returns available models from /v1/models
+func (c *OpenAIClient) ListModels() ([]string, error) {
+	httpReq, err := http.NewRequest(http.MethodGet, c.baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(httpReq)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai-compatible server returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]string, len(result.Data))
+	for i, m := range result.Data {
+		models[i] = m.ID
+	}
+
+	return models, nil
+}
+
+// Ping checks if the server is accessible
+func (c *OpenAIClient) Ping() error {
+	if _, err := c.ListModels(); err != nil {
+		return fmt.Errorf("openai-compatible server not accessible: %w", err)
+	}
+	return nil
+}
+
+// authorize adds the bearer token when an API key is configured
+func (c *OpenAIClient) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOpenAIClientOptions tests that generation options reach the chat completions request
+func TestOpenAIClientOptions(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"model": "m", "choices": [{"message": {"role": "assistant", "content": "hi"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(server.URL+"/v1", "", 5)
+	resp, err := client.Complete(context.Background(), &Request{
+		Model:  "m",
+		Prompt: "hello",
+		Options: map[string]interface{}{
+			"temperature": 0.2,
+			"top_p":       0.9,
+			"seed":        7,
+			"stop":        []string{"###"},
+			"num_predict": 256,
+			"num_ctx":     8192,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "hi" {
+		t.Errorf("text = %q", resp.Text)
+	}
+
+	want := map[string]interface{}{
+		"temperature": 0.2,
+		"top_p":       0.9,
+		"seed":        7.0,
+		"stop":        []interface{}{"###"},
+		"max_tokens":  256.0,
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(got[key], value) {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+	if _, ok := got["num_ctx"]; ok {
+		t.Error("num_ctx should not be sent")
+	}
+
+	// No limit (-1) and no options leave the fields out
+	got = nil
+	if _, err := client.Complete(context.Background(), &Request{Model: "m", Prompt: "hello", Options: map[string]interface{}{"num_predict": -1}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"temperature", "top_p", "seed", "stop", "max_tokens", "stream_options"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s sent without being set", key)
+		}
+	}
+}
+
+// TestReadSSEStream tests decoding streamed chunks, usage, stream errors and premature ends
+func TestReadSSEStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		want     *Response
+		tokens   string
+		errMatch string
+	}{
+		{
+			name: "content, reasoning and usage",
+			stream: `data: {"model": "m", "choices": [{"delta": {"reasoning_content": "let me think"}}]}
+
+: keep-alive
+data: {"choices": [{"delta": {"content": "Hel"}}]}
+data: {"choices": [{"delta": {"content": "lo"}}]}
+data: {"choices": [], "usage": {"prompt_tokens": 3, "completion_tokens": 2}}
+data: [DONE]
+`,
+			want:   &Response{Text: "Hello", Thinking: "let me think", Model: "m", Usage: Usage{PromptTokens: 3, CompletionTokens: 2}},
+			tokens: "Hello",
+		},
+		{
+			name:     "error chunk",
+			stream:   "data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\ndata: {\"error\": {\"message\": \"overloaded\"}}\n",
+			errMatch: "overloaded",
+		},
+		{
+			name:     "ends without [DONE]",
+			stream:   "data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n",
+			errMatch: "stream ended",
+		},
+		{
+			name:     "malformed chunk",
+			stream:   "data: {not json\n",
+			errMatch: "decode stream chunk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens strings.Builder
+			resp, err := readSSEStream(strings.NewReader(tt.stream), func(chunk string) { tokens.WriteString(chunk) })
+			if tt.errMatch != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+					t.Fatalf("err = %v, want %q", err, tt.errMatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp, tt.want) {
+				t.Errorf("response = %+v, want %+v", resp, tt.want)
+			}
+			if tokens.String() != tt.tokens {
+				t.Errorf("tokens = %q, want %q", tokens.String(), tt.tokens)
+			}
+		})
+	}
+}
+
+// TestOpenAIClientStreamAndModels tests streaming completions, model listing and the API key header
+func TestOpenAIClientStreamAndModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("authorization = %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data": [{"id": "a"}, {"id": "b"}]}`))
+		case "/v1/chat/completions":
+			var req chatCompletionRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if !req.Stream {
+				t.Error("request with a token handler was not streamed")
+			}
+			if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+				t.Error("streamed request did not ask for usage")
+			}
+			// Usage arrives on a final chunk without choices, as OpenAI sends it
+			w.Write([]byte("data: {\"model\": \"a\", \"choices\": [{\"delta\": {\"content\": \"ok\"}}]}\n\n" +
+				"data: {\"model\": \"a\", \"choices\": [], \"usage\": {\"prompt_tokens\": 12, \"completion_tokens\": 1}}\n\n" +
+				"data: [DONE]\n\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(server.URL, "secret", 5)
+	models, err := client.ListModels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(models, []string{"a", "b"}) {
+		t.Errorf("models = %q", models)
+	}
+
+	var streamed string
+	resp, err := client.Complete(context.Background(), &Request{Model: "a", Prompt: "hi", OnToken: func(chunk string) { streamed += chunk }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "ok" || streamed != "ok" || resp.Model != "a" {
+		t.Errorf("response = %+v, streamed %q", resp, streamed)
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 1 {
+		t.Errorf("usage = %+v, want the final chunk's counts", resp.Usage)
+	}
+
+	// A server that rejects requests reports its status
+	client = NewOpenAIClient(server.URL+"/missing", "secret", 5)
+	if err := client.Ping(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("ping err = %v, want a 404", err)
+	}
+}
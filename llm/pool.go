@@ -0,0 +1,350 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyHost is returned when every pool host is down, as opposed to the model
+// being missing from the healthy ones (ErrModelNotFound); callers should retry it
+var ErrNoHealthyHost = errors.New("no healthy host")
+
+// Host states reported by a Pool
+const (
+	HostStateUnknown   = "unknown" // Not checked yet; still eligible for routing
+	HostStateHealthy   = "healthy"
+	HostStateUnhealthy = "unhealthy"
+)
+
+// HostStatus is a snapshot of one backend host in a Pool
+type HostStatus struct {
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	InFlight    int       `json:"in_flight"`
+	Models      []string  `json:"models"`
+	LastChecked time.Time `json:"last_checked"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// HostReporter is implemented by providers that can report the state of their backend hosts
+type HostReporter interface {
+	HostStatus() []HostStatus
+}
+
+// poolHost is the routing state of one backend
+type poolHost struct {
+	name        string
+	provider    Provider
+	state       string
+	models      map[string]bool
+	inFlight    int
+	lastChecked time.Time
+	lastError   string
+}
+
+// Pool spreads requests over several equivalent backends (e.g. one Ollama per GPU box).
+// Each request goes to the least-loaded healthy host that has the requested model.
+// Hosts are re-checked periodically with Ping and ListModels; a host whose request
+// fails is marked unhealthy until its next successful check, and the request moves on
+// to the next candidate host. Only transport failures mark a host unhealthy; an error
+// response to one bad request says nothing about the host.
+type Pool struct {
+	hosts []*poolHost
+	mu    sync.Mutex
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewPool creates a pool over the named providers; hosts start in the unknown state
+func NewPool(providers map[string]Provider) *Pool {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pool := &Pool{stop: make(chan struct{})}
+	for _, name := range names {
+		pool.hosts = append(pool.hosts, &poolHost{
+			name:     name,
+			provider: providers[name],
+			state:    HostStateUnknown,
+		})
+	}
+
+	return pool
+}
+
+// Start checks every host now and then every interval until Stop is called
+func (p *Pool) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			p.CheckHosts()
+
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic health checks
+func (p *Pool) Stop() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// CheckHosts pings every host and refreshes its model list, concurrently
+func (p *Pool) CheckHosts() {
+	var wg sync.WaitGroup
+	for _, host := range p.hosts {
+		wg.Add(1)
+		go func(host *poolHost) {
+			defer wg.Done()
+			p.checkHost(host)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// checkHost updates one host's state from Ping and ListModels
+func (p *Pool) checkHost(host *poolHost) {
+	err := host.provider.Ping()
+	var models []string
+	if err == nil {
+		models, err = host.provider.ListModels()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wasState := host.state
+	host.lastChecked = time.Now()
+
+	if err != nil {
+		host.state = HostStateUnhealthy
+		host.lastError = err.Error()
+	} else {
+		host.state = HostStateHealthy
+		host.lastError = ""
+		host.models = make(map[string]bool, len(models))
+		for _, m := range models {
+			host.models[m] = true
+		}
+	}
+
+	if wasState != host.state {
+		log.Printf("LLM pool: host %s is %s", host.name, host.state)
+	}
+}
+
+// Complete routes the request to the best host for its model, trying other hosts on failure
+func (p *Pool) Complete(ctx context.Context, req *Request) (*Response, error) {
+	tried := make(map[*poolHost]bool)
+	stream := NewRetryStream(ctx, req.OnToken)
+	var lastErr error
+
+	for {
+		host := p.acquire(req.Model, tried)
+		if host == nil {
+			break
+		}
+		tried[host] = true
+
+		attempt := *req
+		attempt.OnToken = stream.Attempt(req.Model)
+		resp, err := host.provider.Complete(ctx, &attempt)
+		p.release(host, req.Model, err, ctx.Err() != nil)
+		if err == nil || ctx.Err() != nil {
+			return resp, err
+		}
+
+		log.Printf("LLM pool: %s failed on host %s: %v", req.Model, host.name, err)
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if !p.anyHealthy() {
+		return nil, fmt.Errorf("%w: no pool host is reachable for %s", ErrNoHealthyHost, req.Model)
+	}
+	return nil, fmt.Errorf("%w: no healthy host has %s", ErrModelNotFound, req.Model)
+}
+
+// anyHealthy reports whether any host is healthy or not checked yet
+func (p *Pool) anyHealthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, host := range p.hosts {
+		if host.state != HostStateUnhealthy {
+			return true
+		}
+	}
+	return false
+}
+
+// acquire picks the least-loaded untried host that can serve model and counts the request against it
+// Healthy hosts known to have the model are preferred over hosts not checked yet
+func (p *Pool) acquire(model string, tried map[*poolHost]bool) *poolHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *poolHost
+	for _, host := range p.hosts {
+		if tried[host] || !host.canServe(model) {
+			continue
+		}
+		if best == nil || host.better(best) {
+			best = host
+		}
+	}
+
+	if best != nil {
+		best.inFlight++
+	}
+	return best
+}
+
+// release records the outcome of a request on host
+// Cancelled requests say nothing about the host, so they don't affect its state
+func (p *Pool) release(host *poolHost, model string, err error, cancelled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	host.inFlight--
+	if err == nil || cancelled {
+		return
+	}
+
+	if errors.Is(err, ErrModelNotFound) {
+		delete(host.models, model)
+		delete(host.models, model+":latest")
+		return
+	}
+
+	if !isTransportError(err) {
+		return
+	}
+	host.state = HostStateUnhealthy
+	host.lastError = err.Error()
+}
+
+// isTransportError reports whether err means the host could not be reached or dropped the connection
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// canServe reports whether a host is eligible for model
+func (h *poolHost) canServe(model string) bool {
+	switch h.state {
+	case HostStateHealthy:
+		return h.models[model] || h.models[model+":latest"]
+	case HostStateUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// better reports whether h should be preferred over other
+func (h *poolHost) better(other *poolHost) bool {
+	if (h.state == HostStateHealthy) != (other.state == HostStateHealthy) {
+		return h.state == HostStateHealthy
+	}
+	return h.inFlight < other.inFlight
+}
+
+// ListModels returns the models available on any healthy host (deduplicated, sorted)
+func (p *Pool) ListModels() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, host := range p.hosts {
+		if host.state != HostStateHealthy {
+			continue
+		}
+		for m := range host.models {
+			seen[m] = true
+		}
+	}
+
+	models := make([]string, 0, len(seen))
+	for m := range seen {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Ping succeeds if at least one host is reachable
+func (p *Pool) Ping() error {
+	var errs []string
+	for _, host := range p.hosts {
+		err := host.provider.Ping()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", host.name, err))
+	}
+	return fmt.Errorf("no pool host reachable (%v)", errs)
+}
+
+// ModelDigest asks a host that has the model for its digest
+func (p *Pool) ModelDigest(model string) (string, error) {
+	p.mu.Lock()
+	var candidates []*poolHost
+	for _, host := range p.hosts {
+		if host.canServe(model) {
+			candidates = append(candidates, host)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, host := range candidates {
+		if dp, ok := host.provider.(DigestProvider); ok {
+			if digest, err := dp.ModelDigest(model); err == nil {
+				return digest, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no pool host reports a digest for %s", model)
+}
+
+// HostStatus returns a snapshot of every host
+func (p *Pool) HostStatus() []HostStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]HostStatus, 0, len(p.hosts))
+	for _, host := range p.hosts {
+		models := make([]string, 0, len(host.models))
+		for m := range host.models {
+			models = append(models, m)
+		}
+		sort.Strings(models)
+
+		statuses = append(statuses, HostStatus{
+			Name:        host.name,
+			State:       host.state,
+			InFlight:    host.inFlight,
+			Models:      models,
+			LastChecked: host.lastChecked,
+			LastError:   host.lastError,
+		})
+	}
+	return statuses
+}
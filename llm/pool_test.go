@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"syscall"
+	"testing"
+)
+
+// fakeHost serves a fixed model list and can be made to fail
+type fakeHost struct {
+	models    []string
+	fail      bool // The connection is refused
+	badStatus bool // The host answers with an error status
+	calls     int
+}
+
+func (h *fakeHost) Complete(ctx context.Context, req *Request) (*Response, error) {
+	h.calls++
+	if h.fail {
+		return nil, fmt.Errorf("ollama request failed: %w", &url.Error{Op: "Post", URL: "http://gpu", Err: syscall.ECONNREFUSED})
+	}
+	if h.badStatus {
+		return nil, fmt.Errorf("ollama returned status 500: bad prompt")
+	}
+	return &Response{Text: "ok", Model: req.Model}, nil
+}
+
+func (h *fakeHost) ListModels() ([]string, error) { return h.models, nil }
+func (h *fakeHost) Ping() error                   { return nil }
+
+// TestPoolRoutesByModelAndHealth tests model-aware routing and failover to another host
+func TestPoolRoutesByModelAndHealth(t *testing.T) {
+	small := &fakeHost{models: []string{"llama3:8b"}}
+	big := &fakeHost{models: []string{"llama3:8b", "deepseek-coder:6.7b-instruct"}}
+	pool := NewPool(map[string]Provider{"gpu1": small, "gpu2": big})
+	pool.CheckHosts()
+
+	if _, err := Generate(context.Background(), pool, "deepseek-coder:6.7b-instruct", "p"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if big.calls != 1 || small.calls != 0 {
+		t.Errorf("request should go to the only host with the model, calls = gpu1:%d gpu2:%d", small.calls, big.calls)
+	}
+
+	// gpu1 sorts first among equally loaded hosts; when it fails the request moves to gpu2
+	small.fail = true
+	if _, err := Generate(context.Background(), pool, "llama3:8b", "p"); err != nil {
+		t.Fatalf("Generate() should fail over, error = %v", err)
+	}
+	if small.calls != 1 || big.calls != 2 {
+		t.Errorf("calls = gpu1:%d gpu2:%d, want 1 / 2", small.calls, big.calls)
+	}
+	if state := pool.HostStatus()[0].State; state != HostStateUnhealthy {
+		t.Errorf("failed host state = %s, want %s", state, HostStateUnhealthy)
+	}
+
+	if _, err := Generate(context.Background(), pool, "mistral:7b", "p"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Generate() for a model no host has: error = %v, want ErrModelNotFound", err)
+	}
+
+	// An error response is tried elsewhere but leaves the host healthy
+	big.badStatus = true
+	if _, err := Generate(context.Background(), pool, "llama3:8b", "p"); err == nil {
+		t.Error("Generate() should return the host's error")
+	}
+	if state := pool.HostStatus()[1].State; state != HostStateHealthy {
+		t.Errorf("host answering with an error status is %s, want %s", state, HostStateHealthy)
+	}
+
+	// Once every host is down the pool reports an outage, not a missing model
+	big.badStatus, big.fail = false, true
+	Generate(context.Background(), pool, "llama3:8b", "p")
+	if _, err := Generate(context.Background(), pool, "llama3:8b", "p"); !errors.Is(err, ErrNoHealthyHost) {
+		t.Errorf("Generate() with every host down: error = %v, want ErrNoHealthyHost", err)
+	}
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrModelNotFound is returned when the backend does not have the requested model
+var ErrModelNotFound = errors.New("model not found")
+
+// Provider is an LLM backend that can serve generation requests
+// Implementations: Client (Ollama), OpenAIClient (OpenAI-compatible servers), Pool, Router
+type Provider interface {
+	Complete(ctx context.Context, req *Request) (*Response, error)
+	ListModels() ([]string, error)
+	Ping() error
+}
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatMessage is one role-tagged message of a chat history
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is a single generation request, independent of the backend
+// With Messages set the request is a chat turn and Prompt and Context are ignored
+type Request struct {
+	Model    string
+	Prompt   string
+	Messages []ChatMessage          // Chat history, oldest first, ending with the user's turn
+	Context  []int                  // Deprecated Ollama conversation context (ignored by backends without one)
+	Options  map[string]interface{} // Backend generation options (temperature, seed, ...)
+	Format   json.RawMessage        // Optional: "json" or a JSON schema the response must follow
+	Think    bool                   // Ask a reasoning model to think first; the trace comes back in Response.Thinking
+	OnToken  TokenHandler           // Optional: receives response chunks while streaming (answer text only)
+}
+
+// Response is the result of a generation request
+type Response struct {
+	Text     string // The answer, without any reasoning trace
+	Thinking string // Reasoning trace, from the think flag or inline <think> blocks
+	Model    string
+	Context  []int // Conversation context to pass to the next request (Ollama only)
+	Usage    Usage // Token counts and timings, where the backend reports them
+}
+
+// DigestProvider is implemented by providers that can report a model's content digest
+type DigestProvider interface {
+	ModelDigest(model string) (string, error)
+}
+
+// TokenHandler receives response chunks as they stream in from the model
+type TokenHandler func(chunk string)
+
+type generationOptionsKey struct{}
+
+// WithOptions returns a context whose LLM calls use options (temperature, num_ctx, ...)
+// Options set on a request take precedence over those from the context.
+func WithOptions(ctx context.Context, options map[string]interface{}) context.Context {
+	if len(options) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, generationOptionsKey{}, options)
+}
+
+// OptionsFrom returns the generation options attached to ctx, if any
+func OptionsFrom(ctx context.Context) map[string]interface{} {
+	options, _ := ctx.Value(generationOptionsKey{}).(map[string]interface{})
+	return options
+}
+
+// effectiveOptions merges a request's options over those attached to ctx
+func effectiveOptions(ctx context.Context, requestOptions map[string]interface{}) map[string]interface{} {
+	return mergeOptions(OptionsFrom(ctx), requestOptions)
+}
+
+// mergeOptions returns base with every entry of override replacing its own
+func mergeOptions(base, override map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 {
+		return override
+	}
+	if len(override) == 0 {
+		return base
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Generate sends a prompt to the provider and returns the response
+// Cancelling ctx aborts the request, including a stream in progress.
+// Token usage and latency are added to any UsageMeter attached to ctx.
+func Generate(ctx context.Context, p Provider, model, prompt string) (string, error) {
+	return GenerateStream(ctx, p, model, prompt, nil)
+}
+
+// GenerateStream sends a prompt and passes each response chunk to onToken
+// as it arrives. The full response is returned once the model is done.
+// A nil onToken falls back to a single non-streaming request.
+func GenerateStream(ctx context.Context, p Provider, model, prompt string, onToken TokenHandler) (string, error) {
+	resp, err := complete(ctx, p, &Request{
+		Model:   model,
+		Prompt:  prompt,
+		OnToken: onToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text, nil
+}
+
+// Chat sends a role-tagged message history and returns the assistant's reply
+// Unlike GenerateWithContext the history is plain data: it can be stored, trimmed
+// and replayed against a different model.
+func Chat(ctx context.Context, p Provider, model string, messages []ChatMessage, options map[string]interface{}) (string, error) {
+	return ChatStream(ctx, p, model, messages, options, nil)
+}
+
+// ChatStream is the streaming variant of Chat
+func ChatStream(ctx context.Context, p Provider, model string, messages []ChatMessage, options map[string]interface{}, onToken TokenHandler) (string, error) {
+	resp, err := complete(ctx, p, &Request{
+		Model:    model,
+		Messages: messages,
+		Options:  options,
+		OnToken:  onToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text, nil
+}
+
+// GenerateWithContext sends a prompt with conversation context and returns both response and new context
+func GenerateWithContext(ctx context.Context, p Provider, model, prompt string, context []int) (string, []int, error) {
+	return GenerateWithContextStream(ctx, p, model, prompt, context, nil)
+}
+
+// GenerateWithContextStream is the streaming variant of GenerateWithContext
+func GenerateWithContextStream(ctx context.Context, p Provider, model, prompt string, context []int, onToken TokenHandler) (string, []int, error) {
+	resp, err := complete(ctx, p, &Request{
+		Model:   model,
+		Prompt:  prompt,
+		Context: context,
+		OnToken: onToken,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return resp.Text, resp.Context, nil
+}
+
+// GenerateWithThinking sends a prompt in a thinking mode (fast, normal or extended)
+// and returns the answer and the model's reasoning trace separately.
+// The mode's ThinkingProfile sets the think flag and generation options; callers
+// that honour a per-mode model pick it with ThinkingModel.
+func GenerateWithThinking(ctx context.Context, p Provider, model, prompt, thinkingMode string) (string, string, error) {
+	return GenerateWithThinkingStream(ctx, p, model, prompt, thinkingMode, nil)
+}
+
+// GenerateWithThinkingStream is the streaming variant of GenerateWithThinking
+// Only answer text is streamed to onToken.
+func GenerateWithThinkingStream(ctx context.Context, p Provider, model, prompt, thinkingMode string, onToken TokenHandler) (string, string, error) {
+	req := &Request{
+		Model:   model,
+		Prompt:  prompt,
+		OnToken: onToken,
+	}
+	ctx = withThinkingMode(ctx, req, thinkingMode)
+
+	resp, err := complete(ctx, p, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	return resp.Text, resp.Thinking, nil
+}
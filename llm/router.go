@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Router dispatches requests to a provider chosen by model name
+// Models without an explicit route go to the default provider
+type Router struct {
+	defaultProvider string
+	providers       map[string]Provider // provider name -> provider
+	routes          map[string]string   // model name -> provider name
+	mu              sync.RWMutex
+}
+
+// NewRouter creates a router whose unrouted models go to defaultProvider
+func NewRouter(defaultName string, defaultProvider Provider) *Router {
+	return &Router{
+		defaultProvider: defaultName,
+		providers:       map[string]Provider{defaultName: defaultProvider},
+		routes:          make(map[string]string),
+	}
+}
+
+// Register adds a named provider
+func (r *Router) Register(name string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = p
+}
+
+// Route sends all requests for model to the named provider
+func (r *Router) Route(model, providerName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[providerName]; !ok {
+		return fmt.Errorf("unknown provider %q for model %s", providerName, model)
+	}
+	r.routes[model] = providerName
+	return nil
+}
+
+// ProviderFor returns the provider name and provider serving model
+func (r *Router) ProviderFor(model string) (string, Provider) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.routes[model]
+	if !ok {
+		name = r.defaultProvider
+	}
+	return name, r.providers[name]
+}
+
+// Complete forwards the request to the provider routed for its model
+func (r *Router) Complete(ctx context.Context, req *Request) (*Response, error) {
+	_, p := r.ProviderFor(req.Model)
+	return p.Complete(ctx, req)
+}
+
+// ModelDigest asks the provider serving model for its digest
+func (r *Router) ModelDigest(model string) (string, error) {
+	name, p := r.ProviderFor(model)
+	dp, ok := p.(DigestProvider)
+	if !ok {
+		return "", fmt.Errorf("provider %s does not report model digests", name)
+	}
+	return dp.ModelDigest(model)
+}
+
+// ListModels returns the models of every reachable provider (deduplicated, sorted)
+// Providers that fail are logged and skipped; it errors only if all of them fail
+func (r *Router) ListModels() ([]string, error) {
+	seen := make(map[string]bool)
+	var errs []string
+	providers := r.snapshot()
+	for _, name := range sortedNames(providers) {
+		models, err := providers[name].ListModels()
+		if err != nil {
+			log.Printf("Warning: provider %s failed to list models: %v", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		for _, m := range models {
+			seen[m] = true
+		}
+	}
+	if len(errs) == len(providers) {
+		return nil, fmt.Errorf("no provider could list models (%s)", strings.Join(errs, "; "))
+	}
+
+	models := make([]string, 0, len(seen))
+	for m := range seen {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// Ping succeeds if any registered provider is reachable, logging the ones that aren't
+func (r *Router) Ping() error {
+	var errs []string
+	providers := r.snapshot()
+	for _, name := range sortedNames(providers) {
+		if err := providers[name].Ping(); err != nil {
+			log.Printf("Warning: provider %s unreachable: %v", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) == len(providers) {
+		return fmt.Errorf("no provider reachable (%s)", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// HostStatus reports the hosts of every registered provider that has several
+func (r *Router) HostStatus() []HostStatus {
+	var statuses []HostStatus
+	for _, p := range r.snapshot() {
+		if hr, ok := p.(HostReporter); ok {
+			statuses = append(statuses, hr.HostStatus()...)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// snapshot copies the provider map so backends are called without holding the lock
+func (r *Router) snapshot() map[string]Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make(map[string]Provider, len(r.providers))
+	for name, p := range r.providers {
+		providers[name] = p
+	}
+	return providers
+}
+
+// sortedNames returns the provider names in order, so errors read the same every time
+func sortedNames(providers map[string]Provider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
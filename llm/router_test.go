@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// namedProvider answers with its name and fails listing and pings when down
+type namedProvider struct {
+	name   string
+	models []string
+	down   bool
+}
+
+func (p *namedProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	return &Response{Text: p.name, Model: req.Model}, nil
+}
+
+func (p *namedProvider) ListModels() ([]string, error) {
+	if p.down {
+		return nil, errors.New("connection refused")
+	}
+	return p.models, nil
+}
+
+func (p *namedProvider) Ping() error {
+	if p.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// TestRouter tests routing by model and tolerating providers that are down
+func TestRouter(t *testing.T) {
+	local := &namedProvider{name: "local", models: []string{"llama3", "qwen"}}
+	remote := &namedProvider{name: "remote", models: []string{"gpt-4o", "qwen"}}
+	r := NewRouter("local", local)
+	r.Register("remote", remote)
+	if err := r.Route("gpt-4o", "remote"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Route("claude", "missing"); err == nil {
+		t.Error("routing to an unknown provider succeeded")
+	}
+
+	for model, want := range map[string]string{"gpt-4o": "remote", "llama3": "local", "unrouted": "local"} {
+		resp, err := r.Complete(context.Background(), &Request{Model: model})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Text != want {
+			t.Errorf("%s went to %s, want %s", model, resp.Text, want)
+		}
+	}
+
+	models, err := r.ListModels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"gpt-4o", "llama3", "qwen"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("models = %q, want %q", models, want)
+	}
+
+	// One provider down: the others still list and answer pings
+	remote.down = true
+	models, err = r.ListModels()
+	if err != nil {
+		t.Fatalf("list with one provider down: %v", err)
+	}
+	if want := []string{"llama3", "qwen"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("models = %q, want %q", models, want)
+	}
+	if err := r.Ping(); err != nil {
+		t.Errorf("ping with one provider down: %v", err)
+	}
+
+	// All providers down: both fail, naming every provider
+	local.down = true
+	if _, err := r.ListModels(); err == nil || !strings.Contains(err.Error(), "local") || !strings.Contains(err.Error(), "remote") {
+		t.Errorf("list err = %v, want both providers named", err)
+	}
+	if err := r.Ping(); err == nil || !strings.Contains(err.Error(), "no provider reachable") {
+		t.Errorf("ping err = %v", err)
+	}
+}
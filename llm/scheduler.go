@@ -0,0 +1,370 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Priority orders requests waiting for a busy host; lower values are served first
+type Priority int
+
+// Request priorities, highest first
+const (
+	PriorityInteractive Priority = iota // A user is waiting on the reply (chat, discovery)
+	PriorityPhase                       // Project phases and submitted tasks
+	PriorityBackground                  // Everything else; the default
+	numPriorities
+)
+
+// String returns the priority's name as used in queue stats
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityPhase:
+		return "phase"
+	default:
+		return "background"
+	}
+}
+
+type priorityKey struct{}
+type projectKey struct{}
+
+// WithPriority returns a context whose LLM calls queue at priority p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority attached to ctx (PriorityBackground if none)
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < numPriorities {
+		return p
+	}
+	return PriorityBackground
+}
+
+// WithProject returns a context whose LLM calls are queued fairly against other projects
+func WithProject(ctx context.Context, projectID string) context.Context {
+	return context.WithValue(ctx, projectKey{}, projectID)
+}
+
+// ProjectFrom returns the project attached to ctx ("" if none)
+func ProjectFrom(ctx context.Context) string {
+	id, _ := ctx.Value(projectKey{}).(string)
+	return id
+}
+
+// QueueStats is a snapshot of one scheduler's queue
+type QueueStats struct {
+	Host            string             `json:"host"`
+	Limit           int                `json:"limit"`
+	Running         int                `json:"running"`
+	Queued          map[string]int     `json:"queued"`                      // priority -> waiting requests
+	QueuedByProject map[string]int     `json:"queued_by_project,omitempty"` // project ID -> waiting requests
+	AvgWaitSeconds  map[string]float64 `json:"avg_wait_seconds"`            // priority -> mean queue wait
+	MaxWaitSeconds  float64            `json:"max_wait_seconds"`            // Longest wait of any request so far
+	Dispatched      int                `json:"dispatched"`
+}
+
+// QueueReporter is implemented by providers that schedule requests
+type QueueReporter interface {
+	QueueStats() []QueueStats
+}
+
+// waiter is a request waiting for a slot
+type waiter struct {
+	project  string
+	enqueued time.Time
+	ready    chan struct{}
+}
+
+// fairQueue holds the waiters of one priority, served round-robin by project
+type fairQueue struct {
+	projects []string             // Projects with waiters, in service order
+	waiting  map[string][]*waiter // project -> waiters, oldest first
+}
+
+// Scheduler limits how many requests run at once on one backend host.
+// Waiting requests are served strictly by priority (interactive, then phase, then
+// background) and, within a priority, round-robin across projects so one busy
+// project can't starve the others.
+type Scheduler struct {
+	name  string
+	inner Provider
+	limit int
+
+	mu         sync.Mutex
+	running    int
+	queues     [numPriorities]fairQueue
+	dispatched int
+	waitCount  [numPriorities]int
+	waitTotal  [numPriorities]time.Duration
+	maxWait    time.Duration
+}
+
+// NewScheduler wraps inner so at most limit requests run on it at once (minimum 1)
+func NewScheduler(name string, inner Provider, limit int) *Scheduler {
+	if limit < 1 {
+		limit = 1
+	}
+	s := &Scheduler{name: name, inner: inner, limit: limit}
+	for i := range s.queues {
+		s.queues[i].waiting = make(map[string][]*waiter)
+	}
+	return s
+}
+
+// Complete waits for a slot, then forwards the request
+// Cancelling ctx while queued abandons the request without running it.
+func (s *Scheduler) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+
+	return s.inner.Complete(ctx, req)
+}
+
+// acquire takes a slot, queueing at the priority and project attached to ctx
+func (s *Scheduler) acquire(ctx context.Context) error {
+	priority := PriorityFrom(ctx)
+	w := &waiter{project: ProjectFrom(ctx), enqueued: time.Now(), ready: make(chan struct{})}
+
+	s.mu.Lock()
+	if s.running < s.limit && s.queuedLocked() == 0 {
+		s.running++
+		s.dispatched++
+		s.recordWaitLocked(priority, 0)
+		s.mu.Unlock()
+		return nil
+	}
+	s.queues[priority].push(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.queues[priority].remove(w)
+		s.mu.Unlock()
+		if !removed {
+			// The slot was granted while we were giving up; pass it on
+			s.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot and hands free slots to the next waiters
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	for s.running < s.limit {
+		priority, w := s.nextLocked()
+		if w == nil {
+			return
+		}
+		s.running++
+		s.dispatched++
+		s.recordWaitLocked(priority, time.Since(w.enqueued))
+		close(w.ready)
+	}
+}
+
+// nextLocked pops the next waiter: highest priority first, round-robin by project
+func (s *Scheduler) nextLocked() (Priority, *waiter) {
+	for p := Priority(0); p < numPriorities; p++ {
+		if w := s.queues[p].pop(); w != nil {
+			return p, w
+		}
+	}
+	return 0, nil
+}
+
+// queuedLocked counts waiting requests
+func (s *Scheduler) queuedLocked() int {
+	n := 0
+	for i := range s.queues {
+		n += s.queues[i].len()
+	}
+	return n
+}
+
+// recordWaitLocked adds one dispatched request's queue wait to the stats
+func (s *Scheduler) recordWaitLocked(p Priority, wait time.Duration) {
+	s.waitCount[p]++
+	s.waitTotal[p] += wait
+	if wait > s.maxWait {
+		s.maxWait = wait
+	}
+}
+
+// QueueStats returns a snapshot of the queue
+func (s *Scheduler) QueueStats() []QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := QueueStats{
+		Host:           s.name,
+		Limit:          s.limit,
+		Running:        s.running,
+		Queued:         make(map[string]int, numPriorities),
+		AvgWaitSeconds: make(map[string]float64, numPriorities),
+		MaxWaitSeconds: s.maxWait.Seconds(),
+		Dispatched:     s.dispatched,
+	}
+
+	for p := Priority(0); p < numPriorities; p++ {
+		q := &s.queues[p]
+		stats.Queued[p.String()] = q.len()
+		if s.waitCount[p] > 0 {
+			stats.AvgWaitSeconds[p.String()] = (s.waitTotal[p] / time.Duration(s.waitCount[p])).Seconds()
+		}
+		for project, waiters := range q.waiting {
+			if project == "" {
+				continue
+			}
+			if stats.QueuedByProject == nil {
+				stats.QueuedByProject = make(map[string]int)
+			}
+			stats.QueuedByProject[project] += len(waiters)
+		}
+	}
+
+	return []QueueStats{stats}
+}
+
+// ListModels delegates to the wrapped provider
+func (s *Scheduler) ListModels() ([]string, error) {
+	return s.inner.ListModels()
+}
+
+// Ping delegates to the wrapped provider
+func (s *Scheduler) Ping() error {
+	return s.inner.Ping()
+}
+
+// ModelDigest delegates to the wrapped provider when it reports digests
+func (s *Scheduler) ModelDigest(model string) (string, error) {
+	dp, ok := s.inner.(DigestProvider)
+	if !ok {
+		return "", fmt.Errorf("provider does not report model digests")
+	}
+	return dp.ModelDigest(model)
+}
+
+// PullModel delegates to the wrapped provider; downloads don't take a request slot
+func (s *Scheduler) PullModel(ctx context.Context, model string, onProgress PullHandler) error {
+	puller, ok := s.inner.(ModelPuller)
+	if !ok {
+		return fmt.Errorf("provider cannot pull models")
+	}
+	return puller.PullModel(ctx, model, onProgress)
+}
+
+// push adds a waiter behind others of the same project
+func (q *fairQueue) push(w *waiter) {
+	if len(q.waiting[w.project]) == 0 {
+		q.projects = append(q.projects, w.project)
+	}
+	q.waiting[w.project] = append(q.waiting[w.project], w)
+}
+
+// pop takes the oldest waiter of the next project in turn, which then moves to the back
+func (q *fairQueue) pop() *waiter {
+	if len(q.projects) == 0 {
+		return nil
+	}
+
+	project := q.projects[0]
+	q.projects = q.projects[1:]
+
+	waiters := q.waiting[project]
+	w := waiters[0]
+	if len(waiters) > 1 {
+		q.waiting[project] = waiters[1:]
+		q.projects = append(q.projects, project)
+	} else {
+		delete(q.waiting, project)
+	}
+	return w
+}
+
+// remove drops a waiter that gave up; it reports false if the waiter was already served
+func (q *fairQueue) remove(w *waiter) bool {
+	waiters := q.waiting[w.project]
+	for i, other := range waiters {
+		if other != w {
+			continue
+		}
+		if len(waiters) == 1 {
+			delete(q.waiting, w.project)
+			for j, project := range q.projects {
+				if project == w.project {
+					q.projects = append(q.projects[:j], q.projects[j+1:]...)
+					break
+				}
+			}
+		} else {
+			q.waiting[w.project] = append(waiters[:i:i], waiters[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// len counts the waiters
+func (q *fairQueue) len() int {
+	n := 0
+	for _, waiters := range q.waiting {
+		n += len(waiters)
+	}
+	return n
+}
+
+// collectQueueStats gathers the queue stats of every provider that reports them, sorted by host
+func collectQueueStats(providers []Provider) []QueueStats {
+	var stats []QueueStats
+	for _, p := range providers {
+		if qr, ok := p.(QueueReporter); ok {
+			stats = append(stats, qr.QueueStats()...)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+// QueueStats reports the queues of the pool's hosts
+func (p *Pool) QueueStats() []QueueStats {
+	providers := make([]Provider, 0, len(p.hosts))
+	for _, host := range p.hosts {
+		providers = append(providers, host.provider)
+	}
+	return collectQueueStats(providers)
+}
+
+// QueueStats reports the queues of every registered provider
+func (r *Router) QueueStats() []QueueStats {
+	var providers []Provider
+	for _, p := range r.snapshot() {
+		providers = append(providers, p)
+	}
+	return collectQueueStats(providers)
+}
+
+// QueueStats delegates to the wrapped provider when it schedules requests
+func (cp *CachedProvider) QueueStats() []QueueStats {
+	return collectQueueStats([]Provider{cp.inner})
+}
+
+// QueueStats delegates to the wrapped provider when it schedules requests
+func (r *Recorder) QueueStats() []QueueStats {
+	return collectQueueStats([]Provider{r.inner})
+}
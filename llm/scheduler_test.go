@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSchedulerOrder tests that queued requests run by priority, then round-robin by project
+func TestSchedulerOrder(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	s := NewScheduler("http://gpu:11434", provider, 1)
+
+	// Occupy the only slot so everything below queues
+	first := make(chan struct{})
+	go func() {
+		s.Complete(context.Background(), &Request{Prompt: "first"})
+		close(first)
+	}()
+	waitFor(t, func() bool { return provider.started() == 1 })
+
+	var wg sync.WaitGroup
+	queued := 0
+	enqueue := func(prompt string, priority Priority, project string) {
+		ctx := WithProject(WithPriority(context.Background(), priority), project)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Complete(ctx, &Request{Prompt: prompt})
+		}()
+		queued++
+		waitFor(t, func() bool { return s.queued() == queued })
+	}
+	enqueue("a1", PriorityPhase, "a")
+	enqueue("a2", PriorityPhase, "a")
+	enqueue("b1", PriorityPhase, "b")
+	enqueue("bg", PriorityBackground, "")
+	enqueue("chat", PriorityInteractive, "")
+
+	stats := s.QueueStats()[0]
+	if stats.Queued["phase"] != 3 || stats.QueuedByProject["a"] != 2 || stats.Running != 1 {
+		t.Errorf("QueueStats() = %+v", stats)
+	}
+
+	close(provider.release)
+	<-first
+	wg.Wait()
+
+	want := []string{"first", "chat", "a1", "b1", "a2", "bg"}
+	if got := provider.order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+// TestSchedulerCancelWhileQueued tests that a cancelled waiter leaves the queue without running
+func TestSchedulerCancelWhileQueued(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	s := NewScheduler("http://gpu:11434", provider, 1)
+
+	done := make(chan struct{})
+	go func() {
+		s.Complete(context.Background(), &Request{Prompt: "first"})
+		close(done)
+	}()
+	waitFor(t, func() bool { return provider.started() == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := s.Complete(ctx, &Request{Prompt: "cancelled"})
+		errc <- err
+	}()
+	waitFor(t, func() bool { return s.queued() == 1 })
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("Complete() error = %v, want context.Canceled", err)
+	}
+
+	close(provider.release)
+	<-done
+	if _, err := s.Complete(context.Background(), &Request{Prompt: "next"}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if got := provider.order(); !reflect.DeepEqual(got, []string{"first", "next"}) {
+		t.Errorf("order = %v", got)
+	}
+}
+
+// queued counts waiting requests
+func (s *Scheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queuedLocked()
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for scheduler state")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingProvider records the order requests start in and blocks them until release is closed
+type blockingProvider struct {
+	release chan struct{}
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *blockingProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.mu.Unlock()
+	<-p.release
+	return &Response{Text: "ok", Model: req.Model}, nil
+}
+
+func (p *blockingProvider) started() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.prompts)
+}
+
+func (p *blockingProvider) order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.prompts...)
+}
+
+func (p *blockingProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *blockingProvider) Ping() error                   { return nil }
@@ -0,0 +1,50 @@
+package llm
+
+import "context"
+
+// TokenResetFunc is told that the output streamed so far was discarded and model is starting over
+type TokenResetFunc func(model string)
+
+type tokenResetKey struct{}
+
+// WithTokenReset returns a context whose retried streams report each restart to fn
+func WithTokenReset(ctx context.Context, fn TokenResetFunc) context.Context {
+	return context.WithValue(ctx, tokenResetKey{}, fn)
+}
+
+// ResetTokens tells the TokenResetFunc attached to ctx (if any) that a stream restarts on model
+func ResetTokens(ctx context.Context, model string) {
+	if fn, ok := ctx.Value(tokenResetKey{}).(TokenResetFunc); ok && fn != nil {
+		fn(model)
+	}
+}
+
+// RetryStream relays the tokens of one request across its attempts (retries, model fallbacks,
+// pool failover). An attempt that follows one which already streamed output first resets the
+// stream, so clients drop the failed attempt's tokens instead of splicing the next onto them.
+type RetryStream struct {
+	ctx      context.Context
+	onToken  TokenHandler
+	streamed bool
+}
+
+// NewRetryStream wraps onToken for a request that may be attempted more than once
+func NewRetryStream(ctx context.Context, onToken TokenHandler) *RetryStream {
+	return &RetryStream{ctx: ctx, onToken: onToken}
+}
+
+// Attempt returns the token handler for the next attempt on model (nil when not streaming)
+// Attempts run one at a time.
+func (s *RetryStream) Attempt(model string) TokenHandler {
+	if s.onToken == nil {
+		return nil
+	}
+	if s.streamed {
+		ResetTokens(s.ctx, model)
+		s.streamed = false
+	}
+	return func(chunk string) {
+		s.streamed = true
+		s.onToken(chunk)
+	}
+}
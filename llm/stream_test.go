@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// partialHost streams part of a reply, then optionally drops the connection
+type partialHost struct {
+	reply string
+	drop  bool
+}
+
+func (h *partialHost) Complete(ctx context.Context, req *Request) (*Response, error) {
+	for _, word := range strings.SplitAfter(h.reply, " ") {
+		if req.OnToken != nil && word != "" {
+			req.OnToken(word)
+		}
+	}
+	if h.drop {
+		return nil, fmt.Errorf("failed to decode stream chunk: %w", io.ErrUnexpectedEOF)
+	}
+	return &Response{Text: h.reply, Model: req.Model}, nil
+}
+
+func (h *partialHost) ListModels() ([]string, error) { return []string{"llama3:8b"}, nil }
+func (h *partialHost) Ping() error                   { return nil }
+
+// TestPoolFailoverResetsStream tests that a host failing mid-stream resets the stream before failover
+func TestPoolFailoverResetsStream(t *testing.T) {
+	pool := NewPool(map[string]Provider{
+		"gpu1": &partialHost{reply: "Half an ", drop: true},
+		"gpu2": &partialHost{reply: "A full answer"},
+	})
+	pool.CheckHosts()
+
+	var events []string
+	ctx := WithTokenReset(context.Background(), func(model string) { events = append(events, "reset:"+model) })
+	text, err := GenerateStream(ctx, pool, "llama3:8b", "p", func(chunk string) { events = append(events, chunk) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "A full answer" {
+		t.Errorf("text = %q", text)
+	}
+
+	want := "Half |an |reset:llama3:8b|A |full |answer"
+	if got := strings.Join(events, "|"); got != want {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+}
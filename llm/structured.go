@@ -0,0 +1,316 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxRepairs is the number of re-prompts GenerateStructured makes after an invalid response
+const DefaultMaxRepairs = 2
+
+// ErrInvalidOutput is returned when the model never produced a response matching the schema
+var ErrInvalidOutput = errors.New("model output does not match schema")
+
+// Validator is implemented by structured outputs that need checks beyond the schema
+type Validator interface {
+	Validate() error
+}
+
+// StructuredOptions tunes a GenerateStructured call
+type StructuredOptions struct {
+	ThinkingMode string       // Optional: fast, normal or extended; applies the mode's think flag and options
+	MaxRepairs   int          // Re-prompts after an invalid response (0 = DefaultMaxRepairs, negative = none)
+	OnToken      TokenHandler // Optional: receives the raw JSON as it streams
+}
+
+// Schema is the subset of JSON Schema that GenerateStructured derives from Go types
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	MinLength            int                `json:"minLength,omitempty"`
+}
+
+// GenerateStructured asks the model for a JSON object matching the schema of out
+// (a pointer to a struct) and decodes the response into it.
+// The schema is sent as Ollama's format parameter and spelled out in the prompt.
+// A response that is not valid JSON, misses required fields, has a value outside
+// an enum or fails out's Validate method is sent back to the model with the
+// problems listed, up to MaxRepairs times; after that ErrInvalidOutput is returned.
+func GenerateStructured(ctx context.Context, p Provider, model, prompt string, out interface{}, opts StructuredOptions) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("structured output must be a pointer to a struct, got %T", out)
+	}
+
+	schema := SchemaOf(target.Elem().Type())
+	format, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	prompt = fmt.Sprintf("%s\n\nRespond with only a JSON object that matches this JSON schema:\n%s", prompt, format)
+
+	maxRepairs := opts.MaxRepairs
+	if maxRepairs == 0 {
+		maxRepairs = DefaultMaxRepairs
+	} else if maxRepairs < 0 {
+		maxRepairs = 0
+	}
+
+	think := false
+	if opts.ThinkingMode != "" {
+		var modeReq Request
+		ctx = withThinkingMode(ctx, &modeReq, opts.ThinkingMode)
+		think = modeReq.Think
+	}
+
+	attemptPrompt := prompt
+	stream := NewRetryStream(ctx, opts.OnToken)
+	var problems []string
+
+	for attempt := 1; attempt <= maxRepairs+1; attempt++ {
+		resp, err := complete(ctx, p, &Request{
+			Model:   model,
+			Prompt:  attemptPrompt,
+			Format:  format,
+			Think:   think,
+			OnToken: stream.Attempt(model),
+		})
+		if err != nil {
+			return err
+		}
+
+		value := reflect.New(target.Elem().Type())
+		problems = decodeStructured(resp.Text, schema, value.Interface())
+		if len(problems) == 0 {
+			target.Elem().Set(value.Elem())
+			return nil
+		}
+
+		log.Printf("LLM structured: %s response rejected (attempt %d/%d): %s",
+			model, attempt, maxRepairs+1, strings.Join(problems, "; "))
+		attemptPrompt = repairPrompt(prompt, resp.Text, problems)
+	}
+
+	return fmt.Errorf("%w after %d attempts: %s", ErrInvalidOutput, maxRepairs+1, strings.Join(problems, "; "))
+}
+
+// SchemaOf derives a JSON schema from a Go type.
+// Struct fields are named by their json tag and are required unless tagged omitempty;
+// required strings must be non-empty. An `enum:"A,B"` tag restricts a string field
+// and a `desc:"..."` tag becomes its description.
+func SchemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return SchemaOf(t.Elem())
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue // Unexported
+			}
+
+			name, omitEmpty := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+
+			prop := SchemaOf(field.Type)
+			prop.Description = field.Tag.Get("desc")
+			if enum := field.Tag.Get("enum"); enum != "" {
+				prop.Enum = strings.Split(enum, ",")
+			}
+			if !omitEmpty {
+				schema.Required = append(schema.Required, name)
+				if prop.Type == "string" && prop.Enum == nil {
+					prop.MinLength = 1
+				}
+			}
+
+			schema.Properties[name] = prop
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: SchemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: SchemaOf(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// jsonFieldName returns a struct field's JSON name and whether it is omitempty
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	name := field.Name
+	omitEmpty := false
+
+	if tag := field.Tag.Get("json"); tag != "" {
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+	}
+
+	return name, omitEmpty
+}
+
+// decodeStructured validates a response against schema and decodes it into out
+// It returns the problems found; none means out holds the decoded value
+func decodeStructured(text string, schema *Schema, out interface{}) []string {
+	text = extractJSONObject(text)
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return []string{fmt.Sprintf("response is not valid JSON: %v", err)}
+	}
+
+	if problems := schema.validate(raw, ""); len(problems) > 0 {
+		return problems
+	}
+
+	if err := json.Unmarshal([]byte(text), out); err != nil {
+		return []string{fmt.Sprintf("response does not decode: %v", err)}
+	}
+
+	if v, ok := out.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return []string{err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// validate checks a decoded JSON value against the schema
+func (s *Schema) validate(value interface{}, path string) []string {
+	where := path
+	if where == "" {
+		where = "response"
+	}
+
+	var problems []string
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an object", where)}
+		}
+		for _, name := range s.Required {
+			if v, present := obj[name]; !present || v == nil {
+				problems = append(problems, fmt.Sprintf("%s is required", joinPath(path, name)))
+			}
+		}
+		for name, v := range obj {
+			if prop, ok := s.Properties[name]; ok && v != nil {
+				problems = append(problems, prop.validate(v, joinPath(path, name))...)
+			} else if s.AdditionalProperties != nil && v != nil {
+				problems = append(problems, s.AdditionalProperties.validate(v, joinPath(path, name))...)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an array", where)}
+		}
+		for i, item := range items {
+			problems = append(problems, s.Items.validate(item, fmt.Sprintf("%s[%d]", where, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a string", where)}
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, str) {
+			problems = append(problems, fmt.Sprintf("%s must be one of %s, got %q", where, strings.Join(s.Enum, ", "), str))
+		}
+		if len(strings.TrimSpace(str)) < s.MinLength {
+			problems = append(problems, fmt.Sprintf("%s must not be empty", where))
+		}
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a number", where)}
+		}
+		if s.Type == "integer" && num != float64(int64(num)) {
+			problems = append(problems, fmt.Sprintf("%s must be a whole number", where))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s must be true or false", where)}
+		}
+	}
+
+	return problems
+}
+
+// repairPrompt asks the model to correct a rejected response
+func repairPrompt(prompt, response string, problems []string) string {
+	return fmt.Sprintf(`%s
+
+Your previous response was rejected:
+%s
+
+Problems:
+- %s
+
+Respond again with only a corrected JSON object.`, prompt, response, strings.Join(problems, "\n- "))
+}
+
+// extractJSONObject strips markdown fences and any prose around the outermost JSON object
+func extractJSONObject(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		return text
+	}
+
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
+
+// joinPath appends a field name to a JSON path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider returns its responses in order and keeps the requests it saw
+type scriptedProvider struct {
+	responses []string
+	requests  []*Request
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	p.requests = append(p.requests, req)
+	text := p.responses[0]
+	if len(p.responses) > 1 {
+		p.responses = p.responses[1:]
+	}
+	return &Response{Text: text, Model: req.Model}, nil
+}
+
+func (p *scriptedProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *scriptedProvider) Ping() error                   { return nil }
+
+type testDecision struct {
+	Decision  string   `json:"decision" enum:"PROCEED,BLOCK"`
+	Reasoning string   `json:"reasoning"`
+	Steps     []string `json:"steps,omitempty"`
+}
+
+// TestGenerateStructuredRepairs tests that an invalid response is re-prompted with its problems
+func TestGenerateStructuredRepairs(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"decision": "MAYBE"}`,
+		"```json\n{\"decision\": \"PROCEED\", \"reasoning\": \"All checks passed\"}\n```",
+	}}
+
+	var out testDecision
+	if err := GenerateStructured(context.Background(), provider, "llama3:8b", "Decide.", &out, StructuredOptions{}); err != nil {
+		t.Fatalf("GenerateStructured() error = %v", err)
+	}
+
+	if out.Decision != "PROCEED" || out.Reasoning != "All checks passed" {
+		t.Errorf("decoded %+v", out)
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("provider called %d times, want 2", len(provider.requests))
+	}
+	if !strings.Contains(string(provider.requests[0].Format), `"enum":["PROCEED","BLOCK"]`) {
+		t.Errorf("format should carry the schema, got %s", provider.requests[0].Format)
+	}
+
+	repair := provider.requests[1].Prompt
+	for _, problem := range []string{`decision must be one of PROCEED, BLOCK, got "MAYBE"`, "reasoning is required"} {
+		if !strings.Contains(repair, problem) {
+			t.Errorf("repair prompt should mention %q:\n%s", problem, repair)
+		}
+	}
+}
+
+// TestGenerateStructuredGivesUp tests that repairs are bounded
+func TestGenerateStructuredGivesUp(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"not json"}}
+
+	var out testDecision
+	err := GenerateStructured(context.Background(), provider, "llama3:8b", "Decide.", &out, StructuredOptions{MaxRepairs: 1})
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("GenerateStructured() error = %v, want ErrInvalidOutput", err)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("provider called %d times, want 2", len(provider.requests))
+	}
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Thinking modes chosen by the complexity scorer
+const (
+	ThinkingFast     = "fast"
+	ThinkingNormal   = "normal"
+	ThinkingExtended = "extended"
+)
+
+// ThinkingProfile maps a thinking mode onto real model controls
+type ThinkingProfile struct {
+	Think   bool                   // Ask reasoning models to think before answering (Ollama's think flag)
+	Model   string                 // Optional: model to use for this mode instead of the configured one
+	Options map[string]interface{} // Generation options for this mode (num_predict, num_ctx, ...)
+}
+
+// DefaultThinkingProfiles are used for modes without a configured profile
+// Models that can't think ignore the flag (see Client.Complete).
+var DefaultThinkingProfiles = map[string]ThinkingProfile{
+	ThinkingFast:   {},
+	ThinkingNormal: {},
+	ThinkingExtended: {
+		Think:   true,
+		Options: map[string]interface{}{"num_ctx": 16384, "num_predict": 8192},
+	},
+}
+
+type thinkingProfilesKey struct{}
+
+// WithThinkingProfiles returns a context whose thinking modes use profiles
+// Modes missing from profiles keep their DefaultThinkingProfiles entry.
+func WithThinkingProfiles(ctx context.Context, profiles map[string]ThinkingProfile) context.Context {
+	if len(profiles) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, thinkingProfilesKey{}, profiles)
+}
+
+// ThinkingProfileFor returns the profile of a thinking mode; unknown modes get the normal profile
+func ThinkingProfileFor(ctx context.Context, mode string) ThinkingProfile {
+	if mode == "" {
+		mode = ThinkingNormal
+	}
+	profiles, _ := ctx.Value(thinkingProfilesKey{}).(map[string]ThinkingProfile)
+	if profile, ok := profiles[mode]; ok {
+		return profile
+	}
+	if profile, ok := DefaultThinkingProfiles[mode]; ok {
+		return profile
+	}
+	return DefaultThinkingProfiles[ThinkingNormal]
+}
+
+// ThinkingModel returns the model a thinking mode uses in place of model
+func ThinkingModel(ctx context.Context, mode, model string) string {
+	if m := ThinkingProfileFor(ctx, mode).Model; m != "" {
+		return m
+	}
+	return model
+}
+
+// ThinkingOptions returns the generation options of calls made in a thinking mode:
+// the mode's options, overridden by any attached to ctx (task and agent settings win)
+func ThinkingOptions(ctx context.Context, mode string) map[string]interface{} {
+	return mergeOptions(ThinkingProfileFor(ctx, mode).Options, OptionsFrom(ctx))
+}
+
+// withThinkingMode prepares ctx and req for a call in a thinking mode
+func withThinkingMode(ctx context.Context, req *Request, mode string) context.Context {
+	req.Think = ThinkingProfileFor(ctx, mode).Think
+	return WithOptions(ctx, ThinkingOptions(ctx, mode))
+}
+
+// Inline reasoning markers emitted by models such as deepseek-r1 and qwq
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinking separates inline <think>...</think> blocks from a model's answer
+// An unterminated block (output cut off mid-thought) is all reasoning.
+func SplitThinking(text string) (answer, thinking string) {
+	if !strings.Contains(text, thinkOpen) {
+		return text, ""
+	}
+
+	var answerParts, thoughts []string
+	rest := text
+	for {
+		start := strings.Index(rest, thinkOpen)
+		if start == -1 {
+			answerParts = append(answerParts, rest)
+			break
+		}
+		answerParts = append(answerParts, rest[:start])
+		rest = rest[start+len(thinkOpen):]
+
+		end := strings.Index(rest, thinkClose)
+		if end == -1 {
+			thoughts = append(thoughts, strings.TrimSpace(rest))
+			break
+		}
+		thoughts = append(thoughts, strings.TrimSpace(rest[:end]))
+		rest = rest[end+len(thinkClose):]
+	}
+
+	return strings.TrimSpace(strings.Join(answerParts, "")), strings.Join(thoughts, "\n\n")
+}
+
+// thinkFilter forwards streamed tokens to a handler with inline <think> blocks removed
+// A tag split across chunks is held back until it can be recognised.
+type thinkFilter struct {
+	onToken  TokenHandler
+	pending  string
+	inThink  bool
+	answered bool // Some answer text has been forwarded
+}
+
+// write processes one streamed chunk
+func (f *thinkFilter) write(chunk string) {
+	f.pending += chunk
+
+	for f.pending != "" {
+		tag := thinkOpen
+		if f.inThink {
+			tag = thinkClose
+		}
+
+		if i := strings.Index(f.pending, tag); i >= 0 {
+			f.emit(f.pending[:i])
+			f.pending = f.pending[i+len(tag):]
+			f.inThink = !f.inThink
+			continue
+		}
+
+		// Keep a possible partial tag at the end for the next chunk
+		keep := partialSuffix(f.pending, tag)
+		f.emit(f.pending[:len(f.pending)-keep])
+		f.pending = f.pending[len(f.pending)-keep:]
+		return
+	}
+}
+
+// flush forwards text held back at the end of the stream
+func (f *thinkFilter) flush() {
+	f.emit(f.pending)
+	f.pending = ""
+}
+
+// emit forwards answer text, dropping reasoning and the blank lines that follow it
+func (f *thinkFilter) emit(text string) {
+	if f.inThink || text == "" {
+		return
+	}
+	if !f.answered {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		f.answered = true
+	}
+	f.onToken(text)
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a proper prefix of tag
+func partialSuffix(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
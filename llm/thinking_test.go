@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestInlineThinkingSeparated tests that <think> blocks are kept out of the answer
+// and the token stream, even when the tags are split across chunks
+func TestInlineThinkingSeparated(t *testing.T) {
+	chunks := []string{"<thi", "nk>Plan the files.\n", "Use one HTML file.</th", "ink>\n\n## Implementation", "\n<p>ok</p>"}
+	provider := &streamingProvider{chunks: chunks}
+
+	var streamed strings.Builder
+	answer, thinking, err := GenerateWithThinkingStream(context.Background(), provider, "deepseek-r1:8b", "Build it.", ThinkingNormal, func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithThinkingStream() error = %v", err)
+	}
+
+	wantAnswer := "## Implementation\n<p>ok</p>"
+	if answer != wantAnswer {
+		t.Errorf("answer = %q, want %q", answer, wantAnswer)
+	}
+	if streamed.String() != wantAnswer {
+		t.Errorf("streamed = %q, want %q", streamed.String(), wantAnswer)
+	}
+	if thinking != "Plan the files.\nUse one HTML file." {
+		t.Errorf("thinking = %q", thinking)
+	}
+}
+
+// TestThinkingModeControls tests that the extended mode sets the think flag and
+// its options without overriding options attached to the context
+func TestThinkingModeControls(t *testing.T) {
+	provider := &streamingProvider{chunks: []string{"done"}}
+	ctx := WithOptions(context.Background(), map[string]interface{}{"num_ctx": 32768})
+
+	if _, _, err := GenerateWithThinking(ctx, provider, "qwen3:8b", "Build it.", ThinkingExtended); err != nil {
+		t.Fatalf("GenerateWithThinking() error = %v", err)
+	}
+
+	req := provider.requests[0]
+	if !req.Think {
+		t.Errorf("extended mode should set the think flag")
+	}
+	if req.Prompt != "Build it." {
+		t.Errorf("prompt should be sent unchanged, got %q", req.Prompt)
+	}
+	if req.Options["num_ctx"] != 32768 || req.Options["num_predict"] != 8192 {
+		t.Errorf("options = %v, want num_ctx from ctx and num_predict from the mode", req.Options)
+	}
+}
+
+// streamingProvider streams its chunks to OnToken and returns them joined
+type streamingProvider struct {
+	chunks   []string
+	requests []*Request
+}
+
+func (p *streamingProvider) Complete(ctx context.Context, req *Request) (*Response, error) {
+	p.requests = append(p.requests, req)
+	for _, chunk := range p.chunks {
+		if req.OnToken != nil {
+			req.OnToken(chunk)
+		}
+	}
+	return &Response{Text: strings.Join(p.chunks, ""), Model: req.Model}, nil
+}
+
+func (p *streamingProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *streamingProvider) Ping() error                   { return nil }
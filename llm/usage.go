@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage holds token counts and timings for one or more LLM calls
+// Backends that don't report a figure leave it at zero
+type Usage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalSeconds     float64 `json:"total_seconds"`   // Generation time reported by the backend
+	LoadSeconds      float64 `json:"load_seconds"`    // Part of TotalSeconds spent loading the model
+	LatencySeconds   float64 `json:"latency_seconds"` // Wall-clock time seen by the caller
+}
+
+// Add accumulates other into u
+func (u *Usage) Add(other Usage) {
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalSeconds += other.TotalSeconds
+	u.LoadSeconds += other.LoadSeconds
+	u.LatencySeconds += other.LatencySeconds
+}
+
+// Sub returns u minus other, for splitting a total into its parts
+func (u Usage) Sub(other Usage) Usage {
+	return Usage{
+		Calls:            u.Calls - other.Calls,
+		PromptTokens:     u.PromptTokens - other.PromptTokens,
+		CompletionTokens: u.CompletionTokens - other.CompletionTokens,
+		TotalSeconds:     u.TotalSeconds - other.TotalSeconds,
+		LoadSeconds:      u.LoadSeconds - other.LoadSeconds,
+		LatencySeconds:   u.LatencySeconds - other.LatencySeconds,
+	}
+}
+
+// IsZero reports whether no calls were recorded
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// UsageMeter accumulates the usage of LLM calls made under a context, in total and per model
+type UsageMeter struct {
+	mu      sync.Mutex
+	total   Usage
+	byModel map[string]Usage
+	parent  *UsageMeter
+}
+
+type usageMeterKey struct{}
+
+// WithUsageMeter returns a context whose LLM calls are added to meter.
+// Meters nest: a call counts towards every UsageMeter attached up the context chain.
+func WithUsageMeter(ctx context.Context, meter *UsageMeter) context.Context {
+	if parent, ok := ctx.Value(usageMeterKey{}).(*UsageMeter); ok && parent != meter {
+		meter.parent = parent
+	}
+	return context.WithValue(ctx, usageMeterKey{}, meter)
+}
+
+// Total returns the usage recorded so far
+func (m *UsageMeter) Total() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
+
+// ByModel returns the usage recorded so far, per model
+func (m *UsageMeter) ByModel() map[string]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byModel := make(map[string]Usage, len(m.byModel))
+	for model, u := range m.byModel {
+		byModel[model] = u
+	}
+	return byModel
+}
+
+// add records one call on this meter only
+func (m *UsageMeter) add(model string, u Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.byModel == nil {
+		m.byModel = make(map[string]Usage)
+	}
+	m.total.Add(u)
+	perModel := m.byModel[model]
+	perModel.Add(u)
+	m.byModel[model] = perModel
+}
+
+// complete runs a request with the generation options attached to ctx and
+// records its usage on every meter attached to ctx
+// Inline <think> blocks are moved from the answer to Response.Thinking and kept
+// out of the token stream.
+// All package-level Generate helpers go through here
+func complete(ctx context.Context, p Provider, req *Request) (*Response, error) {
+	req.Options = effectiveOptions(ctx, req.Options)
+
+	var filter *thinkFilter
+	if req.OnToken != nil {
+		filter = &thinkFilter{onToken: req.OnToken}
+		req.OnToken = filter.write
+	}
+
+	start := time.Now()
+
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		filter.flush()
+	}
+	answer, inline := SplitThinking(resp.Text)
+	resp.Text = answer
+	if inline != "" {
+		resp.Thinking = strings.TrimSpace(resp.Thinking + "\n\n" + inline)
+	}
+
+	resp.Usage.Calls = 1
+	resp.Usage.LatencySeconds = time.Since(start).Seconds()
+
+	meter, _ := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	for ; meter != nil; meter = meter.parent {
+		meter.add(req.Model, resp.Usage)
+	}
+
+	return resp, nil
+}
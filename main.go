@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"ai-studio/orchestrator/api"
+	"ai-studio/orchestrator/batch"
+	"ai-studio/orchestrator/llm"
 	"ai-studio/orchestrator/project"
 	"ai-studio/orchestrator/supervisor"
 	"ai-studio/orchestrator/task"
@@ -15,9 +21,12 @@ import (
 
 func main() {
 	// CLI flags
-	mode := flag.String("mode", "server", "Run mode: server or cli")
+	mode := flag.String("mode", "server", "Run mode: server, cli, batch or project (lifecycle commands; -mode=project help)")
 	taskType := flag.String("task", "", "Task type for CLI mode: validate or review")
-	input := flag.String("input", "", "Input file path for CLI mode")
+	input := flag.String("input", "", "Input file path for CLI mode, or JSONL requests file for batch mode")
+	noCache := flag.Bool("no-cache", false, "Bypass the LLM response cache (CLI, batch and project modes)")
+	parallel := flag.Int("parallel", batch.DefaultParallel, "Requests run at once (batch mode)")
+	outDir := flag.String("out", "", "Results directory for batch mode (default batch_results/<input name>)")
 
 	// Use Railway PORT if available
 	defaultPort := 8080
@@ -37,7 +46,10 @@ func main() {
 	}
 
 	// Initialize base task manager
-	baseMgr := task.NewManager(baseConfig)
+	baseMgr, err := task.NewManager(baseConfig)
+	if err != nil {
+		log.Fatalf("Failed to create task manager: %v", err)
+	}
 
 	// Wrap with supervisor if enabled
 	var taskMgr api.TaskManager
@@ -74,10 +86,25 @@ func main() {
 		log.Printf("✓ ProjectOrchestrator enabled (projects dir: %s)", baseConfig.ProjectOrchestrator.ProjectsDir)
 	}
 
+	// Check that every model the configuration refers to is installed
+	modelUsage := api.CollectModelUsage(baseConfig, supervisorConfig)
+	missingModels, err := api.VerifyModels(baseMgr.GetClient(), modelUsage)
+	if err != nil {
+		log.Printf("Warning: could not verify models: %v", err)
+	}
+
 	switch *mode {
 	case "server":
 		// Start HTTP server
 		server := api.NewServer(taskMgr, *port)
+		server.SetModelUsage(modelUsage)
+		server.SetDiscoverOptions(task.DiscoverOptions{
+			MinQuestions: baseConfig.Discovery.MinQuestions,
+			MaxQuestions: baseConfig.Discovery.MaxQuestions,
+		})
+		if baseConfig.AutoPullModels && len(missingModels) > 0 {
+			server.PullModels(missingModels)
+		}
 
 		// Wire up WebSocket hub to orchestrator (if it's a ProjectOrchestrator)
 		if orchestrator, ok := taskMgr.(interface{ SetWebSocketHub(interface{}) }); ok {
@@ -103,7 +130,18 @@ func main() {
 			log.Fatalf("Failed to read input: %v", err)
 		}
 
-		result, err := taskMgr.ExecuteTask(*taskType, string(inputData))
+		// Ctrl+C cancels the running task (and any child processes) cleanly
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *noCache {
+			ctx = llm.WithoutCache(ctx)
+		}
+
+		if baseConfig.AutoPullModels {
+			pullModels(ctx, baseMgr.GetClient(), missingModels)
+		}
+
+		result, err := taskMgr.ExecuteTask(ctx, *taskType, string(inputData))
 		if err != nil {
 			log.Fatalf("Task execution failed: %v", err)
 		}
@@ -116,7 +154,72 @@ func main() {
 			fmt.Printf("\n=== Task Result ===\n%+v\n", result)
 		}
 
+	case "batch":
+		// Batch mode runs every request of a JSONL file, resuming where an earlier run stopped
+		if *input == "" {
+			fmt.Println("Usage: orchestrator -mode=batch -input=<requests.jsonl> [-parallel=N] [-out=<dir>]")
+			os.Exit(1)
+		}
+		if *outDir == "" {
+			name := strings.TrimSuffix(filepath.Base(*input), filepath.Ext(*input))
+			*outDir = filepath.Join("batch_results", name)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *noCache {
+			ctx = llm.WithoutCache(ctx)
+		}
+
+		if baseConfig.AutoPullModels {
+			pullModels(ctx, baseMgr.GetClient(), missingModels)
+		}
+
+		runner := batch.NewRunner(taskMgr, batch.Options{Parallel: *parallel, OutDir: *outDir})
+		summary, err := runner.Run(ctx, *input)
+		if summary != nil {
+			fmt.Printf("\n=== Batch Summary ===\n%d completed, %d failed, %d pending of %d request(s)\n",
+				summary.Completed, summary.Failed, summary.Pending, summary.Total)
+			fmt.Printf("Report: %s\n", filepath.Join(*outDir, "summary.md"))
+		}
+		if err != nil {
+			log.Fatalf("Batch run stopped: %v", err)
+		}
+		if summary.Failed > 0 {
+			os.Exit(1)
+		}
+
+	case "project":
+		// Project lifecycle commands run in-process against the projects store
+		orchestrator, ok := taskMgr.(*project.ProjectOrchestrator)
+		if !ok {
+			log.Fatalf("Project orchestrator not enabled (requires supervisor and project_orchestrator in config)")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *noCache {
+			ctx = llm.WithoutCache(ctx)
+		}
+
+		if baseConfig.AutoPullModels && flag.Arg(0) == "run" {
+			pullModels(ctx, baseMgr.GetClient(), missingModels)
+		}
+
+		if err := runProjectCommand(ctx, orchestrator, flag.Args()); err != nil {
+			log.Fatalf("Project command failed: %v", err)
+		}
+
 	default:
 		log.Fatalf("Unknown mode: %s", *mode)
 	}
 }
+
+// pullModels pulls models that aren't installed, logging failures
+func pullModels(ctx context.Context, client llm.Provider, models []string) {
+	for _, model := range models {
+		if err := api.PullModel(ctx, client, model, nil); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package project
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteZip writes every file under dir to w as a ZIP archive, with paths relative to dir
+func WriteZip(w io.Writer, dir string) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		zipFile, err := zipWriter.Create(filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+
+		sourceFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer sourceFile.Close()
+
+		_, err = io.Copy(zipFile, sourceFile)
+		return err
+	})
+	if err != nil {
+		zipWriter.Close()
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	return zipWriter.Close()
+}
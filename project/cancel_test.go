@@ -0,0 +1,119 @@
+package project
+
+import (
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCancelPhase tests that cancelling a phase blocked on the model marks it cancelled and frees the project
+func TestCancelPhase(t *testing.T) {
+	// An Ollama whose model calls never answer until the request is abandoned
+	// (the body is read so the server notices the client hanging up)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models": []}`))
+			return
+		}
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer ollama.Close()
+	defer close(release)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := &config.Config{OllamaURL: ollama.URL, ArtifactsDir: "artifacts", Timeout: 60}
+	baseMgr, err := task.NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisorCfg := supervisor.DefaultSupervisorConfig()
+	supervisorCfg.Agents.Requirements.Enabled = true
+	supervisedMgr := supervisor.NewSupervisedTaskManager(baseMgr, cfg, supervisorCfg)
+	po, err := NewProjectOrchestrator(supervisedMgr, "projects", cfg.ArtifactsDir, baseMgr.GetClient(),
+		supervisedMgr.GetRequirementsAgent(), supervisedMgr.GetTechStackAgent(), supervisedMgr.GetScopeAgent(),
+		supervisedMgr.GetQAAgent(), supervisedMgr.GetTestingAgent(), supervisedMgr.GetDocsAgent(),
+		supervisedMgr.GetComplexityScorer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := po.CreateProject("Todo App", "A todo list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := po.CancelPhase(project.ID); err == nil {
+		t.Error("cancelling a project with no running phase succeeded")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := po.ExecuteProjectPhase(context.Background(), project.ID, PhaseDiscovery)
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("phase never called the model")
+	}
+	if !po.IsPhaseRunning(project.ID) {
+		t.Fatal("running phase is not registered")
+	}
+	if _, err := po.ExecuteProjectPhase(context.Background(), project.ID, PhaseDiscovery); err == nil {
+		t.Error("a second run of the same project started")
+	}
+
+	if err := po.CancelPhase(project.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("cancelled phase reported success")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("phase kept running after cancel")
+	}
+
+	if po.IsPhaseRunning(project.ID) {
+		t.Error("cancelled phase is still registered as running")
+	}
+	project, err = po.GetProject(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status PhaseStatus
+	for _, phaseExec := range project.Phases {
+		if phaseExec.Phase == PhaseDiscovery {
+			status = phaseExec.Status
+		}
+	}
+	if status != PhaseStatusCancelled {
+		t.Errorf("discovery phase status = %q, want %s", status, PhaseStatusCancelled)
+	}
+}
@@ -0,0 +1,38 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"ai-studio/orchestrator/task"
+)
+
+// TestAssessPatch tests that a failed, partial or empty incremental patch sends CodeGen back to REFINE
+func TestAssessPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    task.PatchReport
+		decision string
+		next     string // Substring of the next steps
+	}{
+		{"applied", task.PatchReport{Modified: []string{"app.js"}}, "PROCEED", "Proceed to Review"},
+		{"write failed", task.PatchReport{Error: "disk full"}, "REFINE", "project is unchanged"},
+		{"failed hunks", task.PatchReport{
+			Modified:    []string{"app.js"},
+			FailedHunks: []task.FailedHunk{{Path: "style.css"}, {Path: "style.css"}, {Path: "index.html"}},
+		}, "REFINE", "style.css, index.html"},
+		{"nothing changed", task.PatchReport{}, "REFINE", "changed no files"},
+	}
+
+	for _, tt := range tests {
+		decision, _, next := assessPatch(&tt.patch, "PROCEED", "", "Proceed to Review phase")
+		if decision != tt.decision || !strings.Contains(next, tt.next) {
+			t.Errorf("%s: got %s, %q; want %s, %q", tt.name, decision, next, tt.decision, tt.next)
+		}
+	}
+
+	// A blocked phase stays blocked
+	if decision, _, _ := assessPatch(&task.PatchReport{}, "BLOCK", "", ""); decision != "BLOCK" {
+		t.Errorf("blocked phase became %s", decision)
+	}
+}
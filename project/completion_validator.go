@@ -2,9 +2,9 @@ package project
 
 import (
 	"ai-studio/orchestrator/supervisor"
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -21,16 +21,16 @@ func NewCompletionValidator(artifactsDir string) *CompletionValidator {
 }
 
 // ValidateHandoffReady validates if a project meets hand-off ready criteria
-func (cv *CompletionValidator) ValidateHandoffReady(project *Project) (*CompletionMetrics, error) {
+func (cv *CompletionValidator) ValidateHandoffReady(ctx context.Context, project *Project) (*CompletionMetrics, error) {
 	metrics := &CompletionMetrics{
 		BlockingIssues: []string{},
 	}
 
 	// Run actual build verification if project directory exists
-	projectDir := cv.extractProjectDirectory(project)
+	projectDir := generatedDir(project)
 	if projectDir != "" {
 		verifyAgent := supervisor.NewVerificationAgent()
-		verifyResult, err := verifyAgent.VerifyProject(projectDir)
+		verifyResult, err := verifyAgent.VerifyProject(ctx, projectDir)
 		if err == nil && verifyResult != nil {
 			// Store verification results
 			metrics.SyntaxValid = verifyResult.SyntaxValid
@@ -101,32 +101,6 @@ func (cv *CompletionValidator) ValidateHandoffReady(project *Project) (*Completi
 	return metrics, nil
 }
 
-// extractProjectDirectory extracts project directory from artifact paths
-func (cv *CompletionValidator) extractProjectDirectory(project *Project) string {
-	// Look for project directory in artifact paths (e.g., "projects/generated_1234567890")
-	for _, artifactPath := range project.ArtifactPaths {
-		if strings.Contains(artifactPath, "projects/generated_") || strings.Contains(artifactPath, "projects\\generated_") {
-			// Extract directory from path like "artifacts\code_123.md (project: projects/generated_456)"
-			if strings.Contains(artifactPath, "(project: ") {
-				start := strings.Index(artifactPath, "(project: ") + len("(project: ")
-				end := strings.Index(artifactPath[start:], ")")
-				if end > 0 {
-					return artifactPath[start : start+end]
-				}
-			}
-			// Direct project path
-			if strings.HasPrefix(artifactPath, "projects/") || strings.HasPrefix(artifactPath, "projects\\") {
-				// Return the base project directory
-				parts := strings.Split(filepath.ToSlash(artifactPath), "/")
-				if len(parts) >= 2 {
-					return filepath.Join(parts[0], parts[1])
-				}
-			}
-		}
-	}
-	return ""
-}
-
 // checkRunnableBuildMarkers checks if runnable code exists (marker-based fallback)
 func (cv *CompletionValidator) checkRunnableBuildMarkers(project *Project) (bool, error) {
 	// Entry point markers for different languages
@@ -3,6 +3,7 @@ package project
 import (
 	"ai-studio/orchestrator/llm"
 	"ai-studio/orchestrator/supervisor"
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -10,8 +11,9 @@ import (
 
 // LeadAgent coordinates project workflow and makes phase decisions
 type LeadAgent struct {
-	llmClient *llm.Client
+	llmClient llm.Provider
 	model     string
+	options   map[string]interface{} // Generation options for decisions and summaries; nil = model defaults
 
 	// Specialist agents
 	requirementsAgent *supervisor.RequirementsAgent
@@ -38,10 +40,14 @@ type PhaseResult struct {
 	RequiresApproval  bool
 	RecommendedAction string
 	PlanDocument      *PlanDocument // For planning phase results
+	CacheHits         int           // LLM responses served from cache during the phase
+	CacheMisses       int           // LLM calls sent to the model during the phase
+	Usage             llm.Usage     // Tokens and timings of every LLM call in the phase
+	ModelUsage        map[string]llm.Usage
 }
 
 // NewLeadAgent creates a new lead agent
-func NewLeadAgent(llmClient *llm.Client, model string,
+func NewLeadAgent(llmClient llm.Provider, model string,
 	requirementsAgent *supervisor.RequirementsAgent,
 	techStackAgent *supervisor.TechStackAgent,
 	scopeAgent *supervisor.ScopeAgent,
@@ -65,27 +71,29 @@ func NewLeadAgent(llmClient *llm.Client, model string,
 }
 
 // ExecutePhase executes a project phase and returns a decision
-func (la *LeadAgent) ExecutePhase(project *Project, phase Phase) (*PhaseResult, error) {
+// Lead Agent output is streamed to onToken when it is non-nil
+// Cancelling ctx aborts the running agent or LLM call
+func (la *LeadAgent) ExecutePhase(ctx context.Context, project *Project, phase Phase, onToken llm.TokenHandler) (*PhaseResult, error) {
 	switch phase {
 	case PhaseDiscovery:
-		return la.executeDiscoveryPhase(project)
+		return la.executeDiscoveryPhase(ctx, project, onToken)
 	case PhaseValidation:
-		return la.executeValidationPhase(project)
+		return la.executeValidationPhase(ctx, project, onToken)
 	case PhasePlanning:
-		return la.executePlanningPhase(project)
+		return la.executePlanningPhase(ctx, project, onToken)
 	case PhaseReview:
-		return la.executeReviewPhase(project)
+		return la.executeReviewPhase(ctx, project, onToken)
 	case PhaseQA:
-		return la.executeQAPhase(project)
+		return la.executeQAPhase(ctx, project)
 	case PhaseDocs:
-		return la.executeDocsPhase(project)
+		return la.executeDocsPhase(ctx, project)
 	default:
 		return nil, fmt.Errorf("unsupported phase for lead agent: %s", phase)
 	}
 }
 
 // executeDiscoveryPhase executes the Discovery phase
-func (la *LeadAgent) executeDiscoveryPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executeDiscoveryPhase(ctx context.Context, project *Project, onToken llm.TokenHandler) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing Discovery phase for project %s", project.Name)
 
 	// Score complexity and determine thinking mode
@@ -106,7 +114,7 @@ func (la *LeadAgent) executeDiscoveryPhase(project *Project) (*PhaseResult, erro
 		"phase":      "discovery",
 	}
 
-	reqOutput, err := la.requirementsAgent.Execute("discovery", project.Description, context)
+	reqOutput, err := la.requirementsAgent.Execute(ctx, "discovery", project.Description, context)
 	if err != nil {
 		return nil, fmt.Errorf("requirements agent failed: %w", err)
 	}
@@ -115,31 +123,28 @@ func (la *LeadAgent) executeDiscoveryPhase(project *Project) (*PhaseResult, erro
 	prompt := la.buildDiscoveryPrompt(project, reqOutput)
 
 	// Get Lead Agent decision
-	response, err := la.llmClient.Generate(la.model, prompt)
+	decision, err := la.decide(ctx, prompt, onToken)
 	if err != nil {
-		return nil, fmt.Errorf("lead agent LLM call failed: %w", err)
+		return nil, err
 	}
 
-	// Parse decision
-	decision, reasoning, nextSteps := la.parseDecision(response)
-
 	result := &PhaseResult{
 		Phase:     PhaseDiscovery,
-		Decision:  decision,
-		Reasoning: reasoning,
-		NextSteps: nextSteps,
+		Decision:  decision.Decision,
+		Reasoning: decision.Reasoning,
+		NextSteps: decision.NextSteps,
 		AgentOutputs: map[string]*supervisor.AgentOutput{
 			"requirements": reqOutput,
 		},
 		RequiresApproval:  true,
-		RecommendedAction: la.getRecommendedAction(decision),
+		RecommendedAction: la.getRecommendedAction(decision.Decision),
 	}
 
 	return result, nil
 }
 
 // executeValidationPhase executes the Validation phase
-func (la *LeadAgent) executeValidationPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executeValidationPhase(ctx context.Context, project *Project, onToken llm.TokenHandler) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing Validation phase for project %s", project.Name)
 
 	context := map[string]interface{}{
@@ -148,12 +153,12 @@ func (la *LeadAgent) executeValidationPhase(project *Project) (*PhaseResult, err
 	}
 
 	// Invoke TechStack and Scope agents in parallel
-	techStackOutput, err := la.techStackAgent.Execute("code", project.Description, context)
+	techStackOutput, err := la.techStackAgent.Execute(ctx, "code", project.Description, context)
 	if err != nil {
 		return nil, fmt.Errorf("tech stack agent failed: %w", err)
 	}
 
-	scopeOutput, err := la.scopeAgent.Execute("code", project.Description, context)
+	scopeOutput, err := la.scopeAgent.Execute(ctx, "code", project.Description, context)
 	if err != nil {
 		return nil, fmt.Errorf("scope agent failed: %w", err)
 	}
@@ -162,36 +167,33 @@ func (la *LeadAgent) executeValidationPhase(project *Project) (*PhaseResult, err
 	prompt := la.buildValidationPrompt(project, techStackOutput, scopeOutput)
 
 	// Get Lead Agent decision
-	response, err := la.llmClient.Generate(la.model, prompt)
+	decision, err := la.decide(ctx, prompt, onToken)
 	if err != nil {
-		return nil, fmt.Errorf("lead agent LLM call failed: %w", err)
+		return nil, err
 	}
 
-	// Parse decision
-	decision, reasoning, nextSteps := la.parseDecision(response)
-
 	result := &PhaseResult{
 		Phase:     PhaseValidation,
-		Decision:  decision,
-		Reasoning: reasoning,
-		NextSteps: nextSteps,
+		Decision:  decision.Decision,
+		Reasoning: decision.Reasoning,
+		NextSteps: decision.NextSteps,
 		AgentOutputs: map[string]*supervisor.AgentOutput{
 			"techstack": techStackOutput,
 			"scope":     scopeOutput,
 		},
 		RequiresApproval:  true,
-		RecommendedAction: la.getRecommendedAction(decision),
+		RecommendedAction: la.getRecommendedAction(decision.Decision),
 	}
 
 	return result, nil
 }
 
 // executePlanningPhase executes the Planning phase and generates a structured plan
-func (la *LeadAgent) executePlanningPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executePlanningPhase(ctx context.Context, project *Project, onToken llm.TokenHandler) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing Planning phase for project %s", project.Name)
 
 	// Generate structured plan document
-	planDoc, err := la.planGenerator.GeneratePlan(project)
+	planDoc, err := la.planGenerator.GeneratePlan(ctx, project, onToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate plan document: %w", err)
 	}
@@ -265,7 +267,7 @@ func (la *LeadAgent) formatPlanSummary(plan *PlanDocument) string {
 }
 
 // executeReviewPhase executes the Review phase
-func (la *LeadAgent) executeReviewPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executeReviewPhase(ctx context.Context, project *Project, onToken llm.TokenHandler) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing Review phase for project %s", project.Name)
 
 	// Get the latest code artifact from project
@@ -285,7 +287,7 @@ func (la *LeadAgent) executeReviewPhase(project *Project) (*PhaseResult, error)
 	}
 
 	// Invoke QA and Testing agents
-	qaOutput, err := la.qaAgent.Execute("code", project.Description, context)
+	qaOutput, err := la.qaAgent.Execute(ctx, "code", project.Description, context)
 	if err != nil {
 		log.Printf("Warning: QA agent failed: %v", err)
 		qaOutput = &supervisor.AgentOutput{
@@ -294,7 +296,7 @@ func (la *LeadAgent) executeReviewPhase(project *Project) (*PhaseResult, error)
 		}
 	}
 
-	testingOutput, err := la.testingAgent.Execute("code", project.Description, context)
+	testingOutput, err := la.testingAgent.Execute(ctx, "code", project.Description, context)
 	if err != nil {
 		log.Printf("Warning: Testing agent failed: %v", err)
 		testingOutput = &supervisor.AgentOutput{
@@ -307,32 +309,29 @@ func (la *LeadAgent) executeReviewPhase(project *Project) (*PhaseResult, error)
 	prompt := la.buildReviewPrompt(project, qaOutput, testingOutput)
 
 	// Get Lead Agent decision
-	response, err := la.llmClient.Generate(la.model, prompt)
+	decision, err := la.decide(ctx, prompt, onToken)
 	if err != nil {
-		return nil, fmt.Errorf("lead agent LLM call failed: %w", err)
+		return nil, err
 	}
 
-	// Parse decision
-	decision, reasoning, nextSteps := la.parseDecision(response)
-
 	result := &PhaseResult{
 		Phase:     PhaseReview,
-		Decision:  decision,
-		Reasoning: reasoning,
-		NextSteps: nextSteps,
+		Decision:  decision.Decision,
+		Reasoning: decision.Reasoning,
+		NextSteps: decision.NextSteps,
 		AgentOutputs: map[string]*supervisor.AgentOutput{
 			"qa":      qaOutput,
 			"testing": testingOutput,
 		},
-		RequiresApproval:  decision != "PROCEED",
-		RecommendedAction: la.getRecommendedAction(decision),
+		RequiresApproval:  decision.Decision != "PROCEED",
+		RecommendedAction: la.getRecommendedAction(decision.Decision),
 	}
 
 	return result, nil
 }
 
 // executeQAPhase executes the QA phase
-func (la *LeadAgent) executeQAPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executeQAPhase(ctx context.Context, project *Project) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing QA phase for project %s", project.Name)
 
 	// QA phase validation is handled by CompletionValidator
@@ -351,7 +350,7 @@ func (la *LeadAgent) executeQAPhase(project *Project) (*PhaseResult, error) {
 }
 
 // executeDocsPhase executes the Docs phase
-func (la *LeadAgent) executeDocsPhase(project *Project) (*PhaseResult, error) {
+func (la *LeadAgent) executeDocsPhase(ctx context.Context, project *Project) (*PhaseResult, error) {
 	log.Printf("Lead Agent: Executing Docs phase for project %s", project.Name)
 
 	context := map[string]interface{}{
@@ -360,7 +359,7 @@ func (la *LeadAgent) executeDocsPhase(project *Project) (*PhaseResult, error) {
 	}
 
 	// Invoke Documentation agent
-	docsOutput, err := la.docsAgent.Execute("code", project.Description, context)
+	docsOutput, err := la.docsAgent.Execute(ctx, "code", project.Description, context)
 	if err != nil {
 		log.Printf("Warning: Documentation agent failed: %v", err)
 		docsOutput = &supervisor.AgentOutput{
@@ -403,10 +402,10 @@ Criteria:
 - REFINE: Requirements need clarification, score 4-6/10
 - BLOCK: Requirements incomplete or unclear, score < 4/10
 
-Respond in this format:
-DECISION: [PROCEED|REFINE|BLOCK]
-REASONING: [2-3 sentences explaining decision]
-NEXT_STEPS: [What needs to happen next]`,
+Respond with:
+- decision: PROCEED, REFINE or BLOCK
+- reasoning: 2-3 sentences explaining the decision
+- next_steps: what needs to happen next`,
 		la.getBaseSystemPrompt(),
 		project.Name,
 		project.Description,
@@ -435,10 +434,10 @@ Criteria:
 - REFINE: Warnings present but addressable
 - BLOCK: Tech stack rejected OR scope too broad
 
-Respond in this format:
-DECISION: [PROCEED|REFINE|BLOCK]
-REASONING: [Why this decision]
-NEXT_STEPS: [Required actions]`,
+Respond with:
+- decision: PROCEED, REFINE or BLOCK
+- reasoning: why this decision
+- next_steps: required actions`,
 		la.getBaseSystemPrompt(),
 		project.Name,
 		techStack.Output,
@@ -492,10 +491,10 @@ Criteria:
 - REFINE: QA score 5-6/10, some issues present
 - BLOCK: QA score < 5/10, critical bugs detected
 
-Respond in this format:
-DECISION: [PROCEED|REFINE|BLOCK]
-REASONING: [Assessment of code quality]
-NEXT_STEPS: [What to do next]`,
+Respond with:
+- decision: PROCEED, REFINE or BLOCK
+- reasoning: assessment of code quality
+- next_steps: what to do next`,
 		la.getBaseSystemPrompt(),
 		project.Name,
 		qa.Output,
@@ -527,43 +526,22 @@ Always:
 - Defer to human judgment on ambiguous cases`
 }
 
-// parseDecision parses the LLM response for DECISION, REASONING, NEXT_STEPS
-func (la *LeadAgent) parseDecision(response string) (decision, reasoning, nextSteps string) {
-	lines := strings.Split(response, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "DECISION:") {
-			decision = strings.TrimSpace(strings.TrimPrefix(line, "DECISION:"))
-			decision = strings.ToUpper(decision)
-			// Extract just the decision word
-			if strings.Contains(decision, "PROCEED") {
-				decision = "PROCEED"
-			} else if strings.Contains(decision, "REFINE") {
-				decision = "REFINE"
-			} else if strings.Contains(decision, "BLOCK") {
-				decision = "BLOCK"
-			}
-		}
-
-		if strings.HasPrefix(line, "REASONING:") {
-			reasoning = strings.TrimSpace(strings.TrimPrefix(line, "REASONING:"))
-		}
-
-		if strings.HasPrefix(line, "NEXT_STEPS:") {
-			nextSteps = strings.TrimSpace(strings.TrimPrefix(line, "NEXT_STEPS:"))
-		}
-	}
+// leadDecision is the structured response to a phase gate prompt
+type leadDecision struct {
+	Decision  string `json:"decision" enum:"PROCEED,REFINE,BLOCK"`
+	Reasoning string `json:"reasoning"`
+	NextSteps string `json:"next_steps"`
+}
 
-	// Default values if parsing failed
-	if decision == "" {
-		decision = "REFINE"
-		reasoning = "Unable to parse decision from response"
-		nextSteps = "Manual review required"
+// decide asks the model for a phase gate decision as validated JSON
+func (la *LeadAgent) decide(ctx context.Context, prompt string, onToken llm.TokenHandler) (*leadDecision, error) {
+	var decision leadDecision
+	ctx = llm.WithOptions(ctx, la.options)
+	err := llm.GenerateStructured(ctx, la.llmClient, la.model, prompt, &decision, llm.StructuredOptions{OnToken: onToken})
+	if err != nil {
+		return nil, fmt.Errorf("lead agent decision failed: %w", err)
 	}
-
-	return decision, reasoning, nextSteps
+	return &decision, nil
 }
 
 // getRecommendedAction returns a human-readable recommended action based on decision
@@ -581,7 +559,7 @@ func (la *LeadAgent) getRecommendedAction(decision string) string {
 }
 
 // GenerateProjectSummary generates a final project summary
-func (la *LeadAgent) GenerateProjectSummary(project *Project) (string, error) {
+func (la *LeadAgent) GenerateProjectSummary(ctx context.Context, project *Project) (string, error) {
 	prompt := fmt.Sprintf(`Generate a comprehensive project completion summary for:
 
 Project: %s
@@ -603,7 +581,7 @@ Format as markdown.`,
 		len(project.Phases),
 	)
 
-	summary, err := la.llmClient.Generate(la.model, prompt)
+	summary, err := llm.Generate(llm.WithOptions(ctx, la.options), la.llmClient, la.model, prompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate summary: %w", err)
 	}
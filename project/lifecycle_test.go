@@ -0,0 +1,198 @@
+package project
+
+import (
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+var recordLifecycle = flag.Bool("record", false, "re-record testdata/lifecycle.cassette.json against the Ollama at OLLAMA_BASE_URL")
+
+// TestProjectLifecycleReplay drives a project from Discovery to Complete with LLM
+// responses replayed from a cassette, so the full pipeline runs without Ollama.
+// Run with -record to refresh the cassette after changing a prompt.
+func TestProjectLifecycleReplay(t *testing.T) {
+	cassettePath, err := filepath.Abs(filepath.Join("testdata", "lifecycle.cassette.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode := "replay"
+	if *recordLifecycle {
+		mode = "record"
+	}
+
+	// Generated code and artifacts are written relative to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
+	if ollamaURL == "" {
+		ollamaURL = "http://localhost:11434"
+	}
+
+	cfg := &config.Config{
+		OllamaURL:    ollamaURL,
+		Models:       map[string]string{"code": "deepseek-coder:6.7b-instruct"},
+		ArtifactsDir: "artifacts",
+		Timeout:      600,
+		Cassette:     config.CassetteConfig{Mode: mode, Path: cassettePath},
+	}
+
+	supervisorCfg := supervisor.DefaultSupervisorConfig()
+	supervisorCfg.Agents.Requirements.Enabled = true
+	supervisorCfg.Agents.QA.Enabled = true
+	supervisorCfg.Agents.Testing.Enabled = true
+	supervisorCfg.Agents.Documentation.Enabled = true
+
+	baseMgr, err := task.NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisedMgr := supervisor.NewSupervisedTaskManager(baseMgr, cfg, supervisorCfg)
+
+	orchestrator, err := NewProjectOrchestrator(
+		supervisedMgr,
+		"projects",
+		cfg.ArtifactsDir,
+		baseMgr.GetClient(),
+		supervisedMgr.GetRequirementsAgent(),
+		supervisedMgr.GetTechStackAgent(),
+		supervisedMgr.GetScopeAgent(),
+		supervisedMgr.GetQAAgent(),
+		supervisedMgr.GetTestingAgent(),
+		supervisedMgr.GetDocsAgent(),
+		supervisedMgr.GetComplexityScorer(),
+	)
+	if err != nil {
+		t.Fatalf("NewProjectOrchestrator() error = %v", err)
+	}
+
+	// Streamed output reaches the hub tagged with its project and phase
+	hub := &recordingHub{}
+	orchestrator.SetWebSocketHub(hub)
+
+	project, err := orchestrator.CreateProject("Pomodoro Timer",
+		"A single-page pomodoro timer in plain HTML, CSS and JavaScript with start, pause and reset buttons and a 25/5 minute work/break cycle.")
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	ctx := context.Background()
+	phases := []Phase{
+		PhaseDiscovery, PhaseValidation, PhasePlanning, PhaseCodeGen,
+		PhaseReview, PhaseQA, PhaseDocs, PhaseComplete,
+	}
+
+	for _, phase := range phases {
+		result, err := orchestrator.ExecuteProjectPhase(ctx, project.ID, phase)
+		if err != nil {
+			t.Fatalf("ExecuteProjectPhase(%s) error = %v", phase, err)
+		}
+		if result.Decision != "PROCEED" {
+			t.Fatalf("%s phase decision = %s (%s), want PROCEED", phase, result.Decision, result.Reasoning)
+		}
+
+		if phase == PhaseComplete {
+			break
+		}
+
+		// Planning moves the project to waiting_approval; approving it starts code generation
+		if err := orchestrator.ApprovePhase(project.ID); err != nil {
+			t.Fatalf("ApprovePhase() after %s error = %v", phase, err)
+		}
+	}
+
+	project, err = orchestrator.GetProject(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if project.Status != ProjectStatusComplete || project.CurrentPhase != PhaseComplete {
+		t.Errorf("project status = %s, phase = %s, want %s / %s",
+			project.Status, project.CurrentPhase, ProjectStatusComplete, PhaseComplete)
+	}
+
+	if project.PlanDocument == nil || !project.PlanDocument.IsApproved {
+		t.Fatalf("plan document missing or not approved: %+v", project.PlanDocument)
+	}
+	if len(project.PlanDocument.FilesToCreate) == 0 {
+		t.Errorf("plan lists no files to create")
+	}
+
+	if project.ValidationResults == nil || !project.ValidationResults.BuildVerified {
+		t.Errorf("generated project did not pass build verification: %+v", project.ValidationResults)
+	}
+
+	// The generated project is found through the artifact index, not parsed out of artifact paths
+	artifacts := orchestrator.QueryArtifacts(task.ArtifactQuery{ProjectID: project.ID, TaskType: "code"})
+	if len(artifacts) == 0 || artifacts[0].GeneratedDir == "" || artifacts[0].GeneratedDir != generatedDir(project) || len(artifacts[0].Files) == 0 {
+		t.Errorf("artifact index lacks the generated project (%q): %+v", generatedDir(project), artifacts)
+	}
+
+	ledger, err := orchestrator.GetUsageLedger(project.ID)
+	if err != nil {
+		t.Fatalf("GetUsageLedger() error = %v", err)
+	}
+	for _, agent := range []string{"requirements", "techstack", "scope", "lead_agent", "coder"} {
+		if ledger.ByAgent[agent].Calls == 0 {
+			t.Errorf("usage ledger has no calls for agent %s: %+v", agent, ledger.ByAgent)
+		}
+	}
+
+	codegenTokens := 0
+	for _, event := range hub.events() {
+		if event.eventType != "token" {
+			continue
+		}
+		if event.projectID != project.ID {
+			t.Errorf("token event for project %q, want %s", event.projectID, project.ID)
+		}
+		if event.phase == string(PhaseCodeGen) && event.data != "" {
+			codegenTokens++
+		}
+	}
+	if codegenTokens == 0 {
+		t.Error("code generation published no token events")
+	}
+
+	if replayer, ok := baseMgr.GetClient().(*llm.Replayer); ok && replayer.Remaining() != 0 {
+		t.Errorf("%d recorded interactions were not replayed; re-record the cassette with -record", replayer.Remaining())
+	}
+}
+
+// hubEvent is one event published to a recordingHub
+type hubEvent struct {
+	eventType, projectID, phase, data string
+}
+
+// recordingHub keeps the events the orchestrator publishes
+type recordingHub struct {
+	mu        sync.Mutex
+	published []hubEvent
+}
+
+func (h *recordingHub) Publish(eventType, projectID, phase, data string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.published = append(h.published, hubEvent{eventType, projectID, phase, data})
+}
+
+func (h *recordingHub) events() []hubEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]hubEvent(nil), h.published...)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -144,6 +145,7 @@ func (pm *ProjectManager) LoadProject(id string) (*Project, error) {
         fmt.Printf("Warning: project %s failed schema validation: %v\n", id, err)
         // Continue loading but mark as potentially problematic
     }
+    migrateLegacyArtifacts(&project)
 
     pm.projects[project.ID] = &project
 
@@ -270,6 +272,7 @@ func (pm *ProjectManager) loadAllProjects() error {
             fmt.Printf("Warning: project %s failed schema validation: %v\n", project.ID, err)
             // Continue loading but keep project in cache for debugging
         }
+        migrateLegacyArtifacts(&project)
 
         pm.projects[project.ID] = &project
     }
@@ -277,6 +280,25 @@ func (pm *ProjectManager) loadAllProjects() error {
     return nil
 }
 
+// migrateLegacyArtifacts converts projects saved before the artifact store, whose artifact
+// paths carried a note such as "(project: projects/generated_123)" after the file name
+func migrateLegacyArtifacts(project *Project) {
+	for i, path := range project.ArtifactPaths {
+		project.ArtifactPaths[i], _, _ = strings.Cut(path, " (")
+	}
+	for i := range project.Tasks {
+		t := &project.Tasks[i]
+		path, note, found := strings.Cut(t.ArtifactPath, " (")
+		if !found {
+			continue
+		}
+		t.ArtifactPath = path
+		if dir, ok := strings.CutPrefix(note, "project: "); ok && t.ProjectDir == "" {
+			t.ProjectDir = strings.TrimSuffix(dir, ")")
+		}
+	}
+}
+
 // getProjectPath returns the file path for a project
 func (pm *ProjectManager) getProjectPath(id string) string {
 	return filepath.Join(pm.projectsDir, fmt.Sprintf("project_%s.json", id))
@@ -1,15 +1,18 @@
 package project
 
 import (
+	"ai-studio/orchestrator/config"
 	"ai-studio/orchestrator/git"
 	"ai-studio/orchestrator/llm"
 	"ai-studio/orchestrator/supervisor"
 	"ai-studio/orchestrator/task"
-	"ai-studio/orchestrator/validation"
+	"context"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,6 +26,21 @@ type ProjectOrchestrator struct {
 	completionValidator *CompletionValidator
 	worktreeMgr         *git.WorktreeManager // Git worktree isolation
 	wsHub               interface{}          // WebSocket hub for real-time updates (imported as interface to avoid circular import)
+
+	// Cancel functions for phases currently executing (project ID -> cancel)
+	running    map[string]context.CancelFunc
+	runningMux sync.Mutex
+}
+
+// DefaultLeadAgentModel is used when project_orchestrator.lead_agent_model is not set
+const DefaultLeadAgentModel = "llama3:8b"
+
+// LeadAgentModel returns the model the Lead Agent and its plan generator use
+func LeadAgentModel(cfg *config.Config) string {
+	if cfg != nil && cfg.ProjectOrchestrator.LeadAgentModel != "" {
+		return cfg.ProjectOrchestrator.LeadAgentModel
+	}
+	return DefaultLeadAgentModel
 }
 
 // NewProjectOrchestrator creates a new project orchestrator
@@ -30,7 +48,7 @@ func NewProjectOrchestrator(
 	supervisedMgr *supervisor.SupervisedTaskManager,
 	projectsDir string,
 	artifactsDir string,
-	llmClient *llm.Client,
+	llmClient llm.Provider,
 	requirementsAgent *supervisor.RequirementsAgent,
 	techStackAgent *supervisor.TechStackAgent,
 	scopeAgent *supervisor.ScopeAgent,
@@ -49,7 +67,7 @@ func NewProjectOrchestrator(
 	// Create LeadAgent
 	leadAgent := NewLeadAgent(
 		llmClient,
-		"llama3:8b", // Ollama model for Lead Agent
+		LeadAgentModel(supervisedMgr.GetConfig()),
 		requirementsAgent,
 		techStackAgent,
 		scopeAgent,
@@ -59,6 +77,13 @@ func NewProjectOrchestrator(
 		complexityScorer,
 	)
 
+	// Generation options for the Lead Agent's decisions and plans
+	if cfg := supervisedMgr.GetConfig(); cfg != nil {
+		leadAgent.options = cfg.AgentGenerationOptions("lead_agent")
+		leadAgent.planGenerator.options = cfg.AgentGenerationOptions("planner")
+		leadAgent.planGenerator.thinkingProfiles = task.ThinkingProfiles(cfg)
+	}
+
 	// Create CompletionValidator
 	completionValidator := NewCompletionValidator(artifactsDir)
 
@@ -78,6 +103,7 @@ func NewProjectOrchestrator(
 		leadAgent:           leadAgent,
 		completionValidator: completionValidator,
 		worktreeMgr:         worktreeMgr,
+		running:             make(map[string]context.CancelFunc),
 	}, nil
 }
 
@@ -106,12 +132,37 @@ func (po *ProjectOrchestrator) ListProjects() []*Project {
 }
 
 // ExecuteProjectPhase executes a specific phase for a project
-func (po *ProjectOrchestrator) ExecuteProjectPhase(projectID string, phase Phase) (*PhaseResult, error) {
+// Only one phase per project may run at a time; CancelPhase stops it and marks it cancelled
+func (po *ProjectOrchestrator) ExecuteProjectPhase(ctx context.Context, projectID string, phase Phase) (*PhaseResult, error) {
 	project, err := po.projectMgr.GetProject(projectID)
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Count LLM cache hits/misses across every model call in this phase
+	cacheStats := &llm.CacheStats{}
+	ctx = llm.WithCacheStats(ctx, cacheStats)
+
+	// Account token usage and latency of every model call in this phase
+	meter := &llm.UsageMeter{}
+	ctx = llm.WithUsageMeter(ctx, meter)
+
+	// Queue this phase's model calls behind interactive work, fairly against other projects
+	ctx = llm.WithProject(llm.WithPriority(ctx, llm.PriorityPhase), projectID)
+
+	// Retried model calls tell clients to drop the tokens streamed by the failed attempt
+	ctx = llm.WithTokenReset(ctx, func(model string) {
+		po.broadcastEvent("token_reset", project.ID, string(phase), model)
+	})
+
+	if err := po.registerRun(projectID, cancel); err != nil {
+		return nil, err
+	}
+	defer po.unregisterRun(projectID)
+
 	log.Printf("ProjectOrchestrator: Executing %s phase for project %s", phase, project.Name)
 
 	// Broadcast phase start
@@ -128,28 +179,25 @@ func (po *ProjectOrchestrator) ExecuteProjectPhase(projectID string, phase Phase
 	switch phase {
 	case PhaseDiscovery, PhaseValidation, PhasePlanning, PhaseReview, PhaseQA, PhaseDocs:
 		// Lead Agent handles these phases
-		phaseResult, err = po.leadAgent.ExecutePhase(project, phase)
+		phaseResult, err = po.leadAgent.ExecutePhase(ctx, project, phase, po.tokenRelay(project, phase))
 		if err != nil {
-			// Revert phase status on error
-			po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusPending)
+			po.abortPhase(ctx, project, phase, meter)
 			return nil, fmt.Errorf("lead agent execution failed: %w", err)
 		}
 
 	case PhaseCodeGen:
 		// Delegate to SupervisedTaskManager for code generation
-		phaseResult, err = po.executeCodeGenPhase(project)
+		phaseResult, err = po.executeCodeGenPhase(ctx, project)
 		if err != nil {
-			// Revert phase status on error
-			po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusPending)
+			po.abortPhase(ctx, project, phase, meter)
 			return nil, fmt.Errorf("code generation failed: %w", err)
 		}
 
 	case PhaseComplete:
 		// Finalize project
-		phaseResult, err = po.executeCompletePhase(project)
+		phaseResult, err = po.executeCompletePhase(ctx, project)
 		if err != nil {
-			// Revert phase status on error
-			po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusPending)
+			po.abortPhase(ctx, project, phase, meter)
 			return nil, fmt.Errorf("project completion failed: %w", err)
 		}
 
@@ -157,11 +205,16 @@ func (po *ProjectOrchestrator) ExecuteProjectPhase(projectID string, phase Phase
 		return nil, fmt.Errorf("unsupported phase: %s", phase)
 	}
 
+	phaseResult.CacheHits = int(cacheStats.Hits())
+	phaseResult.CacheMisses = int(cacheStats.Misses())
+	phaseResult.Usage = meter.Total()
+	phaseResult.ModelUsage = meter.ByModel()
+
 	// Store phase result in project
 	err = po.storePhaseResult(project, phase, phaseResult)
 	if err != nil {
-		// Revert phase status on storage error
-		po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusPending)
+		// storePhaseResult has already added this run's usage
+		po.abortPhase(ctx, project, phase, nil)
 		return nil, fmt.Errorf("failed to store phase result: %w", err)
 	}
 
@@ -173,8 +226,71 @@ func (po *ProjectOrchestrator) ExecuteProjectPhase(projectID string, phase Phase
 	return phaseResult, nil
 }
 
+// abortPhase resets a phase that did not finish
+// Cancelled phases are marked cancelled; failed ones revert to pending so they can be retried.
+// LLM usage of the unfinished run is still added to the phase's ledger.
+func (po *ProjectOrchestrator) abortPhase(ctx context.Context, project *Project, phase Phase, meter *llm.UsageMeter) {
+	if phaseExec := findPhaseExecution(project, phase); phaseExec != nil && meter != nil {
+		addPhaseUsage(phaseExec, meter.Total(), meter.ByModel())
+	}
+
+	if ctx.Err() != nil {
+		log.Printf("ProjectOrchestrator: %s phase cancelled for project %s", phase, project.Name)
+		po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusCancelled)
+		po.broadcastPhaseTransition(project, phase, "cancelled")
+		return
+	}
+
+	po.projectMgr.UpdateProjectPhase(project, phase, PhaseStatusPending)
+}
+
+// CancelPhase cancels the phase currently executing for a project
+// LLM requests are aborted and any build, runtime or test processes are killed
+func (po *ProjectOrchestrator) CancelPhase(projectID string) error {
+	po.runningMux.Lock()
+	cancel, ok := po.running[projectID]
+	po.runningMux.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no phase running for project %s", projectID)
+	}
+
+	log.Printf("ProjectOrchestrator: Cancelling running phase for project %s", projectID)
+	cancel()
+	return nil
+}
+
+// IsPhaseRunning reports whether a phase is currently executing for a project
+func (po *ProjectOrchestrator) IsPhaseRunning(projectID string) bool {
+	po.runningMux.Lock()
+	defer po.runningMux.Unlock()
+
+	_, ok := po.running[projectID]
+	return ok
+}
+
+// registerRun records the cancel function of a phase about to run
+func (po *ProjectOrchestrator) registerRun(projectID string, cancel context.CancelFunc) error {
+	po.runningMux.Lock()
+	defer po.runningMux.Unlock()
+
+	if _, ok := po.running[projectID]; ok {
+		return fmt.Errorf("a phase is already running for project %s", projectID)
+	}
+	po.running[projectID] = cancel
+	return nil
+}
+
+// unregisterRun forgets the cancel function once a phase has finished
+func (po *ProjectOrchestrator) unregisterRun(projectID string) {
+	po.runningMux.Lock()
+	defer po.runningMux.Unlock()
+
+	delete(po.running, projectID)
+}
+
 // executeCodeGenPhase executes the code generation phase
-func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResult, error) {
+func (po *ProjectOrchestrator) executeCodeGenPhase(ctx context.Context, project *Project) (*PhaseResult, error) {
 	// Create worktree for isolated development (if worktree manager is available)
 	var worktreePath string
 	if po.worktreeMgr != nil {
@@ -193,14 +309,38 @@ func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResu
 	for _, phase := range project.Phases {
 		if phase.Phase == PhasePlanning && phase.Status == PhaseStatusComplete {
 			fullInput += "### Planning Phase Output:\n"
-			for agent, output := range phase.AgentOutputs {
-				fullInput += fmt.Sprintf("#### %s Agent:\n%s\n\n", agent, output)
+			for _, agent := range sortedKeys(phase.AgentOutputs) {
+				fullInput += fmt.Sprintf("#### %s Agent:\n%s\n\n", agent, phase.AgentOutputs[agent])
 			}
 		}
 	}
 
+	cfg := po.supervisedMgr.GetConfig().ProjectOrchestrator
+	existingDir := po.existingProjectDir(project)
+	plan := project.PlanDocument
+	switch {
+	case existingDir != "" && !cfg.FullRegeneration:
+		// Back from Review/QA: patch the files of the previous run instead of regenerating them
+		fullInput += reviewFeedback(project)
+		ctx = task.WithProjectDir(ctx, existingDir)
+		log.Printf("ProjectOrchestrator: Incremental code generation against %s", existingDir)
+	case cfg.CodeGenStrategy == config.CodeGenPerFile:
+		if plan == nil || !plan.IsApproved || len(plan.FilesToCreate) == 0 {
+			log.Printf("Warning: per-file code generation needs an approved plan with files to create; generating in one call")
+			break
+		}
+		// One call per file of the approved plan, reporting each file as it starts
+		ctx = task.WithFileManifest(ctx, plan.FilesToCreate)
+		parent := ctx
+		ctx = task.WithProgress(ctx, func(stage, detail string) {
+			task.ReportProgress(parent, stage, detail)
+			po.broadcastEvent("codegen_progress", project.ID, string(PhaseCodeGen), fmt.Sprintf("%s: %s", stage, detail))
+		})
+		log.Printf("ProjectOrchestrator: Per-file code generation of %d planned files", len(plan.FilesToCreate))
+	}
+
 	// Execute code generation via SupervisedTaskManager
-	result, err := po.supervisedMgr.ExecuteTask("code", fullInput)
+	result, err := po.supervisedMgr.ExecuteTaskStream(ctx, "code", fullInput, po.tokenRelay(project, PhaseCodeGen))
 	if err != nil {
 		return nil, fmt.Errorf("supervised task execution failed: %w", err)
 	}
@@ -218,9 +358,17 @@ func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResu
 		TaskType:        "code",
 		Input:           project.Description,
 		Output:          supervisedResult.Result.Output,
+		ArtifactID:      supervisedResult.Result.ArtifactID,
 		ArtifactPath:    supervisedResult.Result.ArtifactPath,
+		ProjectDir:      supervisedResult.Result.ProjectDir,
 		ComplexityScore: supervisedResult.ComplexityScore,
 		ExecutionRoute:  supervisedResult.ExecutionRoute,
+		CacheHits:       int(supervisedResult.AgentDurations["cache_hits"]),
+		CacheMisses:     int(supervisedResult.AgentDurations["cache_misses"]),
+		Usage:           supervisedResult.TotalUsage,
+		ThinkingPath:    supervisedResult.Result.ThinkingPath,
+		Patch:           supervisedResult.Result.Patch,
+		Coverage:        supervisedResult.Result.Coverage,
 		CreatedAt:       time.Now(),
 	}
 
@@ -248,109 +396,46 @@ func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResu
 	// Run Triple Guarantee System: Build + Runtime + Test verification
 	decision := "PROCEED"
 	reasoning := fmt.Sprintf("Code generated via %s (complexity: %d)", supervisedResult.ExecutionRoute, supervisedResult.ComplexityScore)
-
-	// Extract project directory and verify if it exists
-	projectDir, err := po.extractProjectDir(supervisedResult.Result.ArtifactPath)
-	if err != nil {
-		log.Printf("[WARN] Failed to extract project dir: %v", err)
-		// Continue with empty projectDir - validation will be skipped
-		projectDir = ""
+	if coverage := supervisedResult.Result.Coverage; coverage != nil {
+		reasoning += fmt.Sprintf(" | Files: %d/%d planned", len(coverage.Generated), len(coverage.Manifest))
+		if !coverage.Complete() {
+			decision = "REFINE"
+			reasoning += fmt.Sprintf(" (missing: %s)", strings.Join(coverage.Missing, ", "))
+		}
+	}
+	nextSteps := "Proceed to Review phase"
+	if patch := supervisedResult.Result.Patch; patch != nil {
+		decision, reasoning, nextSteps = assessPatch(patch, decision, reasoning, nextSteps)
 	}
 
-	var verifyResult *supervisor.VerificationResult
-	var runtimeResult *validation.RuntimeResult
-	var testResult *validation.TestResult
-
+	// Verify the generated project; without one (no files extracted) validation is skipped
+	projectDir := supervisedResult.Result.ProjectDir
 	if projectDir != "" {
-		// Phase 1: Build Verification
-		verifyAgent := supervisor.NewVerificationAgent()
-		verifyResult, err = verifyAgent.VerifyProject(projectDir)
-
-		if err == nil && verifyResult != nil {
-			// Check for critical failures
-			if !verifyResult.SyntaxValid || !verifyResult.EntryPointValid {
-				decision = "BLOCK"
-				reasoning = fmt.Sprintf("Build verification failed: %v", verifyResult.Errors)
-			} else if !verifyResult.DependenciesOK {
-				decision = "REFINE"
-				reasoning = fmt.Sprintf("Code generated but dependencies missing: %v", verifyResult.Errors)
+		check := runVerification(ctx, projectDir)
+
+		// Feed failures back to the code model until verification passes or the repair budget runs out
+		check, repairs := po.repairProject(ctx, project, taskExec.TaskID, projectDir, check)
+
+		decision, reasoning = check.assess(decision, reasoning)
+		if len(repairs) > 0 {
+			last := repairs[len(repairs)-1]
+			if last.Passed {
+				reasoning += fmt.Sprintf(" | Repaired in %d iteration(s)", len(repairs))
 			} else {
-				reasoning += fmt.Sprintf(" | Build: ✓")
-
-				// Phase 2: Runtime Verification (only if build passed)
-				runtimeValidator := validation.NewRuntimeValidator()
-				var rtErr error
-				runtimeResult, rtErr = runtimeValidator.ValidateRuntime(projectDir, verifyResult.ProjectType)
-
-				if rtErr == nil && runtimeResult != nil {
-					if runtimeResult.ApplicationStarts {
-						reasoning += " | Runtime: ✓"
-						if runtimeResult.HealthCheckPassed {
-							reasoning += " (health check passed)"
-						}
-					} else {
-						reasoning += " | Runtime: ⚠️ (startup failed)"
-						log.Printf("ProjectOrchestrator: Runtime validation warnings: %v", runtimeResult.Errors)
-					}
-
-					// Phase 3: Test Execution (only if build passed)
-					testExecutor := validation.NewTestExecutor()
-					var testErr error
-					testResult, testErr = testExecutor.ExecuteTests(projectDir, verifyResult.ProjectType)
-
-					if testErr == nil && testResult != nil && testResult.TestsExecuted {
-						if testResult.TestsFailed == 0 && testResult.TestsPassed > 0 {
-							reasoning += fmt.Sprintf(" | Tests: ✓ (%d/%d passed)", testResult.TestsPassed, testResult.TotalTests)
-						} else if testResult.TestsFailed > 0 {
-							reasoning += fmt.Sprintf(" | Tests: ⚠️ (%d/%d passed)", testResult.TestsPassed, testResult.TotalTests)
-						}
-						log.Printf("ProjectOrchestrator: Tests executed - Passed: %d, Failed: %d, Total: %d",
-							testResult.TestsPassed, testResult.TestsFailed, testResult.TotalTests)
-					}
-				} else if rtErr != nil {
-					log.Printf("ProjectOrchestrator: Runtime validation failed: %v", rtErr)
-				}
+				reasoning += fmt.Sprintf(" | Repair: %d iteration(s), still failing", len(repairs))
 			}
 
-			log.Printf("ProjectOrchestrator: Build verification - Syntax: %v, Dependencies: %v, Entry Point: %v",
-				verifyResult.SyntaxValid, verifyResult.DependenciesOK, verifyResult.EntryPointValid)
-		} else {
-			log.Printf("ProjectOrchestrator: Build verification skipped or failed: %v", err)
+			// Commit the repairs to the worktree as well
+			if worktreePath != "" && po.worktreeMgr != nil {
+				commitMsg := fmt.Sprintf("AI Factory: Repaired code for project '%s'", project.Name)
+				if err := po.worktreeMgr.CommitChanges(worktreePath, commitMsg); err != nil {
+					log.Printf("Warning: Failed to commit repairs to worktree: %v", err)
+				}
+			}
 		}
 
 		// Store validation results in project for persistence
-		project.ValidationResults = &ValidationResults{
-			LastValidated: time.Now(),
-		}
-
-		// Store build verification results
-		if verifyResult != nil {
-			project.ValidationResults.BuildVerified = verifyResult.SyntaxValid && verifyResult.DependenciesOK && verifyResult.EntryPointValid
-			project.ValidationResults.SyntaxValid = verifyResult.SyntaxValid
-			project.ValidationResults.DependenciesOK = verifyResult.DependenciesOK
-			project.ValidationResults.EntryPointValid = verifyResult.EntryPointValid
-			project.ValidationResults.BuildErrors = verifyResult.Errors
-		}
-
-		// Store runtime verification results
-		if runtimeResult != nil {
-			project.ValidationResults.RuntimeVerified = runtimeResult.ApplicationStarts
-			project.ValidationResults.ApplicationStarts = runtimeResult.ApplicationStarts
-			project.ValidationResults.HealthCheckPassed = runtimeResult.HealthCheckPassed
-			project.ValidationResults.RuntimeErrors = runtimeResult.Errors
-			project.ValidationResults.RuntimeWarnings = runtimeResult.Warnings
-		}
-
-		// Store test execution results
-		if testResult != nil {
-			project.ValidationResults.TestsExecuted = testResult.TestsExecuted
-			project.ValidationResults.TestsPassed = testResult.TestsPassed
-			project.ValidationResults.TestsFailed = testResult.TestsFailed
-			project.ValidationResults.TestsSkipped = testResult.TestsSkipped
-			project.ValidationResults.TotalTests = testResult.TotalTests
-			project.ValidationResults.TestFramework = testResult.TestFramework
-			project.ValidationResults.TestErrors = testResult.Errors
-		}
+		check.store(project)
 
 		// Persist to disk
 		if err := po.projectMgr.SaveProject(project); err != nil {
@@ -358,11 +443,16 @@ func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResu
 		}
 	}
 
+	// Validation results above are partial if the phase was cancelled
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
 	phaseResult := &PhaseResult{
 		Phase:             PhaseCodeGen,
 		Decision:          decision,
 		Reasoning:         reasoning,
-		NextSteps:         "Proceed to Review phase",
+		NextSteps:         nextSteps,
 		AgentOutputs:      make(map[string]*supervisor.AgentOutput),
 		RequiresApproval:  decision == "BLOCK", // Require approval if verification failed
 		RecommendedAction: "Automated transition to Review phase",
@@ -371,50 +461,114 @@ func (po *ProjectOrchestrator) executeCodeGenPhase(project *Project) (*PhaseResu
 	return phaseResult, nil
 }
 
-// extractProjectDir extracts project directory from artifact path
-// Handles formats: "(project: projects/generated_123)" or "projects/generated_123"
-func (po *ProjectOrchestrator) extractProjectDir(artifactPath string) (string, error) {
-	// Try format: "(project: PATH)"
-	if strings.Contains(artifactPath, "(project:") {
-		start := strings.Index(artifactPath, "projects/")
-		if start == -1 {
-			return "", fmt.Errorf("no 'projects/' found in artifact path: %s", artifactPath)
+// assessPatch checks that an incremental CodeGen run actually applied the review feedback.
+// A patch that failed, left hunks unapplied or changed nothing sends the phase back to REFINE,
+// naming the files still to fix.
+func assessPatch(patch *task.PatchReport, decision, reasoning, nextSteps string) (string, string, string) {
+	reasoning += fmt.Sprintf(" | Patch: %d file(s) changed", patch.Changed())
+	if len(patch.FailedHunks) > 0 {
+		reasoning += fmt.Sprintf(", %d hunk(s) failed", len(patch.FailedHunks))
+	}
+
+	switch {
+	case patch.Error != "":
+		reasoning += fmt.Sprintf(" (failed: %s)", patch.Error)
+		nextSteps = "Re-run CodeGen: the patch could not be written and the project is unchanged"
+	case len(patch.FailedHunks) > 0:
+		var files []string
+		seen := make(map[string]bool)
+		for _, h := range patch.FailedHunks {
+			if !seen[h.Path] {
+				seen[h.Path] = true
+				files = append(files, h.Path)
+			}
 		}
+		nextSteps = fmt.Sprintf("Re-run CodeGen: changes to %s could not be applied", strings.Join(files, ", "))
+	case patch.Changed() == 0:
+		nextSteps = "Re-run CodeGen: the response changed no files, so the review feedback was not applied"
+	default:
+		return decision, reasoning, nextSteps
+	}
 
-		end := strings.Index(artifactPath[start:], ")")
-		if end == -1 {
-			return "", fmt.Errorf("no closing ')' found in artifact path: %s", artifactPath)
+	if decision != "BLOCK" {
+		decision = "REFINE"
+	}
+	return decision, reasoning, nextSteps
+}
+
+// existingProjectDir returns the directory of the project's latest CodeGen output, if it still exists
+func (po *ProjectOrchestrator) existingProjectDir(project *Project) string {
+	dir := generatedDir(project)
+	if dir == "" {
+		return ""
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// generatedDir returns the project directory of the latest CodeGen task that produced one
+func generatedDir(project *Project) string {
+	for i := len(project.Tasks) - 1; i >= 0; i-- {
+		if t := project.Tasks[i]; t.Phase == PhaseCodeGen && t.ProjectDir != "" {
+			return t.ProjectDir
 		}
+	}
+	return ""
+}
 
-		projectDir := artifactPath[start : start+end]
-		return projectDir, nil
+// ProjectDir returns the directory holding the project's latest generated code
+func (po *ProjectOrchestrator) ProjectDir(projectID string) (string, error) {
+	project, err := po.projectMgr.GetProject(projectID)
+	if err != nil {
+		return "", err
+	}
+	dir := po.existingProjectDir(project)
+	if dir == "" {
+		return "", fmt.Errorf("project %s has no generated code", projectID)
 	}
+	return dir, nil
+}
 
-	// Try direct path format: "projects/generated_123"
-	if strings.HasPrefix(artifactPath, "projects/") {
-		// Extract just the project directory part (before any additional path components)
-		parts := strings.Split(artifactPath, "/")
-		if len(parts) >= 2 {
-			projectDir := filepath.Join(parts[0], parts[1])
-			return projectDir, nil
+// reviewFeedback collects the latest output of each completed phase after CodeGen (Review, QA, ...),
+// so an incremental CodeGen run knows what to fix
+func reviewFeedback(project *Project) string {
+	latest := make(map[Phase]PhaseExecution)
+	var order []Phase
+	for _, p := range project.Phases {
+		if !isAfter(p.Phase, PhaseCodeGen) || p.Status != PhaseStatusComplete || len(p.AgentOutputs) == 0 {
+			continue
+		}
+		if _, seen := latest[p.Phase]; !seen {
+			order = append(order, p.Phase)
 		}
+		latest[p.Phase] = p
 	}
 
-	return "", fmt.Errorf("unrecognized artifact path format: %s", artifactPath)
+	var feedback string
+	for _, phase := range order {
+		p := latest[phase]
+		feedback += fmt.Sprintf("### %s Phase Feedback:\n", phase)
+		for _, agent := range sortedKeys(p.AgentOutputs) {
+			feedback += fmt.Sprintf("#### %s Agent:\n%s\n\n", agent, p.AgentOutputs[agent])
+		}
+	}
+	return feedback
 }
 
 // executeCompletePhase finalizes the project
-func (po *ProjectOrchestrator) executeCompletePhase(project *Project) (*PhaseResult, error) {
+func (po *ProjectOrchestrator) executeCompletePhase(ctx context.Context, project *Project) (*PhaseResult, error) {
 	log.Printf("ProjectOrchestrator: Finalizing project %s", project.Name)
 
 	// Validate hand-off ready criteria
-	metrics, err := po.completionValidator.ValidateHandoffReady(project)
+	metrics, err := po.completionValidator.ValidateHandoffReady(ctx, project)
 	if err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	// Generate project summary
-	summary, err := po.leadAgent.GenerateProjectSummary(project)
+	summary, err := po.leadAgent.GenerateProjectSummary(ctx, project)
 	if err != nil {
 		log.Printf("Warning: Failed to generate summary: %v", err)
 		summary = "Summary generation unavailable"
@@ -424,17 +578,7 @@ func (po *ProjectOrchestrator) executeCompletePhase(project *Project) (*PhaseRes
 	qualityReport := GenerateQualityReport(project.Name, *metrics)
 
 	// Save quality report to project directory
-	projectDir := ""
-	if len(project.ArtifactPaths) > 0 {
-		var err error
-		projectDir, err = po.extractProjectDir(project.ArtifactPaths[0])
-		if err != nil {
-			log.Printf("[WARN] Failed to extract project dir for quality report: %v", err)
-			projectDir = ""
-		}
-	}
-
-	if projectDir != "" {
+	if projectDir := po.existingProjectDir(project); projectDir != "" {
 		reportPath := fmt.Sprintf("%s/QUALITY_REPORT.md", projectDir)
 		if err := qualityReport.SaveToFile(reportPath); err != nil {
 			log.Printf("Warning: Failed to save quality report: %v", err)
@@ -667,14 +811,24 @@ func isAfter(phaseA Phase, phaseB Phase) bool {
 	return idxA > idxB && idxA >= 0 && idxB >= 0
 }
 
+// GetUsageLedger rolls up the LLM usage recorded on a project
+func (po *ProjectOrchestrator) GetUsageLedger(projectID string) (*UsageLedger, error) {
+	project, err := po.projectMgr.GetProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildUsageLedger(project), nil
+}
+
 // GetCompletionMetrics gets hand-off ready metrics for a project
-func (po *ProjectOrchestrator) GetCompletionMetrics(projectID string) (*CompletionMetrics, error) {
+func (po *ProjectOrchestrator) GetCompletionMetrics(ctx context.Context, projectID string) (*CompletionMetrics, error) {
 	project, err := po.projectMgr.GetProject(projectID)
 	if err != nil {
 		return nil, err
 	}
 
-	return po.completionValidator.ValidateHandoffReady(project)
+	return po.completionValidator.ValidateHandoffReady(ctx, project)
 }
 
 // storePhaseResult stores phase result in project
@@ -684,6 +838,10 @@ func (po *ProjectOrchestrator) storePhaseResult(project *Project, phase Phase, r
 		if project.Phases[i].Phase == phase && project.Phases[i].Status == PhaseStatusInProgress {
 			project.Phases[i].LeadAgentDecision = result.Decision
 			project.Phases[i].LeadAgentInput = project.Description
+			project.Phases[i].CacheHits = result.CacheHits
+			project.Phases[i].CacheMisses = result.CacheMisses
+			addPhaseUsage(&project.Phases[i], result.Usage, result.ModelUsage)
+			addAgentUsage(&project.Phases[i], phaseAgentUsage(phase, result))
 
 			// Mark phase as complete (execution finished successfully)
 			now := time.Now()
@@ -727,9 +885,9 @@ func (po *ProjectOrchestrator) getNextPhase(current Phase) (Phase, error) {
 // Implement task.Manager interface for backward compatibility
 
 // ExecuteTask executes a task (backward compatibility)
-func (po *ProjectOrchestrator) ExecuteTask(taskType, input string) (interface{}, error) {
+func (po *ProjectOrchestrator) ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error) {
 	// Delegate to SupervisedTaskManager for backward compatibility
-	return po.supervisedMgr.ExecuteTask(taskType, input)
+	return po.supervisedMgr.ExecuteTask(ctx, taskType, input)
 }
 
 // Ping checks system health
@@ -737,13 +895,18 @@ func (po *ProjectOrchestrator) Ping() error {
 	return po.supervisedMgr.Ping()
 }
 
-// GetHistory gets task history
-func (po *ProjectOrchestrator) GetHistory(taskType string) []task.Result {
-	return po.supervisedMgr.GetHistory(taskType)
+// QueryHistory searches task history
+func (po *ProjectOrchestrator) QueryHistory(q task.HistoryQuery) (task.HistoryPage, error) {
+	return po.supervisedMgr.QueryHistory(q)
+}
+
+// QueryArtifacts searches the artifact index
+func (po *ProjectOrchestrator) QueryArtifacts(q task.ArtifactQuery) []task.Artifact {
+	return po.supervisedMgr.QueryArtifacts(q)
 }
 
 // GetClient gets LLM client
-func (po *ProjectOrchestrator) GetClient() *llm.Client {
+func (po *ProjectOrchestrator) GetClient() llm.Provider {
 	return po.supervisedMgr.GetClient()
 }
 
@@ -769,19 +932,24 @@ func (po *ProjectOrchestrator) broadcastEvent(eventType, projectID, phase, data
 	}
 
 	// Type assert to the Hub interface
-	type EventBroadcaster interface {
-		Broadcast(event interface{})
+	type EventPublisher interface {
+		Publish(eventType, projectID, phase, data string)
 	}
 
-	if hub, ok := po.wsHub.(EventBroadcaster); ok {
-		event := map[string]interface{}{
-			"type":       eventType,
-			"project_id": projectID,
-			"phase":      phase,
-			"data":       data,
-			"timestamp":  time.Now(),
-		}
-		hub.Broadcast(event)
+	if hub, ok := po.wsHub.(EventPublisher); ok {
+		hub.Publish(eventType, projectID, phase, data)
+	}
+}
+
+// tokenRelay returns a handler that forwards streamed LLM output as "token" events
+// Returns nil (non-streaming) when no WebSocket hub is attached
+func (po *ProjectOrchestrator) tokenRelay(project *Project, phase Phase) llm.TokenHandler {
+	if po.wsHub == nil {
+		return nil
+	}
+
+	return func(chunk string) {
+		po.broadcastEvent("token", project.ID, string(phase), chunk)
 	}
 }
 
@@ -2,21 +2,26 @@ package project
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
 	"log"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 // PlanGenerator generates structured implementation plans
 type PlanGenerator struct {
-	llmClient *llm.Client
+	llmClient llm.Provider
 	model     string
+	options   map[string]interface{} // Generation options for plans; nil = model defaults
+
+	thinkingProfiles map[string]llm.ThinkingProfile // Configured thinking modes; nil = built-in defaults
 }
 
 // NewPlanGenerator creates a new plan generator
-func NewPlanGenerator(llmClient *llm.Client, model string) *PlanGenerator {
+func NewPlanGenerator(llmClient llm.Provider, model string) *PlanGenerator {
 	return &PlanGenerator{
 		llmClient: llmClient,
 		model:     model,
@@ -24,7 +29,8 @@ func NewPlanGenerator(llmClient *llm.Client, model string) *PlanGenerator {
 }
 
 // GeneratePlan creates a structured implementation plan for a project
-func (pg *PlanGenerator) GeneratePlan(project *Project) (*PlanDocument, error) {
+// The raw plan JSON is streamed to onToken when it is non-nil
+func (pg *PlanGenerator) GeneratePlan(ctx context.Context, project *Project, onToken llm.TokenHandler) (*PlanDocument, error) {
 	log.Printf("Plan Generator: Generating implementation plan for project %s", project.Name)
 
 	// Build planning prompt
@@ -33,19 +39,25 @@ func (pg *PlanGenerator) GeneratePlan(project *Project) (*PlanDocument, error) {
 	// Determine thinking mode (use project metadata if set, default to normal)
 	thinkingMode := string(project.Metadata.ThinkingMode)
 	if thinkingMode == "" {
-		thinkingMode = "normal"
+		thinkingMode = llm.ThinkingNormal
 	}
 
 	log.Printf("Plan Generator: Using %s thinking mode for plan generation", thinkingMode)
 
 	// Generate plan from LLM with appropriate thinking mode
-	response, err := pg.llmClient.GenerateWithThinking(pg.model, prompt, thinkingMode)
+	var response planResponse
+	ctx = llm.WithOptions(ctx, pg.options)
+	ctx = llm.WithThinkingProfiles(ctx, pg.thinkingProfiles)
+	model := llm.ThinkingModel(ctx, thinkingMode, pg.model)
+	err := llm.GenerateStructured(ctx, pg.llmClient, model, prompt, &response, llm.StructuredOptions{
+		ThinkingMode: thinkingMode,
+		OnToken:      onToken,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate plan: %w", err)
 	}
 
-	// Parse the response into structured plan
-	plan := pg.parsePlanResponse(response, project.ID)
+	plan := response.toPlanDocument(project.ID)
 
 	log.Printf("Plan Generator: Successfully generated plan with %d files to create, %d to modify",
 		len(plan.FilesToCreate), len(plan.FilesToModify))
@@ -60,8 +72,8 @@ func (pg *PlanGenerator) buildPlanningPrompt(project *Project) string {
 	for _, phaseExec := range project.Phases {
 		if phaseExec.Status == PhaseStatusComplete {
 			previousContext += fmt.Sprintf("\n### %s Phase Results:\n", phaseExec.Phase)
-			for agentName, output := range phaseExec.AgentOutputs {
-				previousContext += fmt.Sprintf("**%s:**\n%s\n\n", agentName, output)
+			for _, agentName := range sortedKeys(phaseExec.AgentOutputs) {
+				previousContext += fmt.Sprintf("**%s:**\n%s\n\n", agentName, phaseExec.AgentOutputs[agentName])
 			}
 		}
 	}
@@ -78,48 +90,19 @@ PREVIOUS ANALYSIS:
 TASK:
 Create a comprehensive, structured implementation plan for this project. Your plan should be clear, actionable, and ready for a developer to execute.
 
-OUTPUT FORMAT (use this EXACT structure):
-
-## Implementation Approach
-[Describe the overall strategy and architectural decisions in 2-3 paragraphs. Explain WHY you chose this approach.]
-
-## Technical Stack
-- Primary Language: [e.g., JavaScript, Python, Go]
-- Framework: [e.g., React 18, Django, Gin]
-- Build Tool: [e.g., Vite, Webpack, Go build]
-- Testing Framework: [e.g., Vitest, pytest, go test]
-- Additional Tools: [list any other key tools]
-
-## Files to Create
-[List each new file with a brief description of its purpose. Format: "- path/to/file.ext - Description"]
-Example:
-- src/App.jsx - Main React application component
-- src/components/TaskList.jsx - Task list component with add/remove functionality
-- package.json - Project dependencies and scripts
-- vite.config.js - Vite build configuration
-- src/App.test.jsx - Unit tests for App component
-
-## Files to Modify
-[If any existing files need modification, list them here. If starting from scratch, write "None - new project"]
-
-## Testing Strategy
-[Describe how the project will be tested. Include:
-- What will be tested (components, functions, endpoints)
-- Test coverage goals
-- Testing approach (unit tests, integration tests)]
-
-## Estimated Complexity
-[Choose ONE: Low, Medium, or High]
-Reasoning: [1-2 sentences explaining the complexity rating]
-
-## Estimated Time
-[e.g., "30 minutes", "2 hours", "4-6 hours"]
-
-## Implementation Steps
-1. [First step]
-2. [Second step]
-3. [Third step]
-[List 5-8 concrete steps in order]
+OUTPUT:
+Respond with a JSON object with these fields:
+- approach: the overall strategy and architectural decisions in 2-3 paragraphs. Explain WHY you chose this approach.
+- tech_stack: one entry per key tool, e.g. "Primary Language: JavaScript", "Framework: React 18", "Build Tool: Vite", "Testing Framework: Vitest"
+- files_to_create: every new file, each with its path and a brief description of its purpose, e.g.
+  {"path": "src/App.jsx", "description": "Main React application component"}
+  {"path": "package.json", "description": "Project dependencies and scripts"}
+- files_to_modify: paths of existing files that need changes (an empty list for a new project)
+- testing_strategy: what will be tested (components, functions, endpoints), coverage goals and approach (unit, integration)
+- complexity: Low, Medium or High
+- complexity_reasoning: 1-2 sentences explaining the complexity rating
+- estimated_time: e.g. "30 minutes", "2 hours", "4-6 hours"
+- implementation_steps: 5-8 concrete steps in order
 
 IMPORTANT:
 - Be specific about file names and paths
@@ -129,133 +112,78 @@ IMPORTANT:
 - For Python projects, include: requirements.txt, main.py, tests/
 - For Go projects, include: go.mod, main.go, *_test.go files
 
-Generate the plan now:`, project.Name, project.Description, previousContext)
+Generate the plan now.`, project.Name, project.Description, previousContext)
 
 	return prompt
 }
 
-// parsePlanResponse extracts structured data from the LLM response
-func (pg *PlanGenerator) parsePlanResponse(response string, projectID string) *PlanDocument {
-	plan := &PlanDocument{
-		ProjectID:   projectID,
-		GeneratedAt: time.Now(),
-		IsApproved:  false,
-	}
-
-	// Extract approach
-	plan.Approach = pg.extractSection(response, "Implementation Approach")
-
-	// Extract tech stack
-	techStackSection := pg.extractSection(response, "Technical Stack")
-	plan.TechStack = pg.parseListItems(techStackSection)
-
-	// Extract files to create
-	filesToCreateSection := pg.extractSection(response, "Files to Create")
-	plan.FilesToCreate = pg.parseFileList(filesToCreateSection)
-
-	// Extract files to modify
-	filesToModifySection := pg.extractSection(response, "Files to Modify")
-	if !strings.Contains(strings.ToLower(filesToModifySection), "none") {
-		plan.FilesToModify = pg.parseFileList(filesToModifySection)
-	}
-
-	// Extract testing strategy
-	plan.TestingStrategy = pg.extractSection(response, "Testing Strategy")
-
-	// Extract complexity
-	complexitySection := pg.extractSection(response, "Estimated Complexity")
-	plan.Complexity = pg.extractComplexity(complexitySection)
-
-	// Extract estimated time
-	timeSection := pg.extractSection(response, "Estimated Time")
-	plan.EstimatedTime = strings.TrimSpace(strings.Split(timeSection, "\n")[0])
+// planResponse is the structured plan returned by the model
+type planResponse struct {
+	Approach            string     `json:"approach"`
+	TechStack           []string   `json:"tech_stack"`
+	FilesToCreate       []planFile `json:"files_to_create"`
+	FilesToModify       []string   `json:"files_to_modify,omitempty"`
+	TestingStrategy     string     `json:"testing_strategy"`
+	Complexity          string     `json:"complexity" enum:"Low,Medium,High"`
+	ComplexityReasoning string     `json:"complexity_reasoning,omitempty"`
+	EstimatedTime       string     `json:"estimated_time"`
+	ImplementationSteps []string   `json:"implementation_steps,omitempty"`
+}
 
-	return plan
+// planFile is one file the plan will create
+type planFile struct {
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
 }
 
-// extractSection extracts content between section headers
-func (pg *PlanGenerator) extractSection(text string, sectionName string) string {
-	// Match "## Section Name" or "**Section Name**" or "Section Name:"
-	patterns := []string{
-		`(?i)##\s*` + regexp.QuoteMeta(sectionName) + `\s*\n((?:(?!##).)*?)(?:\n##|$)`,
-		`(?i)\*\*` + regexp.QuoteMeta(sectionName) + `\*\*\s*\n((?:(?!\*\*).)*?)(?:\n\*\*|$)`,
-		`(?i)` + regexp.QuoteMeta(sectionName) + `:\s*\n((?:(?!\n[A-Z]).)*?)(?:\n[A-Z]|$)`,
+// Validate rejects plans that code generation could not act on
+func (r *planResponse) Validate() error {
+	if len(r.FilesToCreate) == 0 {
+		return fmt.Errorf("files_to_create must list at least one file")
 	}
 
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(text)
-		if len(matches) > 1 {
-			return strings.TrimSpace(matches[1])
+	for _, file := range append(r.FilesToModify, r.filePaths()...) {
+		clean := filepath.ToSlash(filepath.Clean(file))
+		if filepath.IsAbs(file) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("file path %q must be relative to the project root", file)
 		}
 	}
 
-	return ""
+	return nil
 }
 
-// parseListItems extracts list items from text
-func (pg *PlanGenerator) parseListItems(text string) []string {
-	lines := strings.Split(text, "\n")
-	items := []string{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// Remove list markers (-, *, •, 1., etc.)
-		line = regexp.MustCompile(`^[-*•]\s*`).ReplaceAllString(line, "")
-		line = regexp.MustCompile(`^\d+\.\s*`).ReplaceAllString(line, "")
-		line = strings.TrimSpace(line)
-
-		if line != "" {
-			items = append(items, line)
-		}
+// filePaths returns the paths of the files to create
+func (r *planResponse) filePaths() []string {
+	paths := make([]string, 0, len(r.FilesToCreate))
+	for _, file := range r.FilesToCreate {
+		paths = append(paths, strings.TrimSpace(file.Path))
 	}
-
-	return items
+	return paths
 }
 
-// parseFileList extracts file paths from file listing
-func (pg *PlanGenerator) parseFileList(text string) []string {
-	lines := strings.Split(text, "\n")
-	files := []string{}
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// Remove list markers
-		line = regexp.MustCompile(`^[-*•]\s*`).ReplaceAllString(line, "")
-		line = regexp.MustCompile(`^\d+\.\s*`).ReplaceAllString(line, "")
-
-		// Extract file path (before any " - " or " : " description)
-		parts := regexp.MustCompile(`\s+-\s+|\s+:\s+`).Split(line, 2)
-		if len(parts) > 0 {
-			filePath := strings.TrimSpace(parts[0])
-			if filePath != "" && !strings.HasPrefix(strings.ToLower(filePath), "none") {
-				files = append(files, filePath)
-			}
-		}
+// toPlanDocument converts the model's plan into the project's plan document
+func (r *planResponse) toPlanDocument(projectID string) *PlanDocument {
+	return &PlanDocument{
+		ProjectID:       projectID,
+		GeneratedAt:     time.Now(),
+		Approach:        strings.TrimSpace(r.Approach),
+		FilesToCreate:   r.filePaths(),
+		FilesToModify:   r.FilesToModify,
+		TechStack:       r.TechStack,
+		TestingStrategy: strings.TrimSpace(r.TestingStrategy),
+		EstimatedTime:   strings.TrimSpace(r.EstimatedTime),
+		Complexity:      r.Complexity,
+		IsApproved:      false,
 	}
-
-	return files
 }
 
-// extractComplexity extracts complexity rating
-func (pg *PlanGenerator) extractComplexity(text string) string {
-	text = strings.ToLower(text)
-
-	if strings.Contains(text, "high") {
-		return "High"
-	} else if strings.Contains(text, "medium") {
-		return "Medium"
-	} else if strings.Contains(text, "low") {
-		return "Low"
+// sortedKeys returns the keys of an agent output map in a stable order,
+// so prompts built from them are identical across runs
+func sortedKeys(outputs map[string]string) []string {
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
 	}
-
-	return "Medium" // Default
+	sort.Strings(keys)
+	return keys
 }
@@ -1,6 +1,8 @@
 package project
 
 import (
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/task"
 	"time"
 )
 
@@ -16,6 +18,7 @@ type Project struct {
 	Metadata          ProjectMetadata    `json:"metadata"`
 	ValidationResults *ValidationResults `json:"validation_results,omitempty"`
 	PlanDocument      *PlanDocument      `json:"plan_document,omitempty"`      // NEW: Generated plan for approval
+	Repairs           []RepairIteration  `json:"repairs,omitempty"`            // Repair loop iterations of every CodeGen run
 	CreatedAt         time.Time          `json:"created_at"`
 	UpdatedAt         time.Time          `json:"updated_at"`
 	CompletedAt       *time.Time         `json:"completed_at,omitempty"`
@@ -48,6 +51,13 @@ type PhaseExecution struct {
 	AgentOutputs      map[string]string `json:"agent_outputs"`
 	HumanApproval     bool              `json:"human_approval"`
 	Notes             string            `json:"notes"`
+	CacheHits         int               `json:"cache_hits,omitempty"`   // LLM responses served from cache
+	CacheMisses       int               `json:"cache_misses,omitempty"` // LLM calls sent to the model
+
+	// LLM usage accumulated over every run of this phase, including cancelled and failed ones
+	Usage      *llm.Usage           `json:"usage,omitempty"`
+	ModelUsage map[string]llm.Usage `json:"model_usage,omitempty"`
+	AgentUsage map[string]llm.Usage `json:"agent_usage,omitempty"` // Completed runs only
 }
 
 // PhaseStatus represents the status of a phase
@@ -58,6 +68,7 @@ const (
 	PhaseStatusInProgress PhaseStatus = "in_progress"
 	PhaseStatusBlocked    PhaseStatus = "blocked"
 	PhaseStatusComplete   PhaseStatus = "complete"
+	PhaseStatusCancelled  PhaseStatus = "cancelled" // Stopped partway via cancel request
 )
 
 // TaskExecution tracks a single task execution within a project
@@ -67,10 +78,18 @@ type TaskExecution struct {
 	TaskType        string                 `json:"task_type"` // code, validate, review
 	Input           string                 `json:"input"`
 	Output          string                 `json:"output"`
+	ArtifactID      string                 `json:"artifact_id,omitempty"` // Artifact store ID of the task's result
 	ArtifactPath    string                 `json:"artifact_path"`
+	ProjectDir      string                 `json:"project_dir,omitempty"` // Project directory generated or patched (CodeGen)
 	ComplexityScore int                    `json:"complexity_score"`
 	ExecutionRoute  string                 `json:"execution_route"` // ollama or claude_code
 	AgentMetadata   map[string]interface{} `json:"agent_metadata"`
+	CacheHits       int                    `json:"cache_hits,omitempty"`    // LLM responses served from cache
+	CacheMisses     int                    `json:"cache_misses,omitempty"`  // LLM calls sent to the model
+	Usage           *llm.Usage             `json:"usage,omitempty"`         // LLM usage of the whole supervised pipeline
+	ThinkingPath    string                 `json:"thinking_path,omitempty"` // Model's reasoning trace, saved apart from Output
+	Patch           *task.PatchReport      `json:"patch,omitempty"`         // Changes made by an incremental CodeGen run
+	Coverage        *task.FileCoverage     `json:"coverage,omitempty"`      // Planned files generated by a per-file CodeGen run
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
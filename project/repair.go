@@ -0,0 +1,338 @@
+package project
+
+import (
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+	"ai-studio/orchestrator/validation"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Bounds on the logs sent to the code model in a repair request
+const (
+	maxRepairLogLines = 80
+	maxRepairLogBytes = 6000
+)
+
+// RepairIteration is one round of the CodeGen repair loop: the failures fed back to the
+// code model, the fix it made and the verification that followed
+type RepairIteration struct {
+	TaskID       string            `json:"task_id"` // CodeGen task execution the loop repaired
+	Iteration    int               `json:"iteration"`
+	StartedAt    time.Time         `json:"started_at"`
+	Duration     float64           `json:"duration_seconds"`
+	Failures     []string          `json:"failures"` // What verification reported before the fix
+	ArtifactPath string            `json:"artifact_path,omitempty"`
+	Patch        *task.PatchReport `json:"patch,omitempty"`     // Files changed, with their diff
+	Passed       bool              `json:"passed"`              // Verification passed after the fix
+	Remaining    []string          `json:"remaining,omitempty"` // Failures left after the fix
+	Usage        *llm.Usage        `json:"usage,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// verification is one pass of the Triple Guarantee: build, runtime and tests
+type verification struct {
+	build   *supervisor.VerificationResult
+	runtime *validation.RuntimeResult
+	tests   *validation.TestResult
+}
+
+// runVerification verifies the project in projectDir; runtime and tests only run once it builds
+func runVerification(ctx context.Context, projectDir string) *verification {
+	v := &verification{}
+
+	// Phase 1: Build Verification
+	build, err := supervisor.NewVerificationAgent().VerifyProject(ctx, projectDir)
+	if err != nil || build == nil {
+		log.Printf("ProjectOrchestrator: Build verification skipped or failed: %v", err)
+		return v
+	}
+	v.build = build
+	log.Printf("ProjectOrchestrator: Build verification - Syntax: %v, Dependencies: %v, Entry Point: %v",
+		build.SyntaxValid, build.DependenciesOK, build.EntryPointValid)
+	if !v.builds() {
+		return v
+	}
+
+	// Phase 2: Runtime Verification (only if build passed)
+	runtime, err := validation.NewRuntimeValidator().ValidateRuntime(ctx, projectDir, build.ProjectType)
+	if err != nil {
+		log.Printf("ProjectOrchestrator: Runtime validation failed: %v", err)
+		return v
+	}
+	if runtime == nil {
+		return v
+	}
+	v.runtime = runtime
+	if !runtime.ApplicationStarts {
+		log.Printf("ProjectOrchestrator: Runtime validation warnings: %v", runtime.Errors)
+	}
+
+	// Phase 3: Test Execution (only if build passed)
+	tests, err := validation.NewTestExecutor().ExecuteTests(ctx, projectDir, build.ProjectType)
+	if err == nil && tests != nil && tests.TestsExecuted {
+		v.tests = tests
+		log.Printf("ProjectOrchestrator: Tests executed - Passed: %d, Failed: %d, Total: %d",
+			tests.TestsPassed, tests.TestsFailed, tests.TotalTests)
+	}
+	return v
+}
+
+// builds reports whether build verification passed
+func (v *verification) builds() bool {
+	return v.build != nil && v.build.SyntaxValid && v.build.EntryPointValid && v.build.DependenciesOK
+}
+
+// failures lists what went wrong, one line per check; empty when there is nothing the code model could fix
+// A runtime check that failed without reporting errors (e.g. an unknown project type) is not a failure.
+func (v *verification) failures() []string {
+	var failures []string
+	if v.build != nil && !v.builds() {
+		failures = append(failures, fmt.Sprintf("build: %s", joinOr(v.build.Errors, "verification failed")))
+	}
+	if v.runtime != nil && !v.runtime.ApplicationStarts && len(v.runtime.Errors) > 0 {
+		failures = append(failures, fmt.Sprintf("runtime: %s", strings.Join(v.runtime.Errors, "; ")))
+	}
+	if v.tests != nil && v.tests.TestsFailed > 0 {
+		failures = append(failures, fmt.Sprintf("tests: %d/%d failed", v.tests.TestsFailed, v.tests.TotalTests))
+	}
+	return failures
+}
+
+// assess applies the verification to the phase decision and reasoning
+func (v *verification) assess(decision, reasoning string) (string, string) {
+	if v.build == nil {
+		return decision, reasoning
+	}
+
+	// Check for critical failures
+	if !v.build.SyntaxValid || !v.build.EntryPointValid {
+		return "BLOCK", fmt.Sprintf("Build verification failed: %v", v.build.Errors)
+	}
+	if !v.build.DependenciesOK {
+		return "REFINE", fmt.Sprintf("Code generated but dependencies missing: %v", v.build.Errors)
+	}
+	reasoning += " | Build: ✓"
+
+	if v.runtime != nil {
+		if v.runtime.ApplicationStarts {
+			reasoning += " | Runtime: ✓"
+			if v.runtime.HealthCheckPassed {
+				reasoning += " (health check passed)"
+			}
+		} else {
+			reasoning += " | Runtime: ⚠️ (startup failed)"
+		}
+	}
+
+	if v.tests != nil {
+		if v.tests.TestsFailed == 0 && v.tests.TestsPassed > 0 {
+			reasoning += fmt.Sprintf(" | Tests: ✓ (%d/%d passed)", v.tests.TestsPassed, v.tests.TotalTests)
+		} else if v.tests.TestsFailed > 0 {
+			reasoning += fmt.Sprintf(" | Tests: ⚠️ (%d/%d passed)", v.tests.TestsPassed, v.tests.TotalTests)
+		}
+	}
+	return decision, reasoning
+}
+
+// store saves the verification in the project's validation results
+func (v *verification) store(project *Project) {
+	project.ValidationResults = &ValidationResults{
+		LastValidated: time.Now(),
+	}
+
+	// Store build verification results
+	if v.build != nil {
+		project.ValidationResults.BuildVerified = v.builds()
+		project.ValidationResults.SyntaxValid = v.build.SyntaxValid
+		project.ValidationResults.DependenciesOK = v.build.DependenciesOK
+		project.ValidationResults.EntryPointValid = v.build.EntryPointValid
+		project.ValidationResults.BuildErrors = v.build.Errors
+	}
+
+	// Store runtime verification results
+	if v.runtime != nil {
+		project.ValidationResults.RuntimeVerified = v.runtime.ApplicationStarts
+		project.ValidationResults.ApplicationStarts = v.runtime.ApplicationStarts
+		project.ValidationResults.HealthCheckPassed = v.runtime.HealthCheckPassed
+		project.ValidationResults.RuntimeErrors = v.runtime.Errors
+		project.ValidationResults.RuntimeWarnings = v.runtime.Warnings
+	}
+
+	// Store test execution results
+	if v.tests != nil {
+		project.ValidationResults.TestsExecuted = v.tests.TestsExecuted
+		project.ValidationResults.TestsPassed = v.tests.TestsPassed
+		project.ValidationResults.TestsFailed = v.tests.TestsFailed
+		project.ValidationResults.TestsSkipped = v.tests.TestsSkipped
+		project.ValidationResults.TotalTests = v.tests.TotalTests
+		project.ValidationResults.TestFramework = v.tests.TestFramework
+		project.ValidationResults.TestErrors = v.tests.Errors
+	}
+}
+
+// feedback describes the failures for the code model, with the tails of the build, runtime and test logs
+func (v *verification) feedback() string {
+	var b strings.Builder
+	if v.build != nil && !v.builds() {
+		fmt.Fprintf(&b, "## Build verification failed (project type: %s)\n", v.build.ProjectType)
+		for _, e := range v.build.Errors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+		if tail := logTail(v.build.BuildLog); tail != "" {
+			fmt.Fprintf(&b, "\nBuild log:\n```\n%s\n```\n", tail)
+		}
+	}
+	if v.runtime != nil && !v.runtime.ApplicationStarts && len(v.runtime.Errors) > 0 {
+		b.WriteString("\n## Application failed to start\n")
+		for _, e := range v.runtime.Errors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+		if tail := logTail(v.runtime.RuntimeLog); tail != "" {
+			fmt.Fprintf(&b, "\nRuntime log:\n```\n%s\n```\n", tail)
+		}
+	}
+	if v.tests != nil && v.tests.TestsFailed > 0 {
+		fmt.Fprintf(&b, "\n## Tests failed: %d of %d (%s)\n", v.tests.TestsFailed, v.tests.TotalTests, v.tests.TestFramework)
+		for _, e := range v.tests.Errors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+		if tail := logTail(v.tests.TestOutput); tail != "" {
+			fmt.Fprintf(&b, "\nTest output:\n```\n%s\n```\n", tail)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// logTail returns the last lines of a log, within the repair request bounds
+func logTail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxRepairLogLines {
+		lines = lines[len(lines)-maxRepairLogLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxRepairLogBytes {
+		tail = tail[len(tail)-maxRepairLogBytes:]
+	}
+	return tail
+}
+
+// joinOr joins items, or returns fallback when there are none
+func joinOr(items []string, fallback string) string {
+	if len(items) == 0 {
+		return fallback
+	}
+	return strings.Join(items, "; ")
+}
+
+// repairExecutor runs the code tasks of the repair loop; *task.Manager implements it
+type repairExecutor interface {
+	ExecuteTaskStream(ctx context.Context, taskType, input, thinkingMode string, onToken llm.TokenHandler) (interface{}, error)
+}
+
+// verifyFunc verifies a project directory (runVerification)
+type verifyFunc func(ctx context.Context, projectDir string) *verification
+
+// repairProject feeds verification failures back to the code model as patch requests against
+// projectDir, re-verifying after each fix, until verification passes or the configured
+// iteration or time budget runs out. Every iteration is recorded on the project.
+// It returns the final verification and the iterations run.
+func (po *ProjectOrchestrator) repairProject(ctx context.Context, project *Project, taskID, projectDir string, check *verification) (*verification, []RepairIteration) {
+	budget := po.supervisedMgr.GetConfig().ProjectOrchestrator.Repair
+	return po.repair(ctx, po.supervisedMgr.GetBaseManager(), runVerification, budget, project, taskID, projectDir, check)
+}
+
+// repair runs the repair loop of repairProject with the given executor and verification
+// The time budget bounds the model calls and verifications themselves, not just when an iteration may start.
+func (po *ProjectOrchestrator) repair(ctx context.Context, exec repairExecutor, verify verifyFunc, budget config.RepairConfig, project *Project, taskID, projectDir string, check *verification) (*verification, []RepairIteration) {
+	if budget.MaxIterations <= 0 || len(check.failures()) == 0 {
+		return check, nil
+	}
+
+	var deadline time.Time
+	loopCtx := ctx
+	if budget.TimeBudgetSeconds > 0 {
+		deadline = time.Now().Add(time.Duration(budget.TimeBudgetSeconds) * time.Second)
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	thinkingMode := string(project.Metadata.ThinkingMode)
+	if thinkingMode == "" {
+		thinkingMode = llm.ThinkingNormal
+	}
+
+	var iterations []RepairIteration
+	for i := 1; i <= budget.MaxIterations; i++ {
+		failures := check.failures()
+		if len(failures) == 0 || ctx.Err() != nil {
+			break
+		}
+		if loopCtx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline)) {
+			log.Printf("ProjectOrchestrator: Repair time budget of %ds used up after %d iteration(s)", budget.TimeBudgetSeconds, i-1)
+			break
+		}
+
+		log.Printf("ProjectOrchestrator: Repair iteration %d/%d for %s: %s", i, budget.MaxIterations, projectDir, strings.Join(failures, " | "))
+		po.broadcastEvent("repair_started", project.ID, string(PhaseCodeGen), fmt.Sprintf("Iteration %d: %s", i, strings.Join(failures, " | ")))
+
+		iteration := RepairIteration{
+			TaskID:    taskID,
+			Iteration: i,
+			StartedAt: time.Now(),
+			Failures:  failures,
+		}
+
+		input := fmt.Sprintf("Project: %s\nDescription: %s\n\nThe project fails verification. Fix the causes of these failures and change nothing else.\n\n%s",
+			project.Name, project.Description, check.feedback())
+		repairCtx := task.WithProjectDir(loopCtx, projectDir)
+		result, err := exec.ExecuteTaskStream(repairCtx, "code", input, thinkingMode, po.tokenRelay(project, PhaseCodeGen))
+		if r, ok := result.(*task.Result); ok {
+			iteration.ArtifactPath = r.ArtifactPath
+			iteration.Patch = r.Patch
+			iteration.Usage = r.Usage
+		}
+
+		if err == nil && (iteration.Patch == nil || iteration.Patch.Changed() == 0) {
+			err = fmt.Errorf("the code model proposed no applicable changes")
+		}
+		if err != nil && loopCtx.Err() != nil && ctx.Err() == nil {
+			err = fmt.Errorf("repair time budget of %ds used up: %w", budget.TimeBudgetSeconds, err)
+		}
+		if err != nil {
+			iteration.Error = err.Error()
+			iteration.Remaining = failures
+			iteration.Duration = time.Since(iteration.StartedAt).Seconds()
+			iterations = append(iterations, iteration)
+			log.Printf("ProjectOrchestrator: Repair iteration %d failed: %v", i, err)
+			break
+		}
+
+		check = verify(loopCtx, projectDir)
+		if loopCtx.Err() != nil && ctx.Err() == nil {
+			// The budget ran out mid-verification; verify once more outside it so the phase decision is accurate
+			iteration.Error = fmt.Sprintf("repair time budget of %ds used up during verification", budget.TimeBudgetSeconds)
+			check = verify(ctx, projectDir)
+		}
+		iteration.Remaining = check.failures()
+		iteration.Passed = len(iteration.Remaining) == 0
+		iteration.Duration = time.Since(iteration.StartedAt).Seconds()
+		iterations = append(iterations, iteration)
+
+		status := "passed"
+		if !iteration.Passed {
+			status = strings.Join(iteration.Remaining, " | ")
+		}
+		log.Printf("ProjectOrchestrator: Repair iteration %d changed %d file(s); verification: %s", i, iteration.Patch.Changed(), status)
+		po.broadcastEvent("repair_completed", project.ID, string(PhaseCodeGen), fmt.Sprintf("Iteration %d: %s", i, status))
+	}
+
+	project.Repairs = append(project.Repairs, iterations...)
+	return check, iterations
+}
@@ -0,0 +1,126 @@
+package project
+
+import (
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+	"ai-studio/orchestrator/validation"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRepairer answers repair requests with a fixed patch, or blocks until its context ends
+type fakeRepairer struct {
+	patch *task.PatchReport
+	block bool
+	calls int
+}
+
+func (f *fakeRepairer) ExecuteTaskStream(ctx context.Context, taskType, input, thinkingMode string, onToken llm.TokenHandler) (interface{}, error) {
+	f.calls++
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &task.Result{TaskType: taskType, Patch: f.patch}, nil
+}
+
+// failingBuild is a verification whose build has a syntax error
+func failingBuild() *verification {
+	return &verification{build: &supervisor.VerificationResult{DependenciesOK: true, EntryPointValid: true, Errors: []string{"app.js:3 unexpected token"}}}
+}
+
+// passingBuild is a verification that builds, starts and passes its tests
+func passingBuild() *verification {
+	return &verification{
+		build:   &supervisor.VerificationResult{SyntaxValid: true, DependenciesOK: true, EntryPointValid: true},
+		runtime: &validation.RuntimeResult{ApplicationStarts: true},
+		tests:   &validation.TestResult{TestsExecuted: true, TestsPassed: 3, TotalTests: 3},
+	}
+}
+
+// TestRepairLoop tests passing after several fixes, an exhausted time budget and a fix that changes nothing
+func TestRepairLoop(t *testing.T) {
+	po := &ProjectOrchestrator{}
+	ctx := context.Background()
+	patch := &task.PatchReport{Modified: []string{"app.js"}}
+
+	// Passes on the third verification
+	verifications := 0
+	verify := func(ctx context.Context, dir string) *verification {
+		verifications++
+		if verifications < 3 {
+			return failingBuild()
+		}
+		return passingBuild()
+	}
+	project := &Project{Name: "demo"}
+	exec := &fakeRepairer{patch: patch}
+	check, iterations := po.repair(ctx, exec, verify, config.RepairConfig{MaxIterations: 5}, project, "t1", "dir", failingBuild())
+	if len(iterations) != 3 || !iterations[2].Passed || iterations[1].Passed || exec.calls != 3 {
+		t.Fatalf("iterations = %+v (calls %d), want 3 ending in a pass", iterations, exec.calls)
+	}
+	if len(check.failures()) != 0 || len(project.Repairs) != 3 {
+		t.Errorf("final failures = %v, recorded repairs = %d", check.failures(), len(project.Repairs))
+	}
+	if decision, reasoning := check.assess("PROCEED", "Generated"); decision != "PROCEED" || !strings.Contains(reasoning, "Tests: ✓ (3/3 passed)") {
+		t.Errorf("assess = %s, %q", decision, reasoning)
+	}
+
+	// The budget cuts off a model call that would never return
+	start := time.Now()
+	exec = &fakeRepairer{block: true}
+	check, iterations = po.repair(ctx, exec, verify, config.RepairConfig{MaxIterations: 3, TimeBudgetSeconds: 1}, &Project{}, "t2", "dir", failingBuild())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("repair took %v with a 1s budget", elapsed)
+	}
+	if len(iterations) != 1 || !strings.Contains(iterations[0].Error, "time budget") || exec.calls != 1 {
+		t.Errorf("iterations = %+v, want one stopped by the time budget", iterations)
+	}
+	if len(check.failures()) == 0 {
+		t.Error("failures should remain after an interrupted repair")
+	}
+
+	// A response that changes no file ends the loop
+	exec = &fakeRepairer{patch: &task.PatchReport{}}
+	_, iterations = po.repair(ctx, exec, verify, config.RepairConfig{MaxIterations: 3}, &Project{}, "t3", "dir", failingBuild())
+	if len(iterations) != 1 || !strings.Contains(iterations[0].Error, "no applicable changes") || len(iterations[0].Remaining) != 1 {
+		t.Errorf("iterations = %+v, want one with no applicable changes", iterations)
+	}
+}
+
+// TestVerificationFailures tests which checks count as failures and how they affect the phase decision
+func TestVerificationFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    *verification
+		failures int
+		decision string
+	}{
+		{"passing", passingBuild(), 0, "PROCEED"},
+		{"syntax error", failingBuild(), 1, "BLOCK"},
+		{"missing dependencies", &verification{build: &supervisor.VerificationResult{SyntaxValid: true, EntryPointValid: true}}, 1, "REFINE"},
+		{"runtime failure without errors", &verification{
+			build:   passingBuild().build,
+			runtime: &validation.RuntimeResult{},
+		}, 0, "PROCEED"},
+		{"crash and failing tests", &verification{
+			build:   passingBuild().build,
+			runtime: &validation.RuntimeResult{Errors: []string{"TypeError"}},
+			tests:   &validation.TestResult{TestsExecuted: true, TestsPassed: 1, TestsFailed: 2, TotalTests: 3},
+		}, 2, "PROCEED"},
+		{"not verified", &verification{}, 0, "PROCEED"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.check.failures(); len(got) != tt.failures {
+			t.Errorf("%s: failures = %v, want %d", tt.name, got, tt.failures)
+		}
+		if decision, _ := tt.check.assess("PROCEED", ""); decision != tt.decision {
+			t.Errorf("%s: decision = %s, want %s", tt.name, decision, tt.decision)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package project
+
+import (
+	"ai-studio/orchestrator/llm"
+)
+
+// UsageLedger rolls up a project's LLM usage so the most expensive phases,
+// agents and models stand out
+type UsageLedger struct {
+	ProjectID string               `json:"project_id"`
+	Total     llm.Usage            `json:"total"`
+	ByPhase   map[string]llm.Usage `json:"by_phase"`
+	ByAgent   map[string]llm.Usage `json:"by_agent"` // Completed phase runs only
+	ByModel   map[string]llm.Usage `json:"by_model"`
+}
+
+// BuildUsageLedger sums the usage recorded on every phase execution of a project
+func BuildUsageLedger(project *Project) *UsageLedger {
+	ledger := &UsageLedger{
+		ProjectID: project.ID,
+		ByPhase:   make(map[string]llm.Usage),
+		ByAgent:   make(map[string]llm.Usage),
+		ByModel:   make(map[string]llm.Usage),
+	}
+
+	for _, phaseExec := range project.Phases {
+		if phaseExec.Usage == nil {
+			continue
+		}
+
+		ledger.Total.Add(*phaseExec.Usage)
+		addUsageTo(ledger.ByPhase, string(phaseExec.Phase), *phaseExec.Usage)
+
+		for agent, usage := range phaseExec.AgentUsage {
+			addUsageTo(ledger.ByAgent, agent, usage)
+		}
+		for model, usage := range phaseExec.ModelUsage {
+			addUsageTo(ledger.ByModel, model, usage)
+		}
+	}
+
+	return ledger
+}
+
+// phaseAgentUsage attributes a completed phase's LLM usage to agents
+// Calls not made by a specialist agent belong to the Lead Agent, or to the coder in CodeGen
+func phaseAgentUsage(phase Phase, result *PhaseResult) map[string]llm.Usage {
+	byAgent := make(map[string]llm.Usage)
+	remainder := result.Usage
+
+	for name, output := range result.AgentOutputs {
+		if output == nil || output.Usage == nil {
+			continue
+		}
+		byAgent[name] = *output.Usage
+		remainder = remainder.Sub(*output.Usage)
+	}
+
+	if remainder.Calls > 0 {
+		owner := "lead_agent"
+		if phase == PhaseCodeGen {
+			owner = "coder"
+		}
+		byAgent[owner] = remainder
+	}
+
+	return byAgent
+}
+
+// findPhaseExecution returns the in-progress execution record of a phase
+func findPhaseExecution(project *Project, phase Phase) *PhaseExecution {
+	for i := range project.Phases {
+		if project.Phases[i].Phase == phase && project.Phases[i].Status == PhaseStatusInProgress {
+			return &project.Phases[i]
+		}
+	}
+	return nil
+}
+
+// addPhaseUsage accumulates one run's usage on a phase execution record
+func addPhaseUsage(phaseExec *PhaseExecution, usage llm.Usage, byModel map[string]llm.Usage) {
+	if usage.IsZero() {
+		return
+	}
+
+	if phaseExec.Usage == nil {
+		phaseExec.Usage = &llm.Usage{}
+	}
+	phaseExec.Usage.Add(usage)
+
+	if phaseExec.ModelUsage == nil {
+		phaseExec.ModelUsage = make(map[string]llm.Usage)
+	}
+	for model, u := range byModel {
+		addUsageTo(phaseExec.ModelUsage, model, u)
+	}
+}
+
+// addAgentUsage accumulates per-agent usage on a phase execution record
+func addAgentUsage(phaseExec *PhaseExecution, byAgent map[string]llm.Usage) {
+	if len(byAgent) == 0 {
+		return
+	}
+
+	if phaseExec.AgentUsage == nil {
+		phaseExec.AgentUsage = make(map[string]llm.Usage)
+	}
+	for agent, u := range byAgent {
+		addUsageTo(phaseExec.AgentUsage, agent, u)
+	}
+}
+
+// addUsageTo adds usage to the entry for key
+func addUsageTo(totals map[string]llm.Usage, key string, usage llm.Usage) {
+	total := totals[key]
+	total.Add(usage)
+	totals[key] = total
+}
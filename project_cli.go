@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"ai-studio/orchestrator/project"
+)
+
+const projectUsage = `Usage: orchestrator -mode=project <command> [flags]
+
+Commands:
+  create   -name <name> -description <text> | -description-file <file>
+  list
+  status   -id <project>
+  run      -id <project> [-phase <phase>]    Run a phase (default: the current one)
+  approve  -id <project>                     Approve the current phase (or the plan)
+  reject   -id <project> [-reason <text>]    Reject the current phase (a rejected plan goes back to planning)
+  revert   -id <project> -phase <phase> [-reason <text>]
+  metrics  -id <project> [-quality]          Completion checks and LLM usage by phase (or the quality report)
+  download -id <project> [-out <file.zip>]   Archive the generated project
+
+Every command accepts -json to print machine-readable output.`
+
+// runProjectCommand runs a project lifecycle subcommand against the local projects store
+func runProjectCommand(ctx context.Context, po *project.ProjectOrchestrator, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" {
+		fmt.Println(projectUsage)
+		return nil
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	id := fs.String("id", "", "Project ID")
+	asJSON := fs.Bool("json", false, "Print JSON output")
+	name := fs.String("name", "", "Project name")
+	description := fs.String("description", "", "Project description")
+	descriptionFile := fs.String("description-file", "", "Read the project description from a file")
+	phase := fs.String("phase", "", "Project phase")
+	reason := fs.String("reason", "", "Reason for a rejection or revert")
+	quality := fs.Bool("quality", false, "Print the quality guarantee report")
+	out := fs.String("out", "", "Output file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if command != "create" && command != "list" && *id == "" {
+		return fmt.Errorf("%s requires -id", command)
+	}
+
+	switch command {
+	case "create":
+		if *descriptionFile != "" {
+			data, err := os.ReadFile(*descriptionFile)
+			if err != nil {
+				return fmt.Errorf("failed to read description: %w", err)
+			}
+			*description = string(data)
+		}
+		if strings.TrimSpace(*name) == "" || strings.TrimSpace(*description) == "" {
+			return errors.New("create requires -name and -description")
+		}
+		proj, err := po.CreateProject(*name, *description)
+		if err != nil {
+			return fmt.Errorf("failed to create project: %w", err)
+		}
+		if *asJSON {
+			return printJSON(proj)
+		}
+		fmt.Printf("✓ Created project %s (%s)\n", proj.ID, proj.Name)
+
+	case "list":
+		projects := po.ListProjects()
+		if *asJSON {
+			return printJSON(projects)
+		}
+		for _, proj := range projects {
+			fmt.Printf("%s  %-20s %-10s %s\n", proj.ID, proj.CurrentPhase, proj.Status, proj.Name)
+		}
+		fmt.Printf("%d project(s)\n", len(projects))
+
+	case "status":
+		proj, err := po.GetProject(*id)
+		if err != nil {
+			return fmt.Errorf("project not found: %w", err)
+		}
+		if *asJSON {
+			return printJSON(proj)
+		}
+		printProjectStatus(proj)
+
+	case "run":
+		proj, err := po.GetProject(*id)
+		if err != nil {
+			return fmt.Errorf("project not found: %w", err)
+		}
+		target := proj.CurrentPhase
+		if *phase != "" {
+			target = project.Phase(*phase)
+		}
+		result, err := po.ExecuteProjectPhase(ctx, *id, target)
+		if err != nil {
+			return fmt.Errorf("failed to execute phase: %w", err)
+		}
+		if *asJSON {
+			return printJSON(result)
+		}
+		fmt.Printf("\n=== %s: %s ===\n%s\n", result.Phase, result.Decision, result.Reasoning)
+		if result.NextSteps != "" {
+			fmt.Printf("\nNext steps: %s\n", result.NextSteps)
+		}
+		if result.RequiresApproval {
+			fmt.Printf("\nAwaiting approval: orchestrator -mode=project approve -id %s\n", *id)
+		}
+
+	case "approve":
+		if err := po.ApprovePhase(*id); err != nil {
+			return fmt.Errorf("failed to approve phase: %w", err)
+		}
+		return printOutcome(po, *id, *asJSON, "Phase approved")
+
+	case "reject":
+		if err := po.RejectPhase(*id, *reason); err != nil {
+			return fmt.Errorf("failed to reject phase: %w", err)
+		}
+		return printOutcome(po, *id, *asJSON, "Phase rejected")
+
+	case "revert":
+		if *phase == "" {
+			return errors.New("revert requires -phase")
+		}
+		if err := po.RevertPhase(*id, project.Phase(*phase), *reason); err != nil {
+			return fmt.Errorf("failed to revert phase: %w", err)
+		}
+		return printOutcome(po, *id, *asJSON, fmt.Sprintf("Reverted to %s phase", *phase))
+
+	case "metrics":
+		proj, err := po.GetProject(*id)
+		if err != nil {
+			return fmt.Errorf("project not found: %w", err)
+		}
+		metrics, err := po.GetCompletionMetrics(ctx, *id)
+		if err != nil {
+			return fmt.Errorf("failed to get metrics: %w", err)
+		}
+		if *quality {
+			report := project.GenerateQualityReport(proj.Name, *metrics)
+			if *asJSON {
+				return printJSON(report)
+			}
+			fmt.Println(report.ToMarkdown())
+			return nil
+		}
+		usage, err := po.GetUsageLedger(*id)
+		if err != nil {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if *asJSON {
+			// Same shape as GET /project/metrics
+			return printJSON(struct {
+				*project.CompletionMetrics
+				Usage *project.UsageLedger `json:"usage"`
+			}{metrics, usage})
+		}
+		printProjectMetrics(metrics, usage)
+
+	case "download":
+		dir, err := po.ProjectDir(*id)
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			*out = *id + ".zip"
+		}
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		if err := project.WriteZip(file, dir); err != nil {
+			file.Close()
+			os.Remove(*out)
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if *asJSON {
+			return printJSON(map[string]string{"project_dir": dir, "archive": *out})
+		}
+		fmt.Printf("✓ Archived %s to %s\n", dir, *out)
+
+	default:
+		return fmt.Errorf("unknown project command %q\n\n%s", command, projectUsage)
+	}
+	return nil
+}
+
+// printProjectStatus prints a project's phases, plan and latest tasks
+func printProjectStatus(proj *project.Project) {
+	fmt.Printf("Project:  %s (%s)\n", proj.Name, proj.ID)
+	fmt.Printf("Status:   %s\n", proj.Status)
+	fmt.Printf("Phase:    %s\n", proj.CurrentPhase)
+	fmt.Printf("Updated:  %s\n", proj.UpdatedAt.Format("2006-01-02 15:04:05"))
+
+	if len(proj.Phases) > 0 {
+		fmt.Println("\nPhases:")
+		for _, p := range proj.Phases {
+			line := fmt.Sprintf("  %-17s %-12s", p.Phase, p.Status)
+			if p.LeadAgentDecision != "" {
+				line += " " + p.LeadAgentDecision
+			}
+			if p.Notes != "" {
+				line += " - " + p.Notes
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+	}
+
+	if plan := proj.PlanDocument; plan != nil {
+		state := "awaiting approval"
+		if plan.IsApproved {
+			state = "approved"
+		} else if plan.RejectedAt != nil {
+			state = "rejected"
+		}
+		fmt.Printf("\nPlan (%s): %d file(s), complexity %s, estimate %s\n", state, len(plan.FilesToCreate), plan.Complexity, plan.EstimatedTime)
+	}
+
+	if n := len(proj.Tasks); n > 0 {
+		fmt.Println("\nLatest tasks:")
+		for _, t := range proj.Tasks[max(n-5, 0):] {
+			fmt.Printf("  %s  %-10s %-9s %s\n", t.CreatedAt.Format("2006-01-02 15:04"), t.Phase, t.TaskType, t.ArtifactPath)
+		}
+	}
+}
+
+// printProjectMetrics prints completion checks, blocking issues and LLM usage by phase
+func printProjectMetrics(metrics *project.CompletionMetrics, usage *project.UsageLedger) {
+	yesNo := func(ok bool) string {
+		if ok {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Printf("Completion:     %.0f%%\n", metrics.CompletionPct)
+	fmt.Printf("Quality score:  %d/100\n", metrics.QualityScore)
+	fmt.Printf("Runnable build: %s (syntax valid: %s, dependencies: %s)\n",
+		yesNo(metrics.HasRunnableBuild), yesNo(metrics.SyntaxValid), yesNo(metrics.DependenciesOK))
+	fmt.Printf("Tests:          %s", yesNo(metrics.HasTests))
+	if metrics.TestsExecuted {
+		fmt.Printf(" (%d passed, %d failed)", metrics.TestsPassed, metrics.TestsFailed)
+	}
+	fmt.Printf("\nREADME:         %s\n", yesNo(metrics.HasReadme))
+
+	if len(metrics.BlockingIssues) > 0 {
+		fmt.Println("\nBlocking issues:")
+		for _, issue := range metrics.BlockingIssues {
+			fmt.Printf("  - %s\n", issue)
+		}
+	}
+
+	total := usage.Total
+	fmt.Printf("\nLLM usage: %d call(s), %d prompt + %d completion tokens, %.1fs\n",
+		total.Calls, total.PromptTokens, total.CompletionTokens, total.LatencySeconds)
+	phases := make([]string, 0, len(usage.ByPhase))
+	for phase := range usage.ByPhase {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		u := usage.ByPhase[phase]
+		fmt.Printf("  %-17s %3d call(s) %8d tokens %7.1fs\n", phase, u.Calls, u.PromptTokens+u.CompletionTokens, u.LatencySeconds)
+	}
+}
+
+// printOutcome prints the result of a lifecycle command and where the project now stands
+func printOutcome(po *project.ProjectOrchestrator, id string, asJSON bool, message string) error {
+	proj, err := po.GetProject(id)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(map[string]interface{}{
+			"success":       true,
+			"message":       message,
+			"current_phase": proj.CurrentPhase,
+			"status":        proj.Status,
+		})
+	}
+	fmt.Printf("✓ %s (now in %s, %s)\n", message, proj.CurrentPhase, proj.Status)
+	return nil
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/project"
+	"ai-studio/orchestrator/supervisor"
+	"ai-studio/orchestrator/task"
+)
+
+// runCLI runs a project command and returns what it printed
+func runCLI(t *testing.T, po *project.ProjectOrchestrator, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := runProjectCommand(context.Background(), po, args)
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), runErr
+}
+
+// TestProjectCommands tests the lifecycle commands against a temporary projects store
+func TestProjectCommands(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// No command here calls a model
+	cfg := &config.Config{OllamaURL: "http://127.0.0.1:1", ArtifactsDir: "artifacts", Timeout: 5}
+	baseMgr, err := task.NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisedMgr := supervisor.NewSupervisedTaskManager(baseMgr, cfg, supervisor.DefaultSupervisorConfig())
+	po, err := project.NewProjectOrchestrator(supervisedMgr, "projects", cfg.ArtifactsDir, baseMgr.GetClient(),
+		supervisedMgr.GetRequirementsAgent(), supervisedMgr.GetTechStackAgent(), supervisedMgr.GetScopeAgent(),
+		supervisedMgr.GetQAAgent(), supervisedMgr.GetTestingAgent(), supervisedMgr.GetDocsAgent(),
+		supervisedMgr.GetComplexityScorer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created project.Project
+	out, err := runCLI(t, po, "create", "-name", "Todo App", "-description", "A todo list", "-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &created); err != nil || created.ID == "" {
+		t.Fatalf("create printed %q (%v)", out, err)
+	}
+	id := created.ID
+
+	if _, err := runCLI(t, po, "create", "-name", "No description"); err == nil {
+		t.Error("create without a description succeeded")
+	}
+	if _, err := runCLI(t, po, "status"); err == nil || !strings.Contains(err.Error(), "requires -id") {
+		t.Errorf("status without -id: %v", err)
+	}
+
+	steps := []struct {
+		args  []string
+		phase project.Phase
+		want  string // Printed output must contain this
+	}{
+		{[]string{"status", "-id", id}, project.PhaseDiscovery, "Todo App"},
+		{[]string{"approve", "-id", id}, project.PhaseValidation, "Phase approved (now in validation"},
+		{[]string{"approve", "-id", id}, project.PhasePlanning, "now in planning"},
+		{[]string{"reject", "-id", id, "-reason", "Use SQLite"}, project.PhasePlanning, "Phase rejected"},
+		{[]string{"revert", "-id", id, "-phase", "discovery", "-reason", "Scope changed"}, project.PhaseDiscovery, "Reverted to discovery phase"},
+	}
+	for _, step := range steps {
+		out, err := runCLI(t, po, step.args...)
+		if err != nil {
+			t.Fatalf("%s: %v", step.args[0], err)
+		}
+		if !strings.Contains(out, step.want) {
+			t.Errorf("%s printed %q, want %q", step.args[0], out, step.want)
+		}
+		proj, err := po.GetProject(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proj.CurrentPhase != step.phase {
+			t.Errorf("after %s: phase %s, want %s", step.args[0], proj.CurrentPhase, step.phase)
+		}
+	}
+
+	proj, _ := po.GetProject(id)
+	if proj.Status != project.ProjectStatusActive {
+		t.Errorf("status after revert = %s, want the rejection lifted", proj.Status)
+	}
+	if _, err := runCLI(t, po, "revert", "-id", id, "-phase", "qa"); err == nil {
+		t.Error("revert to a phase never reached succeeded")
+	}
+
+	// Metrics are readable by default and JSON on request
+	out, err = runCLI(t, po, "metrics", "-id", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Completion:") || !strings.Contains(out, "LLM usage:") || strings.HasPrefix(out, "{") {
+		t.Errorf("metrics printed %q", out)
+	}
+	out, err = runCLI(t, po, "metrics", "-id", id, "-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metrics map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &metrics); err != nil {
+		t.Fatalf("metrics -json printed %q: %v", out, err)
+	}
+	if _, ok := metrics["usage"]; !ok {
+		t.Error("metrics -json has no usage")
+	}
+}
@@ -2,18 +2,20 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
 	"time"
 )
 
 // DocumentationAgent generates README and API docs
 type DocumentationAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
-func NewDocumentationAgent(client *llm.Client, model string) *DocumentationAgent {
-	return &DocumentationAgent{client: client, model: model}
+func NewDocumentationAgent(client llm.Provider, model string, options map[string]interface{}) *DocumentationAgent {
+	return &DocumentationAgent{client: client, model: model, options: options}
 }
 
 func (a *DocumentationAgent) Name() string {
@@ -24,7 +26,7 @@ func (a *DocumentationAgent) RequiresInput() bool {
 	return false
 }
 
-func (a *DocumentationAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *DocumentationAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	output, ok := context["output"].(string)
@@ -33,7 +35,8 @@ func (a *DocumentationAgent) Execute(taskType, input string, context map[string]
 	}
 
 	prompt := a.buildPrompt(taskType, input, output)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	response, err := llm.Generate(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("documentation agent failed: %w", err)
 	}
@@ -43,6 +46,7 @@ func (a *DocumentationAgent) Execute(taskType, input string, context map[string]
 		Status:    "passed",
 		Output:    response,
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
@@ -2,18 +2,20 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
 	"time"
 )
 
 // QAAgent reviews code quality, bugs, and security
 type QAAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
-func NewQAAgent(client *llm.Client, model string) *QAAgent {
-	return &QAAgent{client: client, model: model}
+func NewQAAgent(client llm.Provider, model string, options map[string]interface{}) *QAAgent {
+	return &QAAgent{client: client, model: model, options: options}
 }
 
 func (a *QAAgent) Name() string {
@@ -24,7 +26,7 @@ func (a *QAAgent) RequiresInput() bool {
 	return false // Runs after execution
 }
 
-func (a *QAAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *QAAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	// Get original output from context
@@ -34,7 +36,8 @@ func (a *QAAgent) Execute(taskType, input string, context map[string]interface{}
 	}
 
 	prompt := a.buildPrompt(taskType, input, output)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	response, err := llm.Generate(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("qa agent failed: %w", err)
 	}
@@ -44,6 +47,7 @@ func (a *QAAgent) Execute(taskType, input string, context map[string]interface{}
 		Status:    "passed", // QA always runs, doesn't block
 		Output:    response,
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
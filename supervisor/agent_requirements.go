@@ -2,22 +2,24 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
-	"strings"
 	"time"
 )
 
 // RequirementsAgent validates requirement completeness
 type RequirementsAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
 // NewRequirementsAgent creates a new requirements agent
-func NewRequirementsAgent(client *llm.Client, model string) *RequirementsAgent {
+func NewRequirementsAgent(client llm.Provider, model string, options map[string]interface{}) *RequirementsAgent {
 	return &RequirementsAgent{
-		client: client,
-		model:  model,
+		client:  client,
+		model:   model,
+		options: options,
 	}
 }
 
@@ -29,27 +31,33 @@ func (a *RequirementsAgent) RequiresInput() bool {
 	return true // Runs before execution
 }
 
-func (a *RequirementsAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *RequirementsAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	prompt := a.buildPrompt(taskType, input)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	var report requirementsReport
+	err := llm.GenerateStructured(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt, &report, llm.StructuredOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("requirements agent failed: %w", err)
 	}
 
-	// Parse response to determine if requirements are complete
-	status := a.parseStatus(response)
-
 	return &AgentOutput{
 		AgentType: "requirements",
-		Status:    status,
-		Output:    response,
+		Status:    a.parseStatus(report.Status),
+		Output:    reportMarkdown(report.Report, "Status", report.Status),
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
 
+// requirementsReport is the structured response of the requirements agent
+type requirementsReport struct {
+	Status string `json:"status" enum:"COMPLETE,NEEDS_CLARIFICATION,INCOMPLETE"`
+	Report string `json:"report"`
+}
+
 func (a *RequirementsAgent) buildPrompt(taskType, input string) string {
 	return fmt.Sprintf(`<system>
 You are a senior requirements analyst specializing in MVP validation for AI-generated software projects.
@@ -83,6 +91,10 @@ Now provide your structured analysis:
 </instructions>
 
 <output_format>
+Respond with:
+- status: COMPLETE, NEEDS_CLARIFICATION or INCOMPLETE
+- report: your analysis as markdown with these sections
+
 ## Completeness Score
 [Rate 1-10: 7+ means we can proceed with reasonable assumptions]
 
@@ -107,9 +119,6 @@ Now provide your structured analysis:
 1. [Question 1]
 2. [Question 2]
 
-## Status
-COMPLETE | NEEDS_CLARIFICATION | INCOMPLETE
-
 ## Recommendation
 [One sentence: proceed, clarify, or reject with reason]
 </output_format>
@@ -122,13 +131,14 @@ COMPLETE | NEEDS_CLARIFICATION | INCOMPLETE
 </rules>`, taskType, input)
 }
 
-func (a *RequirementsAgent) parseStatus(response string) string {
-	// Simple status extraction
-	if strings.Contains(response, "Status\nCOMPLETE") || strings.Contains(response, "## Status\nCOMPLETE") {
+// parseStatus maps the reported requirements status onto an agent status
+func (a *RequirementsAgent) parseStatus(status string) string {
+	switch status {
+	case "COMPLETE":
 		return "passed"
-	}
-	if strings.Contains(response, "INCOMPLETE") {
+	case "INCOMPLETE":
 		return "failed"
+	default:
+		return "warning" // NEEDS_CLARIFICATION
 	}
-	return "warning" // NEEDS_CLARIFICATION
 }
@@ -2,19 +2,20 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
-	"strings"
 	"time"
 )
 
 // ScopeAgent validates project scope
 type ScopeAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
-func NewScopeAgent(client *llm.Client, model string) *ScopeAgent {
-	return &ScopeAgent{client: client, model: model}
+func NewScopeAgent(client llm.Provider, model string, options map[string]interface{}) *ScopeAgent {
+	return &ScopeAgent{client: client, model: model, options: options}
 }
 
 func (a *ScopeAgent) Name() string {
@@ -25,26 +26,33 @@ func (a *ScopeAgent) RequiresInput() bool {
 	return true
 }
 
-func (a *ScopeAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *ScopeAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	prompt := a.buildPrompt(taskType, input)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	var report scopeReport
+	err := llm.GenerateStructured(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt, &report, llm.StructuredOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("scope agent failed: %w", err)
 	}
 
-	status := a.parseStatus(response)
-
 	return &AgentOutput{
 		AgentType: "scope",
-		Status:    status,
-		Output:    response,
+		Status:    a.parseStatus(report.Verdict),
+		Output:    reportMarkdown(report.Report, "Verdict", report.Verdict),
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
 
+// scopeReport is the structured response of the scope agent
+type scopeReport struct {
+	Verdict string `json:"verdict" enum:"APPROPRIATE,TOO_BROAD,TOO_NARROW"`
+	Report  string `json:"report"`
+}
+
 func (a *ScopeAgent) buildPrompt(taskType, input string) string {
 	return fmt.Sprintf(`<system>
 You are a project scoping expert specializing in MVP definition and scope control.
@@ -76,6 +84,10 @@ Analyze the scope:
 </instructions>
 
 <output_format>
+Respond with:
+- verdict: APPROPRIATE, TOO_BROAD or TOO_NARROW
+- report: your analysis as markdown with these sections
+
 ## Scope Analysis
 | Metric | Value | Status |
 |--------|-------|--------|
@@ -99,9 +111,6 @@ Analyze the scope:
 - [Scope creep indicator 1]
 - [Hidden complexity 1]
 
-## Verdict
-APPROPRIATE | TOO_BROAD | TOO_NARROW
-
 ## Recommended MVP Definition
 [If TOO_BROAD: A trimmed-down version that fits the constraints]
 [If APPROPRIATE: Confirmation of the current scope]
@@ -116,12 +125,14 @@ APPROPRIATE | TOO_BROAD | TOO_NARROW
 </rules>`, taskType, input)
 }
 
-func (a *ScopeAgent) parseStatus(response string) string {
-	if strings.Contains(response, "APPROPRIATE") {
+// parseStatus maps the scope verdict onto an agent status
+func (a *ScopeAgent) parseStatus(verdict string) string {
+	switch verdict {
+	case "APPROPRIATE":
 		return "passed"
-	}
-	if strings.Contains(response, "TOO_BROAD") {
+	case "TOO_BROAD":
 		return "failed"
+	default:
+		return "warning" // TOO_NARROW
 	}
-	return "warning" // TOO_NARROW
 }
@@ -2,19 +2,20 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
-	"strings"
 	"time"
 )
 
 // TechStackAgent validates technology choices
 type TechStackAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
-func NewTechStackAgent(client *llm.Client, model string) *TechStackAgent {
-	return &TechStackAgent{client: client, model: model}
+func NewTechStackAgent(client llm.Provider, model string, options map[string]interface{}) *TechStackAgent {
+	return &TechStackAgent{client: client, model: model, options: options}
 }
 
 func (a *TechStackAgent) Name() string {
@@ -25,7 +26,7 @@ func (a *TechStackAgent) RequiresInput() bool {
 	return true
 }
 
-func (a *TechStackAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *TechStackAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	// Only run for code generation tasks
@@ -40,22 +41,29 @@ func (a *TechStackAgent) Execute(taskType, input string, context map[string]inte
 	}
 
 	prompt := a.buildPrompt(input)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	var report techStackReport
+	err := llm.GenerateStructured(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt, &report, llm.StructuredOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("tech stack agent failed: %w", err)
 	}
 
-	status := a.parseStatus(response)
-
 	return &AgentOutput{
 		AgentType: "techstack",
-		Status:    status,
-		Output:    response,
+		Status:    a.parseStatus(report.Verdict),
+		Output:    reportMarkdown(report.Report, "Verdict", report.Verdict),
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
 
+// techStackReport is the structured response of the tech stack agent
+type techStackReport struct {
+	Verdict string `json:"verdict" enum:"APPROVED,NEEDS_REVISION,REJECTED"`
+	Report  string `json:"report"`
+}
+
 func (a *TechStackAgent) buildPrompt(input string) string {
 	return fmt.Sprintf(`<system>
 You are a senior tech lead specializing in modern, lightweight technology stacks for MVPs.
@@ -94,6 +102,10 @@ Provide your analysis:
 </instructions>
 
 <output_format>
+Respond with:
+- verdict: APPROVED, NEEDS_REVISION or REJECTED
+- report: your analysis as markdown with these sections
+
 ## Recommended Stack
 | Layer | Technology | Justification |
 |-------|------------|---------------|
@@ -116,9 +128,6 @@ Provide your analysis:
 - [Risk 1]: [Mitigation]
 - [Risk 2]: [Mitigation]
 
-## Verdict
-APPROVED | NEEDS_REVISION | REJECTED
-
 ## Quick Start Commands
 ` + "```bash" + `
 # Commands to scaffold this project
@@ -134,12 +143,14 @@ APPROVED | NEEDS_REVISION | REJECTED
 </rules>`, input)
 }
 
-func (a *TechStackAgent) parseStatus(response string) string {
-	if strings.Contains(response, "APPROVED") {
+// parseStatus maps the tech stack verdict onto an agent status
+func (a *TechStackAgent) parseStatus(verdict string) string {
+	switch verdict {
+	case "APPROVED":
 		return "passed"
-	}
-	if strings.Contains(response, "REJECTED") {
+	case "REJECTED":
 		return "failed"
+	default:
+		return "warning" // NEEDS_REVISION
 	}
-	return "warning" // NEEDS_REVISION
 }
@@ -2,18 +2,20 @@ package supervisor
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
 	"time"
 )
 
 // TestingAgent generates test plans and unit tests
 type TestingAgent struct {
-	client *llm.Client
-	model  string
+	client  llm.Provider
+	model   string
+	options map[string]interface{} // Generation options (temperature, num_ctx, ...); nil = model defaults
 }
 
-func NewTestingAgent(client *llm.Client, model string) *TestingAgent {
-	return &TestingAgent{client: client, model: model}
+func NewTestingAgent(client llm.Provider, model string, options map[string]interface{}) *TestingAgent {
+	return &TestingAgent{client: client, model: model, options: options}
 }
 
 func (a *TestingAgent) Name() string {
@@ -24,7 +26,7 @@ func (a *TestingAgent) RequiresInput() bool {
 	return false
 }
 
-func (a *TestingAgent) Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
+func (a *TestingAgent) Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error) {
 	start := time.Now()
 
 	output, ok := context["output"].(string)
@@ -33,7 +35,8 @@ func (a *TestingAgent) Execute(taskType, input string, context map[string]interf
 	}
 
 	prompt := a.buildPrompt(taskType, input, output)
-	response, err := a.client.Generate(a.model, prompt)
+	meter := &llm.UsageMeter{}
+	response, err := llm.Generate(llm.WithUsageMeter(llm.WithOptions(ctx, a.options), meter), a.client, a.model, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("testing agent failed: %w", err)
 	}
@@ -43,6 +46,7 @@ func (a *TestingAgent) Execute(taskType, input string, context map[string]interf
 		Status:    "passed",
 		Output:    response,
 		Duration:  time.Since(start).Seconds(),
+		Usage:     meteredUsage(meter),
 		Timestamp: time.Now(),
 	}, nil
 }
@@ -1,9 +1,10 @@
 package supervisor
 
 import (
+	"ai-studio/orchestrator/validation"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -45,7 +46,8 @@ const (
 )
 
 // VerifyProject verifies a generated project
-func (va *VerificationAgent) VerifyProject(projectPath string) (*VerificationResult, error) {
+// Cancelling ctx kills any running install/build commands and returns ctx.Err()
+func (va *VerificationAgent) VerifyProject(ctx context.Context, projectPath string) (*VerificationResult, error) {
 	result := &VerificationResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -72,19 +74,27 @@ func (va *VerificationAgent) VerifyProject(projectPath string) (*VerificationRes
 	}
 
 	// Install dependencies
-	depsOK, depsLog, depsErr := va.installDependencies(projectPath, projectType)
+	depsOK, depsLog, depsErr := va.installDependencies(ctx, projectPath, projectType)
 	result.DependenciesOK = depsOK
 	result.BuildLog += depsLog
 
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+
 	if depsErr != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Dependency installation failed: %v", depsErr))
 	}
 
 	// Validate syntax
-	syntaxValid, syntaxLog, syntaxErr := va.validateSyntax(projectPath, projectType)
+	syntaxValid, syntaxLog, syntaxErr := va.validateSyntax(ctx, projectPath, projectType)
 	result.SyntaxValid = syntaxValid
 	result.BuildLog += syntaxLog
 
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+
 	if syntaxErr != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Syntax validation failed: %v", syntaxErr))
 	}
@@ -198,8 +208,8 @@ func (va *VerificationAgent) checkEntryPoint(projectPath string, projectType Pro
 }
 
 // installDependencies installs project dependencies
-func (va *VerificationAgent) installDependencies(projectPath string, projectType ProjectType) (bool, string, error) {
-	var cmd *exec.Cmd
+func (va *VerificationAgent) installDependencies(ctx context.Context, projectPath string, projectType ProjectType) (bool, string, error) {
+	var command []string
 	var workDir string
 
 	switch projectType {
@@ -212,8 +222,7 @@ func (va *VerificationAgent) installDependencies(projectPath string, projectType
 		} else {
 			return false, "", fmt.Errorf("no package.json found")
 		}
-		cmd = exec.Command("npm", "install")
-		cmd.Dir = workDir
+		command = []string{"npm", "install"}
 
 	case ProjectTypePython:
 		// Try both root and backend directories
@@ -225,8 +234,7 @@ func (va *VerificationAgent) installDependencies(projectPath string, projectType
 			// No requirements.txt - not necessarily an error for simple scripts
 			return true, "No requirements.txt found - assuming no dependencies\n", nil
 		}
-		cmd = exec.Command("pip", "install", "-r", "requirements.txt")
-		cmd.Dir = workDir
+		command = []string{"pip", "install", "-r", "requirements.txt"}
 
 	case ProjectTypeGo:
 		// Try both root and backend directories
@@ -237,8 +245,7 @@ func (va *VerificationAgent) installDependencies(projectPath string, projectType
 		} else {
 			return false, "", fmt.Errorf("no go.mod found")
 		}
-		cmd = exec.Command("go", "mod", "download")
-		cmd.Dir = workDir
+		command = []string{"go", "mod", "download"}
 
 	case ProjectTypeFrontend:
 		// Frontend-only projects don't need dependency installation
@@ -249,7 +256,7 @@ func (va *VerificationAgent) installDependencies(projectPath string, projectType
 	}
 
 	// Run installation with timeout
-	output, err := va.runCommandWithTimeout(cmd, va.timeout)
+	output, err := va.runCommandWithTimeout(ctx, workDir, va.timeout, command[0], command[1:]...)
 	if err != nil {
 		return false, string(output), err
 	}
@@ -258,19 +265,19 @@ func (va *VerificationAgent) installDependencies(projectPath string, projectType
 }
 
 // validateSyntax validates code syntax
-func (va *VerificationAgent) validateSyntax(projectPath string, projectType ProjectType) (bool, string, error) {
+func (va *VerificationAgent) validateSyntax(ctx context.Context, projectPath string, projectType ProjectType) (bool, string, error) {
 	switch projectType {
 	case ProjectTypeVite:
-		return va.validateViteSyntax(projectPath)
+		return va.validateViteSyntax(ctx, projectPath)
 
 	case ProjectTypeNodeJS:
-		return va.validateNodeJSSyntax(projectPath)
+		return va.validateNodeJSSyntax(ctx, projectPath)
 
 	case ProjectTypePython:
-		return va.validatePythonSyntax(projectPath)
+		return va.validatePythonSyntax(ctx, projectPath)
 
 	case ProjectTypeGo:
-		return va.validateGoSyntax(projectPath)
+		return va.validateGoSyntax(ctx, projectPath)
 
 	case ProjectTypeFrontend:
 		return va.validateHTMLSyntax(projectPath)
@@ -281,7 +288,7 @@ func (va *VerificationAgent) validateSyntax(projectPath string, projectType Proj
 }
 
 // validateNodeJSSyntax validates Node.js syntax
-func (va *VerificationAgent) validateNodeJSSyntax(projectPath string) (bool, string, error) {
+func (va *VerificationAgent) validateNodeJSSyntax(ctx context.Context, projectPath string) (bool, string, error) {
 	// Find all .js files
 	jsFiles, err := va.findFiles(projectPath, ".js")
 	if err != nil {
@@ -297,8 +304,7 @@ func (va *VerificationAgent) validateNodeJSSyntax(projectPath string) (bool, str
 
 	// Validate each JS file
 	for _, jsFile := range jsFiles {
-		cmd := exec.Command("node", "--check", jsFile)
-		output, err := va.runCommandWithTimeout(cmd, 10*time.Second)
+		output, err := va.runCommandWithTimeout(ctx, "", 10*time.Second, "node", "--check", jsFile)
 		allOutput.WriteString(string(output))
 
 		if err != nil {
@@ -315,7 +321,7 @@ func (va *VerificationAgent) validateNodeJSSyntax(projectPath string) (bool, str
 }
 
 // validateViteSyntax validates Vite project syntax
-func (va *VerificationAgent) validateViteSyntax(projectPath string) (bool, string, error) {
+func (va *VerificationAgent) validateViteSyntax(ctx context.Context, projectPath string) (bool, string, error) {
 	// For Vite projects, we run a build check
 	// This will validate JSX/TSX syntax and React components
 	var allOutput strings.Builder
@@ -328,10 +334,7 @@ func (va *VerificationAgent) validateViteSyntax(projectPath string) (bool, strin
 
 	// Try to run vite build in check mode (doesn't actually build, just validates)
 	// We use a timeout because build can be slow
-	cmd := exec.Command("npm", "run", "build", "--", "--mode", "development")
-	cmd.Dir = projectPath
-
-	output, err := va.runCommandWithTimeout(cmd, 30*time.Second)
+	output, err := va.runCommandWithTimeout(ctx, projectPath, 30*time.Second, "npm", "run", "build", "--", "--mode", "development")
 	allOutput.WriteString(string(output))
 
 	if err != nil {
@@ -346,7 +349,7 @@ func (va *VerificationAgent) validateViteSyntax(projectPath string) (bool, strin
 }
 
 // validatePythonSyntax validates Python syntax
-func (va *VerificationAgent) validatePythonSyntax(projectPath string) (bool, string, error) {
+func (va *VerificationAgent) validatePythonSyntax(ctx context.Context, projectPath string) (bool, string, error) {
 	// Find all .py files
 	pyFiles, err := va.findFiles(projectPath, ".py")
 	if err != nil {
@@ -362,8 +365,7 @@ func (va *VerificationAgent) validatePythonSyntax(projectPath string) (bool, str
 
 	// Validate each Python file
 	for _, pyFile := range pyFiles {
-		cmd := exec.Command("python", "-m", "py_compile", pyFile)
-		output, err := va.runCommandWithTimeout(cmd, 10*time.Second)
+		output, err := va.runCommandWithTimeout(ctx, "", 10*time.Second, "python", "-m", "py_compile", pyFile)
 		allOutput.WriteString(string(output))
 
 		if err != nil {
@@ -380,7 +382,7 @@ func (va *VerificationAgent) validatePythonSyntax(projectPath string) (bool, str
 }
 
 // validateGoSyntax validates Go syntax
-func (va *VerificationAgent) validateGoSyntax(projectPath string) (bool, string, error) {
+func (va *VerificationAgent) validateGoSyntax(ctx context.Context, projectPath string) (bool, string, error) {
 	// Try both root and backend directories
 	var workDir string
 	if _, err := os.Stat(filepath.Join(projectPath, "go.mod")); err == nil {
@@ -391,10 +393,8 @@ func (va *VerificationAgent) validateGoSyntax(projectPath string) (bool, string,
 		return false, "", fmt.Errorf("no go.mod found")
 	}
 
-	cmd := exec.Command("go", "build", "-o", "nul") // Build to nul (discard output on Windows)
-	cmd.Dir = workDir
-
-	output, err := va.runCommandWithTimeout(cmd, 30*time.Second)
+	// Build to nul (discard output on Windows)
+	output, err := va.runCommandWithTimeout(ctx, workDir, 30*time.Second, "go", "build", "-o", "nul")
 	if err != nil {
 		return false, string(output), err
 	}
@@ -459,25 +459,22 @@ func (va *VerificationAgent) findFiles(root string, ext string) ([]string, error
 	return files, err
 }
 
-// runCommandWithTimeout runs a command with a timeout
-func (va *VerificationAgent) runCommandWithTimeout(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
-	// Create a context with timeout
-	done := make(chan error, 1)
-	var output []byte
-
-	go func() {
-		var err error
-		output, err = cmd.CombinedOutput()
-		done <- err
-	}()
-
-	select {
-	case <-time.After(timeout):
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
+// runCommandWithTimeout runs a command in dir with a timeout
+// The command's whole process tree is killed on timeout or when ctx is cancelled
+func (va *VerificationAgent) runCommandWithTimeout(ctx context.Context, dir string, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := validation.CommandContext(cmdCtx, name, args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return output, ctx.Err()
+	}
+	if cmdCtx.Err() == context.DeadlineExceeded {
 		return output, fmt.Errorf("command timed out after %v", timeout)
-	case err := <-done:
-		return output, err
 	}
+
+	return output, err
 }
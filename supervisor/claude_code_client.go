@@ -2,6 +2,7 @@ package supervisor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,7 +48,7 @@ type File struct {
 }
 
 // Generate sends a task to Claude Code and returns the result
-func (cc *ClaudeCodeClient) Generate(taskType, input string) (string, error) {
+func (cc *ClaudeCodeClient) Generate(ctx context.Context, taskType, input string) (string, error) {
 	req := ClaudeCodeRequest{
 		Task:  taskType,
 		Input: input,
@@ -58,11 +59,13 @@ func (cc *ClaudeCodeClient) Generate(taskType, input string) (string, error) {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := cc.client.Post(
-		cc.endpoint,
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := cc.client.Do(httpReq)
 	if err != nil {
 		return "", fmt.Errorf("claude code request failed: %w", err)
 	}
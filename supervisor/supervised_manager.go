@@ -4,6 +4,7 @@ import (
 	"ai-studio/orchestrator/config"
 	"ai-studio/orchestrator/llm"
 	"ai-studio/orchestrator/task"
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -25,6 +26,12 @@ type SupervisedTaskManager struct {
 	docsAgent         *DocumentationAgent
 }
 
+// Models of the tech stack and scope agents, which are not configurable
+const (
+	TechStackModel = "llama3:8b"
+	ScopeModel     = "mistral:7b-instruct-v0.2-q4_K_M"
+)
+
 // NewSupervisedTaskManager creates a supervised task manager
 func NewSupervisedTaskManager(baseMgr *task.Manager, cfg *config.Config, supervisorCfg *SupervisorConfig) *SupervisedTaskManager {
 	client := baseMgr.GetClient()
@@ -42,27 +49,34 @@ func NewSupervisedTaskManager(baseMgr *task.Manager, cfg *config.Config, supervi
 
 	// Initialize agents
 	if supervisorCfg.Agents.Requirements.Enabled {
-		stm.requirementsAgent = NewRequirementsAgent(client, supervisorCfg.Agents.Requirements.Model)
+		stm.requirementsAgent = NewRequirementsAgent(client, supervisorCfg.Agents.Requirements.Model, cfg.AgentGenerationOptions("requirements"))
 	}
 	if supervisorCfg.Agents.QA.Enabled {
-		stm.qaAgent = NewQAAgent(client, supervisorCfg.Agents.QA.Model)
+		stm.qaAgent = NewQAAgent(client, supervisorCfg.Agents.QA.Model, cfg.AgentGenerationOptions("qa"))
 	}
 	if supervisorCfg.Agents.Testing.Enabled {
-		stm.testingAgent = NewTestingAgent(client, supervisorCfg.Agents.Testing.Model)
+		stm.testingAgent = NewTestingAgent(client, supervisorCfg.Agents.Testing.Model, cfg.AgentGenerationOptions("testing"))
 	}
 	if supervisorCfg.Agents.Documentation.Enabled {
-		stm.docsAgent = NewDocumentationAgent(client, supervisorCfg.Agents.Documentation.Model)
+		stm.docsAgent = NewDocumentationAgent(client, supervisorCfg.Agents.Documentation.Model, cfg.AgentGenerationOptions("documentation"))
 	}
 
 	// Tech stack and scope agents (created on-demand for code tasks)
-	stm.techStackAgent = NewTechStackAgent(client, "llama3:8b")
-	stm.scopeAgent = NewScopeAgent(client, "mistral:7b-instruct-v0.2-q4_K_M")
+	stm.techStackAgent = NewTechStackAgent(client, TechStackModel, cfg.AgentGenerationOptions("techstack"))
+	stm.scopeAgent = NewScopeAgent(client, ScopeModel, cfg.AgentGenerationOptions("scope"))
 
 	return stm
 }
 
 // ExecuteTask runs the full supervised execution pipeline
-func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface{}, error) {
+func (stm *SupervisedTaskManager) ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error) {
+	return stm.ExecuteTaskStream(ctx, taskType, input, nil)
+}
+
+// ExecuteTaskStream runs the supervised pipeline, streaming the main model output to onToken
+// Claude Code execution is not streamed
+// Cancelling ctx stops the pipeline at the current stage and returns ctx.Err()
+func (stm *SupervisedTaskManager) ExecuteTaskStream(ctx context.Context, taskType, input string, onToken llm.TokenHandler) (interface{}, error) {
 	startTime := time.Now()
 
 	result := &SupervisedResult{
@@ -70,9 +84,19 @@ func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface
 		AgentDurations: make(map[string]float64),
 	}
 
+	// Count LLM cache hits/misses for every model call made by this pipeline
+	cacheStats := &llm.CacheStats{}
+	ctx = llm.WithCacheStats(ctx, cacheStats)
+	defer recordCacheStats(result, cacheStats)
+
+	// Account token usage and latency across the whole pipeline
+	meter := &llm.UsageMeter{}
+	ctx = llm.WithUsageMeter(ctx, meter)
+	defer func() { result.TotalUsage = meteredUsage(meter) }()
+
 	// Phase 1: Pre-execution quality gates (only if enabled)
 	if stm.cfg.Enabled {
-		if err := stm.runQualityGates(taskType, input, result); err != nil {
+		if err := stm.runQualityGates(ctx, taskType, input, result); err != nil {
 			result.Error = err.Error()
 			result.TotalDuration = time.Since(startTime).Seconds()
 			// Return full SupervisedResult even on error
@@ -93,12 +117,13 @@ func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface
 	var err error
 
 	if complexity.RecommendedRoute == "claude_code" && stm.claudeCodeClient != nil {
-		baseResult, err = stm.executeWithClaudeCode(taskType, input)
-		if err != nil {
+		task.ReportProgress(ctx, "generating", "claude-code")
+		baseResult, err = stm.executeWithClaudeCode(ctx, taskType, input)
+		if err != nil && ctx.Err() == nil {
 			log.Printf("⚠️  Claude Code execution failed, falling back to Ollama: %v", err)
 			// Reset error and try Ollama with thinking mode
 			err = nil
-			execResult, execErr := stm.baseManager.ExecuteTaskWithThinking(taskType, input, complexity.ThinkingMode)
+			execResult, execErr := stm.baseManager.ExecuteTaskStream(ctx, taskType, input, complexity.ThinkingMode, onToken)
 			err = execErr
 			if execErr == nil {
 				var ok bool
@@ -111,7 +136,7 @@ func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface
 			}
 		}
 	} else {
-		execResult, execErr := stm.baseManager.ExecuteTaskWithThinking(taskType, input, complexity.ThinkingMode)
+		execResult, execErr := stm.baseManager.ExecuteTaskStream(ctx, taskType, input, complexity.ThinkingMode, onToken)
 		err = execErr
 		if execErr == nil {
 			// Type assert the result back to *task.Result
@@ -134,7 +159,12 @@ func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface
 
 	// Phase 4: Post-execution agents (only if enabled)
 	if stm.cfg.Enabled {
-		stm.runPostExecutionAgents(taskType, input, baseResult.Output, result)
+		stm.runPostExecutionAgents(ctx, taskType, input, baseResult.Output, result)
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			result.TotalDuration = time.Since(startTime).Seconds()
+			return result, ctx.Err()
+		}
 	}
 
 	result.TotalDuration = time.Since(startTime).Seconds()
@@ -143,14 +173,24 @@ func (stm *SupervisedTaskManager) ExecuteTask(taskType, input string) (interface
 	return result, nil
 }
 
+// recordCacheStats reports LLM cache hits and misses alongside the agent durations
+func recordCacheStats(result *SupervisedResult, stats *llm.CacheStats) {
+	if stats.Hits()+stats.Misses() == 0 {
+		return
+	}
+	result.AgentDurations["cache_hits"] = float64(stats.Hits())
+	result.AgentDurations["cache_misses"] = float64(stats.Misses())
+}
+
 // runQualityGates executes pre-execution quality gates
-func (stm *SupervisedTaskManager) runQualityGates(taskType, input string, result *SupervisedResult) error {
+func (stm *SupervisedTaskManager) runQualityGates(ctx context.Context, taskType, input string, result *SupervisedResult) error {
 	context := make(map[string]interface{})
 
 	// Gate 1: Requirements check
 	if stm.cfg.QualityGates.RequirementsCheck && stm.requirementsAgent != nil {
 		log.Printf("Running requirements check...")
-		reqOutput, err := stm.requirementsAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "requirements", "")
+		reqOutput, err := stm.requirementsAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			return fmt.Errorf("requirements check failed: %w", err)
 		}
@@ -171,7 +211,8 @@ func (stm *SupervisedTaskManager) runQualityGates(taskType, input string, result
 	// Gate 2: Tech stack approval (code tasks only)
 	if stm.cfg.QualityGates.TechStackApproval && taskType == "code" {
 		log.Printf("Running tech stack approval...")
-		tsOutput, err := stm.techStackAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "techstack", "")
+		tsOutput, err := stm.techStackAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			return fmt.Errorf("tech stack approval failed: %w", err)
 		}
@@ -192,7 +233,8 @@ func (stm *SupervisedTaskManager) runQualityGates(taskType, input string, result
 	// Gate 3: Scope validation
 	if stm.cfg.QualityGates.ScopeValidation {
 		log.Printf("Running scope validation...")
-		scopeOutput, err := stm.scopeAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "scope", "")
+		scopeOutput, err := stm.scopeAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			return fmt.Errorf("scope validation failed: %w", err)
 		}
@@ -215,7 +257,7 @@ func (stm *SupervisedTaskManager) runQualityGates(taskType, input string, result
 }
 
 // runPostExecutionAgents executes QA, testing, and documentation agents
-func (stm *SupervisedTaskManager) runPostExecutionAgents(taskType, input, output string, result *SupervisedResult) {
+func (stm *SupervisedTaskManager) runPostExecutionAgents(ctx context.Context, taskType, input, output string, result *SupervisedResult) {
 	context := map[string]interface{}{
 		"output": output,
 	}
@@ -223,7 +265,8 @@ func (stm *SupervisedTaskManager) runPostExecutionAgents(taskType, input, output
 	// QA Review
 	if stm.qaAgent != nil {
 		log.Printf("Running QA review...")
-		qaOutput, err := stm.qaAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "qa", "")
+		qaOutput, err := stm.qaAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			log.Printf("QA agent failed: %v", err)
 		} else {
@@ -235,7 +278,8 @@ func (stm *SupervisedTaskManager) runPostExecutionAgents(taskType, input, output
 	// Testing
 	if stm.testingAgent != nil && taskType == "code" {
 		log.Printf("Generating test plan...")
-		testOutput, err := stm.testingAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "testing", "")
+		testOutput, err := stm.testingAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			log.Printf("Testing agent failed: %v", err)
 		} else {
@@ -247,7 +291,8 @@ func (stm *SupervisedTaskManager) runPostExecutionAgents(taskType, input, output
 	// Documentation
 	if stm.docsAgent != nil {
 		log.Printf("Generating documentation...")
-		docsOutput, err := stm.docsAgent.Execute(taskType, input, context)
+		task.ReportProgress(ctx, "documentation", "")
+		docsOutput, err := stm.docsAgent.Execute(ctx, taskType, input, context)
 		if err != nil {
 			log.Printf("Documentation agent failed: %v", err)
 		} else {
@@ -258,10 +303,10 @@ func (stm *SupervisedTaskManager) runPostExecutionAgents(taskType, input, output
 }
 
 // executeWithClaudeCode routes execution to Claude Code
-func (stm *SupervisedTaskManager) executeWithClaudeCode(taskType, input string) (*task.Result, error) {
+func (stm *SupervisedTaskManager) executeWithClaudeCode(ctx context.Context, taskType, input string) (*task.Result, error) {
 	start := time.Now()
 
-	output, err := stm.claudeCodeClient.Generate(taskType, input)
+	output, err := stm.claudeCodeClient.Generate(ctx, taskType, input)
 	if err != nil {
 		return nil, fmt.Errorf("claude code execution failed: %w", err)
 	}
@@ -294,16 +339,31 @@ func (stm *SupervisedTaskManager) Ping() error {
 	return nil
 }
 
-// GetHistory delegates to base manager
-func (stm *SupervisedTaskManager) GetHistory(taskType string) []task.Result {
-	return stm.baseManager.GetHistory(taskType)
+// QueryHistory delegates to base manager
+func (stm *SupervisedTaskManager) QueryHistory(q task.HistoryQuery) (task.HistoryPage, error) {
+	return stm.baseManager.QueryHistory(q)
+}
+
+// QueryArtifacts delegates to base manager
+func (stm *SupervisedTaskManager) QueryArtifacts(q task.ArtifactQuery) []task.Artifact {
+	return stm.baseManager.QueryArtifacts(q)
 }
 
 // GetClient returns LLM client for chat
-func (stm *SupervisedTaskManager) GetClient() *llm.Client {
+func (stm *SupervisedTaskManager) GetClient() llm.Provider {
 	return stm.baseManager.GetClient()
 }
 
+// GetBaseManager returns the unsupervised task manager, for calls that skip the agent pipeline
+func (stm *SupervisedTaskManager) GetBaseManager() *task.Manager {
+	return stm.baseManager
+}
+
+// GetConfig returns the orchestrator configuration of the base manager
+func (stm *SupervisedTaskManager) GetConfig() *config.Config {
+	return stm.baseManager.GetConfig()
+}
+
 // GetRequirementsAgent returns the requirements agent
 func (stm *SupervisedTaskManager) GetRequirementsAgent() *RequirementsAgent {
 	return stm.requirementsAgent
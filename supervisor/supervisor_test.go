@@ -48,17 +48,17 @@ func TestRequirementsAgentParseStatus(t *testing.T) {
 	}{
 		{
 			name:     "Complete status",
-			response: "## Status\nCOMPLETE",
+			response: "COMPLETE",
 			expected: "passed",
 		},
 		{
 			name:     "Incomplete status",
-			response: "The requirements are INCOMPLETE",
+			response: "INCOMPLETE",
 			expected: "failed",
 		},
 		{
 			name:     "Needs clarification",
-			response: "Status: NEEDS_CLARIFICATION",
+			response: "NEEDS_CLARIFICATION",
 			expected: "warning",
 		},
 	}
@@ -107,12 +107,12 @@ func TestTechStackAgentParseStatus(t *testing.T) {
 	}{
 		{
 			name:     "Approved",
-			response: "## Verdict\nAPPROVED",
+			response: "APPROVED",
 			expected: "passed",
 		},
 		{
 			name:     "Rejected",
-			response: "This stack is REJECTED due to complexity",
+			response: "REJECTED",
 			expected: "failed",
 		},
 		{
@@ -166,17 +166,17 @@ func TestScopeAgentParseStatus(t *testing.T) {
 	}{
 		{
 			name:     "Appropriate scope",
-			response: "This scope is APPROPRIATE",
+			response: "APPROPRIATE",
 			expected: "passed",
 		},
 		{
 			name:     "Too broad",
-			response: "The scope is TOO_BROAD",
+			response: "TOO_BROAD",
 			expected: "failed",
 		},
 		{
 			name:     "Too narrow",
-			response: "TOO_NARROW - add more features",
+			response: "TOO_NARROW",
 			expected: "warning",
 		},
 	}
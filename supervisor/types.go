@@ -1,7 +1,11 @@
 package supervisor
 
 import (
+	"ai-studio/orchestrator/llm"
 	"ai-studio/orchestrator/task"
+	"context"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -48,6 +52,7 @@ type SupervisedResult struct {
 	Documentation        *AgentOutput       `json:"documentation,omitempty"`
 	TotalDuration        float64            `json:"total_duration_seconds"`
 	AgentDurations       map[string]float64 `json:"agent_durations"`
+	TotalUsage           *llm.Usage         `json:"total_usage,omitempty"` // LLM usage of the whole pipeline (gates, main task, post agents)
 }
 
 // AgentOutput represents the result from a single agent
@@ -57,9 +62,25 @@ type AgentOutput struct {
 	Output    string                 `json:"output"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Duration  float64                `json:"duration_seconds"`
+	Usage     *llm.Usage             `json:"usage,omitempty"` // Tokens and timings of the agent's LLM calls
 	Timestamp time.Time              `json:"timestamp"`
 }
 
+// meteredUsage returns the usage recorded by meter, or nil if it saw no calls
+func meteredUsage(meter *llm.UsageMeter) *llm.Usage {
+	usage := meter.Total()
+	if usage.IsZero() {
+		return nil
+	}
+	return &usage
+}
+
+// reportMarkdown appends an agent's structured status to its markdown report as a final section,
+// so the Lead Agent and humans read one document
+func reportMarkdown(report, heading, status string) string {
+	return fmt.Sprintf("%s\n\n## %s\n%s", strings.TrimSpace(report), heading, status)
+}
+
 // ComplexityAnalysis holds scoring details
 type ComplexityAnalysis struct {
 	Score            int            `json:"score"` // 1-10
@@ -71,7 +92,7 @@ type ComplexityAnalysis struct {
 
 // Agent interface that all agents must implement
 type Agent interface {
-	Execute(taskType, input string, context map[string]interface{}) (*AgentOutput, error)
+	Execute(ctx context.Context, taskType, input string, context map[string]interface{}) (*AgentOutput, error)
 	Name() string
 	RequiresInput() bool // Some agents run pre-execution, some post
 }
@@ -0,0 +1,271 @@
+package task
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeneratedProjectsDir is where code tasks write the projects they generate
+const GeneratedProjectsDir = "projects"
+
+// Artifact describes one saved task result: its Markdown record, the project
+// directory a code task generated or patched, and the files written there
+type Artifact struct {
+	ID           string         `json:"id"`
+	TaskType     string         `json:"task_type"`
+	Model        string         `json:"model,omitempty"`
+	ProjectID    string         `json:"project_id,omitempty"` // Orchestrator project the task ran for
+	Path         string         `json:"path"`                 // Markdown record of the task
+	ThinkingPath string         `json:"thinking_path,omitempty"`
+	GeneratedDir string         `json:"generated_dir,omitempty"` // Project directory written or patched by a code task
+	Patched      bool           `json:"patched,omitempty"`       // GeneratedDir existed and was patched
+	Files        []ArtifactFile `json:"files,omitempty"`         // Files written to GeneratedDir
+	Note         string         `json:"note,omitempty"`          // Why a code task left no project, or its patch failed
+	InputHash    string         `json:"input_sha256"`
+	OutputHash   string         `json:"output_sha256"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// ArtifactFile is one file written by a code task
+type ArtifactFile struct {
+	Path   string `json:"path"` // Relative to the generated directory
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ArtifactQuery selects indexed artifacts; zero fields match everything
+type ArtifactQuery struct {
+	ID        string
+	ProjectID string
+	TaskType  string
+	Since     time.Time
+	Limit     int // 0 = no limit
+}
+
+// ArtifactStore saves task artifacts under collision-free IDs.
+// Each artifact gets <id>.md and an <id>.json metadata sidecar; index.jsonl lists
+// every artifact and is rebuilt from the sidecars if it goes missing.
+type ArtifactStore struct {
+	dir string
+
+	mu        sync.RWMutex
+	index     *os.File
+	artifacts []Artifact // In save order
+}
+
+// OpenArtifactStore opens (or creates) the artifact store in dir
+func OpenArtifactStore(dir string) (*ArtifactStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	indexPath := filepath.Join(dir, "index.jsonl")
+	_, statErr := os.Stat(indexPath)
+	rebuild := errors.Is(statErr, os.ErrNotExist)
+
+	file, err := os.OpenFile(indexPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact index: %w", err)
+	}
+
+	s := &ArtifactStore{dir: dir, index: file}
+	if rebuild {
+		err = s.rebuild()
+	} else {
+		err = s.load()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the index, skipping lines that can't be parsed
+// A final line cut short by a crash is removed so appends start on a fresh line.
+func (s *ArtifactStore) load() error {
+	reader := bufio.NewReader(s.index)
+	var offset int64
+	skipped := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				skipped++
+				if err := s.index.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to repair artifact index: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read artifact index: %w", err)
+		}
+		offset += int64(len(line))
+
+		var a Artifact
+		if json.Unmarshal(line, &a) != nil || a.ID == "" {
+			skipped++
+			continue
+		}
+		s.artifacts = append(s.artifacts, a)
+	}
+
+	if skipped > 0 {
+		log.Printf("Warning: skipped %d unreadable record(s) in artifact index", skipped)
+	}
+	return nil
+}
+
+// rebuild recreates the index from the metadata sidecars
+func (s *ArtifactStore) rebuild() error {
+	sidecars, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range sidecars {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var a Artifact
+		if json.Unmarshal(data, &a) != nil || a.ID == "" || a.Path == "" {
+			continue // Not a sidecar
+		}
+		s.artifacts = append(s.artifacts, a)
+	}
+	sort.SliceStable(s.artifacts, func(i, j int) bool { return s.artifacts[i].CreatedAt.Before(s.artifacts[j].CreatedAt) })
+
+	for _, a := range s.artifacts {
+		if err := s.appendIndex(&a); err != nil {
+			return err
+		}
+	}
+	if len(s.artifacts) > 0 {
+		log.Printf("✓ Rebuilt artifact index from %d sidecar(s)", len(s.artifacts))
+	}
+	return nil
+}
+
+var unsafeIDChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// Create reserves a new artifact ID and creates its empty Markdown file,
+// returning the artifact to fill in and Save
+func (s *ArtifactStore) Create(taskType string, createdAt time.Time) (*Artifact, error) {
+	prefix := unsafeIDChars.ReplaceAllString(strings.ToLower(taskType), "_")
+	if prefix == "" {
+		prefix = "task"
+	}
+
+	// The file is created exclusively, so an ID is never handed out twice
+	for attempt := 0; attempt < 5; attempt++ {
+		id := fmt.Sprintf("%s_%s_%s", prefix, createdAt.Format("20060102-150405"), uuid.New().String()[:8])
+		path := filepath.Join(s.dir, id+".md")
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create artifact: %w", err)
+		}
+		file.Close()
+		return &Artifact{ID: id, TaskType: taskType, Path: path, CreatedAt: createdAt}, nil
+	}
+	return nil, fmt.Errorf("failed to allocate a unique artifact ID")
+}
+
+// GeneratedDirFor returns a new directory path for the project generated by an artifact
+func GeneratedDirFor(a *Artifact) string {
+	return filepath.Join(GeneratedProjectsDir, a.ID)
+}
+
+// Save writes the artifact's metadata sidecar and adds it to the index
+func (s *ArtifactStore) Save(a *Artifact) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, a.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.appendIndex(a); err != nil {
+		return err
+	}
+	s.artifacts = append(s.artifacts, *a)
+	return nil
+}
+
+// appendIndex adds one line to index.jsonl; callers hold the lock (or own the store)
+func (s *ArtifactStore) appendIndex(a *Artifact) error {
+	line, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact metadata: %w", err)
+	}
+	if _, err := s.index.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write artifact index: %w", err)
+	}
+	return nil
+}
+
+// Query returns the artifacts matching q, newest first
+func (s *ArtifactStore) Query(q ArtifactQuery) []Artifact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []Artifact{}
+	for i := len(s.artifacts) - 1; i >= 0; i-- {
+		a := s.artifacts[i]
+		if (q.ID != "" && a.ID != q.ID) ||
+			(q.ProjectID != "" && a.ProjectID != q.ProjectID) ||
+			(q.TaskType != "" && q.TaskType != "all" && a.TaskType != q.TaskType) ||
+			(!q.Since.IsZero() && a.CreatedAt.Before(q.Since)) {
+			continue
+		}
+		results = append(results, a)
+		if q.Limit > 0 && len(results) == q.Limit {
+			break
+		}
+	}
+	return results
+}
+
+// Close closes the index file
+func (s *ArtifactStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Close()
+}
+
+// artifactFiles describes written files, hashing their content
+func artifactFiles(files []FileContent) []ArtifactFile {
+	out := make([]ArtifactFile, 0, len(files))
+	for _, f := range files {
+		out = append(out, ArtifactFile{Path: f.Path, Size: len(f.Content), SHA256: hashString(f.Content)})
+	}
+	return out
+}
+
+// hashString returns the hex SHA-256 of s
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
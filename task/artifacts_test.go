@@ -0,0 +1,56 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestArtifactStore tests unique IDs within the same second, queries and rebuilding a lost index
+func TestArtifactStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenArtifactStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		taskType, project := "code", "p1"
+		if i%2 == 1 {
+			taskType, project = "review", "p2"
+		}
+		a, err := store.Create(taskType, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[a.ID] {
+			t.Fatalf("duplicate artifact ID %s", a.ID)
+		}
+		seen[a.ID] = true
+		a.ProjectID = project
+		if err := store.Save(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := store.Query(ArtifactQuery{ProjectID: "p1", TaskType: "code", Limit: 3}); len(got) != 3 || got[0].TaskType != "code" {
+		t.Errorf("query = %+v", got)
+	}
+	store.Close()
+
+	// Without its index, the store is rebuilt from the sidecars
+	if err := os.Remove(filepath.Join(dir, "index.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	store, err = OpenArtifactStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if got := store.Query(ArtifactQuery{ProjectID: "p2"}); len(got) != 10 {
+		t.Errorf("rebuilt index has %d artifacts for p2, want 10", len(got))
+	}
+}
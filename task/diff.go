@@ -0,0 +1,152 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around each change in generated diffs
+const diffContext = 3
+
+// maxDiffCells bounds the line-matching table; larger files are diffed as a whole replacement
+const maxDiffCells = 4 << 20
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the unified diff turning before into after for the file at path
+// existed and exists say whether the file is there before and after, for /dev/null headers;
+// identical contents give "".
+func unifiedDiff(path, before, after string, existed, exists bool) string {
+	if before == after && existed == exists {
+		return ""
+	}
+
+	oldName, newName := "a/"+path, "b/"+path
+	if !existed {
+		oldName = "/dev/null"
+	}
+	if !exists {
+		newName = "/dev/null"
+	}
+
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Group changes closer than twice the context into one hunk
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		from := max(start-diffContext, 0)
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i
+			} else if i-end > 2*diffContext {
+				break
+			}
+		}
+		to := min(end+diffContext+1, len(ops))
+
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		oldLen, newLen := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		if oldLen == 0 {
+			oldStart--
+		}
+		if newLen == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range ops[from:to] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			b.WriteByte('\n')
+		}
+		start = to
+	}
+
+	return b.String()
+}
+
+// splitLines splits content into lines, without the final newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns an edit script from a to b following their longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	ops := make([]diffOp, 0, n+m)
+
+	if (n+1)*(m+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i*(m+1)+j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"ai-studio/orchestrator/llm"
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -9,6 +10,9 @@ import (
 	"time"
 )
 
+// DiscoveryModel generates discovery questions and scores the answers
+const DiscoveryModel = "mistral:7b-instruct-v0.2-q4_K_M"
+
 // DiscoverQuestions are the default/fallback validation questions
 var DiscoverQuestions = []string{
 	"What makes it different from existing solutions?",
@@ -20,34 +24,37 @@ var DiscoverQuestions = []string{
 type DiscoverSession struct {
 	ID        string    `json:"id"`
 	RawIdea   string    `json:"raw_idea"`
-	Questions []string  `json:"questions"`   // Dynamic AI-generated questions
+	Questions []string  `json:"questions"`   // Questions asked so far; Questions[i] is answered by Answers[i]
+	Planned   []string  `json:"planned,omitempty"` // Generated questions not asked yet; follow-ups may skip them
 	IdeaType  string    `json:"idea_type"`   // Detected category (game, app, saas, etc.)
 	Answers   []string  `json:"answers"`
 	Verdict   string    `json:"verdict"`     // "GO", "REFINE", "PASS", ""
 	Reasoning string    `json:"reasoning"`
 	Status    string    `json:"status"`      // "answering", "complete"
+	Confidence int      `json:"confidence,omitempty"` // Model's confidence (0-100) that the answers support a verdict
+	Archived  bool      `json:"archived,omitempty"` // Hidden from the default history listing
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ResearchModule generates tailored discovery questions
 type ResearchModule struct {
-	client *llm.Client
+	client llm.Provider
 }
 
 // NewResearchModule creates a new research module
-func NewResearchModule(client *llm.Client) *ResearchModule {
+func NewResearchModule(client llm.Provider) *ResearchModule {
 	return &ResearchModule{client: client}
 }
 
 // GenerateQuestions analyzes the idea and generates category-specific questions
-func (rm *ResearchModule) GenerateQuestions(rawIdea string) ([]string, string, error) {
+func (rm *ResearchModule) GenerateQuestions(ctx context.Context, rawIdea string) ([]string, string, error) {
 	prompt := rm.buildQuestionPrompt(rawIdea)
 
 	log.Printf("Generating dynamic questions for idea: %s", rawIdea[:min(len(rawIdea), 50)]+"...")
 
 	// Use Mistral model for question generation (fast + free)
-	response, err := rm.client.Generate("mistral:7b-instruct-v0.2-q4_K_M", prompt)
+	response, err := llm.Generate(ctx, rm.client, DiscoveryModel, prompt)
 	if err != nil {
 		log.Printf("Question generation failed, using defaults: %v", err)
 		return getDefaultQuestions(), "unknown", nil
@@ -157,129 +164,173 @@ func getDefaultQuestions() []string {
 }
 
 // DiscoverManager handles discovery sessions
+// Sessions are saved to dir as they change and reloaded on start, so they survive restarts.
 type DiscoverManager struct {
 	sessions       map[string]*DiscoverSession
 	sessionsMux    sync.RWMutex
-	client         *llm.Client
+	dir            string // "" keeps sessions in memory only
+	answering      map[string]bool // Sessions whose latest answer is being processed
+	options        DiscoverOptions
+	client         llm.Provider
 	researchModule *ResearchModule
 }
 
-// NewDiscoverManager creates a new discovery manager
-func NewDiscoverManager(client *llm.Client) *DiscoverManager {
+// NewDiscoverManager creates a new discovery manager, loading the sessions saved in dir
+func NewDiscoverManager(client llm.Provider, dir string) *DiscoverManager {
 	return &DiscoverManager{
-		sessions:       make(map[string]*DiscoverSession),
+		sessions:       loadDiscoverSessions(dir),
+		dir:            dir,
+		answering:      make(map[string]bool),
 		client:         client,
 		researchModule: NewResearchModule(client),
 	}
 }
 
-// StartSession creates a new discovery session with dynamic questions
-func (dm *DiscoverManager) StartSession(rawIdea string) *DiscoverSession {
+// SetOptions sets the question bounds for sessions; zero fields keep the defaults
+func (dm *DiscoverManager) SetOptions(opts DiscoverOptions) {
 	dm.sessionsMux.Lock()
 	defer dm.sessionsMux.Unlock()
+	dm.options = opts.normalized()
+}
 
-	// Generate tailored questions based on idea type
-	questions, ideaType, err := dm.researchModule.GenerateQuestions(rawIdea)
+// StartSession creates a new discovery session
+// The generated questions are a plan: the first is asked now, and each answer decides what follows.
+func (dm *DiscoverManager) StartSession(ctx context.Context, rawIdea string) *DiscoverSession {
+	// Generate tailored questions based on idea type (outside the lock; this calls the model)
+	questions, ideaType, err := dm.researchModule.GenerateQuestions(ctx, rawIdea)
 	if err != nil {
 		// Use default questions on error
 		questions = getDefaultQuestions()
 		ideaType = "unknown"
 	}
 
+	dm.sessionsMux.Lock()
+	defer dm.sessionsMux.Unlock()
+
 	session := &DiscoverSession{
-		ID:        fmt.Sprintf("discover_%d", time.Now().Unix()),
+		ID:        newDiscoverID(),
 		RawIdea:   rawIdea,
-		Questions: questions,
+		Questions: questions[:1],
+		Planned:   questions[1:],
 		IdeaType:  ideaType,
-		Answers:   make([]string, 0, len(questions)),
+		Answers:   []string{},
 		Status:    "answering",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	dm.sessions[session.ID] = session
-	return session
+	dm.persist(session)
+	return session.clone()
 }
 
-// AddAnswer adds an answer to the session
-func (dm *DiscoverManager) AddAnswer(sessionID, answer string) (*DiscoverSession, error) {
+// AddAnswer answers the session's current question, then asks the next one or scores the session
+// The model calls run outside the lock; a session takes one answer at a time.
+func (dm *DiscoverManager) AddAnswer(ctx context.Context, sessionID, answer string) (*DiscoverSession, error) {
 	dm.sessionsMux.Lock()
-	defer dm.sessionsMux.Unlock()
-
 	session := dm.sessions[sessionID]
 	if session == nil {
+		dm.sessionsMux.Unlock()
 		return nil, fmt.Errorf("session not found")
 	}
-
 	if session.Status == "complete" {
+		dm.sessionsMux.Unlock()
 		return nil, fmt.Errorf("session already complete")
 	}
-
+	if dm.answering[sessionID] {
+		dm.sessionsMux.Unlock()
+		return nil, fmt.Errorf("previous answer is still being processed")
+	}
 	if len(session.Answers) >= len(session.Questions) {
+		dm.sessionsMux.Unlock()
 		return nil, fmt.Errorf("all questions already answered")
 	}
 
 	session.Answers = append(session.Answers, answer)
 	session.UpdatedAt = time.Now()
+	dm.answering[sessionID] = true
+	snapshot := *session
+	opts := dm.options
+	dm.sessionsMux.Unlock()
+
+	// Sessions started with every question up front keep asking them
+	step := discoverStep{Planned: snapshot.Planned, Confidence: snapshot.Confidence}
+	if len(snapshot.Answers) >= len(snapshot.Questions) {
+		step = dm.researchModule.NextQuestion(ctx, &snapshot, opts)
+	}
 
-	// Check if all questions answered
-	if len(session.Answers) >= len(session.Questions) {
-		dm.scoreSession(session)
+	var verdict, reasoning string
+	pending := len(snapshot.Answers) < len(snapshot.Questions)
+	if !pending && step.Question == "" {
+		verdict, reasoning = dm.scoreWithLLM(ctx, &snapshot)
 	}
 
-	return session, nil
+	dm.sessionsMux.Lock()
+	defer dm.sessionsMux.Unlock()
+	delete(dm.answering, sessionID)
+	if dm.sessions[sessionID] != session {
+		return nil, fmt.Errorf("session was deleted")
+	}
+
+	session.Planned = step.Planned
+	session.Confidence = step.Confidence
+	if step.Question != "" {
+		session.Questions = append(session.Questions, step.Question)
+	} else if !pending {
+		session.Status = "complete"
+		session.Verdict = verdict
+		session.Reasoning = reasoning
+	}
+	session.UpdatedAt = time.Now()
+	dm.persist(session)
+
+	return session.clone(), nil
 }
 
-// GetSession retrieves a session by ID
+// GetSession returns a copy of a session, or nil if there is none
 func (dm *DiscoverManager) GetSession(sessionID string) *DiscoverSession {
 	dm.sessionsMux.RLock()
 	defer dm.sessionsMux.RUnlock()
-	return dm.sessions[sessionID]
+
+	session := dm.sessions[sessionID]
+	if session == nil {
+		return nil
+	}
+	return session.clone()
 }
 
-// GetAllSessions returns all discovery sessions
+// GetAllSessions returns copies of all discovery sessions
 func (dm *DiscoverManager) GetAllSessions() []*DiscoverSession {
 	dm.sessionsMux.RLock()
 	defer dm.sessionsMux.RUnlock()
 
 	sessions := make([]*DiscoverSession, 0, len(dm.sessions))
 	for _, session := range dm.sessions {
-		sessions = append(sessions, session)
+		sessions = append(sessions, session.clone())
 	}
 	return sessions
 }
 
-// scoreSession determines verdict based on answers
-func (dm *DiscoverManager) scoreSession(session *DiscoverSession) {
-	session.Status = "complete"
-
-	// Try LLM-based scoring first (primary)
-	verdict, reasoning := dm.scoreWithLLM(session)
-
-	session.Verdict = verdict
-	session.Reasoning = reasoning
+// discoverVerdict is the structured response to the scoring prompt
+type discoverVerdict struct {
+	Verdict   string `json:"verdict" enum:"GO,REFINE,PASS"`
+	Reasoning string `json:"reasoning"`
 }
 
 // scoreWithLLM uses LLM to intelligently score answers
-func (dm *DiscoverManager) scoreWithLLM(session *DiscoverSession) (string, string) {
+// Rule-based scoring is used when the model is unavailable or never returns a valid verdict
+func (dm *DiscoverManager) scoreWithLLM(ctx context.Context, session *DiscoverSession) (string, string) {
 	prompt := dm.buildScoringPrompt(session)
 
 	// Use mistral model for scoring (same as validate task)
-	response, err := dm.client.Generate("mistral:7b-instruct-v0.2-q4_K_M", prompt)
+	var verdict discoverVerdict
+	err := llm.GenerateStructured(ctx, dm.client, DiscoveryModel, prompt, &verdict, llm.StructuredOptions{})
 	if err != nil {
-		// Fallback to basic scoring
-		return dm.scoreBasic(session)
-	}
-
-	// Parse LLM response for verdict
-	verdict, reasoning := dm.parseVerdictResponse(response)
-
-	// If parsing failed, use fallback
-	if verdict == "" {
+		log.Printf("Discovery scoring failed for session %s, using rule-based scoring: %v", session.ID, err)
 		return dm.scoreBasic(session)
 	}
 
-	return verdict, reasoning
+	return verdict.Verdict, verdict.Reasoning
 }
 
 // scoreBasic provides fallback rule-based scoring
@@ -309,9 +360,9 @@ func (dm *DiscoverManager) scoreBasic(session *DiscoverSession) (string, string)
 func (dm *DiscoverManager) buildScoringPrompt(session *DiscoverSession) string {
 	// Build Q&A pairs dynamically
 	var qaSection strings.Builder
-	for i, question := range session.Questions {
-		qaSection.WriteString(fmt.Sprintf("\nQuestion %d: %s\n", i+1, question))
-		qaSection.WriteString(fmt.Sprintf("Answer: %s\n", session.Answers[i]))
+	for i, answer := range session.Answers {
+		qaSection.WriteString(fmt.Sprintf("\nQuestion %d: %s\n", i+1, session.Questions[i]))
+		qaSection.WriteString(fmt.Sprintf("Answer: %s\n", answer))
 	}
 
 	categoryContext := ""
@@ -330,64 +381,14 @@ Based on these answers, provide a verdict:
 - REFINE: Decent answers but need more detail or clarity.
 - PASS: Weak answers, idea needs more thought.
 
-Respond in this exact format:
-VERDICT: [GO|REFINE|PASS]
-REASONING: [2-3 sentence explanation]`,
+Respond with:
+- verdict: GO, REFINE or PASS
+- reasoning: a 2-3 sentence explanation`,
 		session.RawIdea,
 		categoryContext,
 		qaSection.String())
 }
 
-// parseVerdictResponse extracts verdict and reasoning from LLM response
-func (dm *DiscoverManager) parseVerdictResponse(response string) (string, string) {
-	lines := strings.Split(response, "\n")
-	verdict := ""
-	reasoning := ""
-
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "VERDICT:") {
-			verdict = strings.TrimSpace(strings.TrimPrefix(line, "VERDICT:"))
-			// Normalize verdict to uppercase
-			verdict = strings.ToUpper(verdict)
-
-			// Validate verdict
-			if verdict != "GO" && verdict != "REFINE" && verdict != "PASS" {
-				verdict = "REFINE" // Default fallback
-			}
-		} else if strings.HasPrefix(line, "REASONING:") {
-			// Collect reasoning (may span multiple lines)
-			reasoning = strings.TrimSpace(strings.TrimPrefix(line, "REASONING:"))
-			// Check if reasoning continues on next lines
-			for j := i + 1; j < len(lines); j++ {
-				nextLine := strings.TrimSpace(lines[j])
-				if nextLine != "" && !strings.Contains(nextLine, ":") {
-					reasoning += " " + nextLine
-				} else {
-					break
-				}
-			}
-		}
-	}
-
-	// If no reasoning found, provide default
-	if reasoning == "" {
-		switch verdict {
-		case "GO":
-			reasoning = "The answers demonstrate a clear understanding of the product vision and market opportunity."
-		case "REFINE":
-			reasoning = "The answers show potential but could benefit from more specific details and deeper analysis."
-		case "PASS":
-			reasoning = "The answers need significant improvement before moving forward with implementation."
-		default:
-			reasoning = "Unable to fully evaluate the responses."
-		}
-	}
-
-	return verdict, reasoning
-}
-
 // GetCurrentQuestion returns the next question to ask based on session state
 func (dm *DiscoverManager) GetCurrentQuestion(session *DiscoverSession) (int, string) {
 	questionNum := len(session.Answers)
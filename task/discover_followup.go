@@ -0,0 +1,198 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"ai-studio/orchestrator/llm"
+)
+
+// Question bounds of a discovery session, and the confidence at which it stops early
+const (
+	DefaultMinDiscoverQuestions = 3
+	DefaultMaxDiscoverQuestions = 6
+	discoverStopConfidence      = 80
+)
+
+// vagueAnswerWords is the length below which the rule-based fallback asks for more detail
+const vagueAnswerWords = 8
+
+// probePrefix starts the rule-based follow-up to a vague answer
+const probePrefix = "Could you be more specific?"
+
+// DiscoverOptions bound how many questions a discovery session asks
+type DiscoverOptions struct {
+	MinQuestions int // Questions asked before the session may stop early
+	MaxQuestions int // The session is scored once this many are answered
+}
+
+// normalized fills in defaults and keeps the bounds consistent
+func (o DiscoverOptions) normalized() DiscoverOptions {
+	if o.MinQuestions <= 0 {
+		o.MinQuestions = DefaultMinDiscoverQuestions
+	}
+	if o.MaxQuestions <= 0 {
+		o.MaxQuestions = DefaultMaxDiscoverQuestions
+	}
+	if o.MaxQuestions < o.MinQuestions {
+		o.MaxQuestions = o.MinQuestions
+	}
+	return o
+}
+
+// discoverStep is what follows an answer: the next question, or the end of the session
+type discoverStep struct {
+	Question   string   // Empty when the session is done
+	Planned    []string // Planned questions still open
+	Confidence int
+}
+
+// discoverFollowUp is the structured response to the follow-up prompt
+type discoverFollowUp struct {
+	Confidence int    `json:"confidence" desc:"0-100: how confident you are that the answers so far support a GO/REFINE/PASS verdict"`
+	Covered    []int  `json:"covered,omitempty" desc:"Numbers of the remaining planned questions the answers already cover"`
+	Done       bool   `json:"done" desc:"true if no further question would change the verdict"`
+	Question   string `json:"question,omitempty" desc:"The next question to ask, unless done"`
+}
+
+// Validate keeps the confidence on its scale
+func (f *discoverFollowUp) Validate() error {
+	if f.Confidence < 0 || f.Confidence > 100 {
+		return fmt.Errorf("confidence must be between 0 and 100, got %d", f.Confidence)
+	}
+	return nil
+}
+
+// NextQuestion picks the question that follows the session's latest answer.
+// The model probes vague answers, drops planned questions the answers already cover
+// and ends the session once it is confident; the bounds in opts always apply.
+// Without a usable model response, planned questions are asked in order.
+func (rm *ResearchModule) NextQuestion(ctx context.Context, session *DiscoverSession, opts DiscoverOptions) discoverStep {
+	opts = opts.normalized()
+	answered := len(session.Answers)
+	planned := append([]string(nil), session.Planned...)
+
+	if answered >= opts.MaxQuestions {
+		return discoverStep{Planned: planned, Confidence: session.Confidence}
+	}
+
+	var followUp discoverFollowUp
+	prompt := rm.buildFollowUpPrompt(session, planned, opts)
+	if err := llm.GenerateStructured(ctx, rm.client, DiscoveryModel, prompt, &followUp, llm.StructuredOptions{}); err != nil {
+		log.Printf("Follow-up generation failed for session %s, using planned questions: %v", session.ID, err)
+		return nextQuestionBasic(session, planned, opts)
+	}
+
+	// Drop the planned questions the model says are already answered
+	covered := make(map[int]bool)
+	for _, n := range followUp.Covered {
+		covered[n-1] = true
+	}
+	remaining := planned[:0]
+	for i, question := range planned {
+		if !covered[i] {
+			remaining = append(remaining, question)
+		}
+	}
+
+	step := discoverStep{Planned: remaining, Confidence: followUp.Confidence}
+	if answered >= opts.MinQuestions && (followUp.Done || followUp.Confidence >= discoverStopConfidence) {
+		log.Printf("Discovery session %s stopping after %d questions (confidence %d)", session.ID, answered, followUp.Confidence)
+		return step
+	}
+
+	step.Question = strings.TrimSpace(followUp.Question)
+	if step.Question == "" || askedBefore(session, step.Question) {
+		step.Question = ""
+		if len(step.Planned) > 0 {
+			step.Question, step.Planned = step.Planned[0], step.Planned[1:]
+		} else if answered < opts.MinQuestions {
+			step.Question = unaskedDefaultQuestion(session)
+		}
+	} else if len(step.Planned) > 0 && step.Question == step.Planned[0] {
+		step.Planned = step.Planned[1:]
+	}
+	return step
+}
+
+// nextQuestionBasic is the rule-based follow-up: probe a short answer once, then
+// ask the planned questions in order, stopping when they run out past the minimum
+func nextQuestionBasic(session *DiscoverSession, planned []string, opts DiscoverOptions) discoverStep {
+	step := discoverStep{Planned: planned, Confidence: session.Confidence}
+
+	last := len(session.Answers) - 1
+	lastQuestion := session.Questions[last]
+	if len(strings.Fields(session.Answers[last])) < vagueAnswerWords && !strings.HasPrefix(lastQuestion, probePrefix) {
+		step.Question = fmt.Sprintf("%s %s", probePrefix, lastQuestion)
+		return step
+	}
+
+	if len(planned) > 0 {
+		step.Question, step.Planned = planned[0], planned[1:]
+	} else if len(session.Answers) < opts.MinQuestions {
+		step.Question = unaskedDefaultQuestion(session)
+	}
+	return step
+}
+
+// unaskedDefaultQuestion returns the first default question the session hasn't asked, or ""
+func unaskedDefaultQuestion(session *DiscoverSession) string {
+	for _, question := range getDefaultQuestions() {
+		if !askedBefore(session, question) {
+			return question
+		}
+	}
+	return ""
+}
+
+// askedBefore reports whether the session already asked a question
+func askedBefore(session *DiscoverSession, question string) bool {
+	for _, asked := range session.Questions {
+		if strings.EqualFold(strings.TrimSpace(asked), question) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFollowUpPrompt creates the LLM prompt for choosing the next question
+func (rm *ResearchModule) buildFollowUpPrompt(session *DiscoverSession, planned []string, opts DiscoverOptions) string {
+	var transcript strings.Builder
+	for i, answer := range session.Answers {
+		transcript.WriteString(fmt.Sprintf("\nQuestion %d: %s\nAnswer: %s\n", i+1, session.Questions[i], answer))
+	}
+
+	var remaining strings.Builder
+	for i, question := range planned {
+		remaining.WriteString(fmt.Sprintf("%d. %s\n", i+1, question))
+	}
+	if len(planned) == 0 {
+		remaining.WriteString("(none)\n")
+	}
+
+	return fmt.Sprintf(`You are an expert product researcher interviewing someone about their product idea.
+
+Product Idea: %s
+Idea Category: %s
+
+Interview so far:
+%s
+Remaining planned questions:
+%s
+%d of at most %d questions have been answered; at least %d are asked before the interview may end.
+
+Decide what to ask next:
+- If the latest answer is vague, evasive or missing specifics, ask a follow-up that probes it
+- Otherwise ask the most important planned question the answers have not already covered, or a better question of your own
+- List the planned questions that earlier answers already cover; they will be skipped
+- Set done to true once the answers are enough to judge the idea GO, REFINE or PASS
+
+Questions should be open-ended and answerable by the person with the idea.`,
+		session.RawIdea,
+		session.IdeaType,
+		transcript.String(),
+		remaining.String(),
+		len(session.Answers), opts.MaxQuestions, opts.MinQuestions)
+}
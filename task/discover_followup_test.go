@@ -0,0 +1,84 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai-studio/orchestrator/llm"
+)
+
+// scriptedProvider answers calls with its responses in order
+type scriptedProvider struct {
+	responses []string
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	if len(p.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	text := p.responses[0]
+	p.responses = p.responses[1:]
+	return &llm.Response{Text: text, Model: req.Model}, nil
+}
+
+func (p *scriptedProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *scriptedProvider) Ping() error                   { return nil }
+
+// TestAdaptiveDiscovery tests probing a vague answer, skipping covered questions and stopping early
+func TestAdaptiveDiscovery(t *testing.T) {
+	ctx := context.Background()
+	provider := &scriptedProvider{responses: []string{
+		"CATEGORY: game\n\nQUESTIONS:\n1. Who plays it?\n2. How is it different?\n3. How does it make money?\n4. Can you build it?",
+		`{"confidence": 40, "covered": [2], "done": false, "question": "Which age group exactly?"}`,
+		`{"confidence": 60, "done": false, "question": "Who plays it?"}`,
+		`{"confidence": 90, "done": true}`,
+		`{"verdict": "GO", "reasoning": "Clear audience and monetization."}`,
+	}}
+	dm := NewDiscoverManager(provider, "")
+	dm.SetOptions(DiscoverOptions{MinQuestions: 3, MaxQuestions: 5})
+
+	session := dm.StartSession(ctx, "A co-op puzzle game")
+	if len(session.Questions) != 1 || len(session.Planned) != 3 {
+		t.Fatalf("started with questions %q, planned %q", session.Questions, session.Planned)
+	}
+
+	for _, answer := range []string{"Kids", "Ages 8 to 12 who play with a parent", "Weekly paid puzzle packs"} {
+		var err error
+		if session, err = dm.AddAnswer(ctx, session.ID, answer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The vague answer was probed, the money question was covered by then, and the model's
+	// repeat of an earlier question fell back to the next planned one; confidence ended the session
+	want := []string{"Who plays it?", "Which age group exactly?", "How is it different?"}
+	if len(session.Questions) != len(want) {
+		t.Fatalf("questions = %q, want %q", session.Questions, want)
+	}
+	for i := range want {
+		if session.Questions[i] != want[i] {
+			t.Errorf("question %d = %q, want %q", i+1, session.Questions[i], want[i])
+		}
+	}
+	if len(session.Planned) != 1 || session.Planned[0] != "Can you build it?" {
+		t.Errorf("planned = %q, want the feasibility question left", session.Planned)
+	}
+	if session.Status != "complete" || session.Verdict != "GO" || session.Confidence != 90 {
+		t.Errorf("session = %s %s (confidence %d), want complete GO (90)", session.Status, session.Verdict, session.Confidence)
+	}
+
+	// Without a model, planned questions are asked in order and the maximum still applies
+	dm = NewDiscoverManager(offlineProvider{}, "")
+	dm.SetOptions(DiscoverOptions{MinQuestions: 1, MaxQuestions: 2})
+	session = dm.StartSession(ctx, "A co-op puzzle game")
+	for session.Status != "complete" {
+		var err error
+		if session, err = dm.AddAnswer(ctx, session.ID, "A detailed answer that is long enough to not need probing"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(session.Answers) != 2 || len(session.Questions) != 2 {
+		t.Errorf("offline session asked %d questions, want 2", len(session.Questions))
+	}
+}
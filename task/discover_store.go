@@ -0,0 +1,211 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiscoverQuery selects discovery sessions for listing
+type DiscoverQuery struct {
+	Archived string // "" leaves archived sessions out, "include" lists them too, "only" lists just them
+	Status   string // "answering" or "complete"; "" for both
+	Cursor   string // NextCursor of the previous page
+	Limit    int    // Page size; 0 = no limit
+}
+
+// DiscoverPage is one page of sessions, newest first
+type DiscoverPage struct {
+	Sessions   []DiscoverSession `json:"sessions"`
+	Count      int               `json:"count"`
+	Total      int               `json:"total"`                 // Sessions matching the filters, on every page
+	NextCursor string            `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// newDiscoverID returns a collision-free session ID
+func newDiscoverID() string {
+	return "discover_" + uuid.New().String()
+}
+
+// save writes a session to the sessions directory atomically (caller holds the lock)
+func (dm *DiscoverManager) save(session *DiscoverSession) error {
+	if dm.dir == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode discovery session: %w", err)
+	}
+
+	if err := os.MkdirAll(dm.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create discovery directory: %w", err)
+	}
+
+	path := filepath.Join(dm.dir, session.ID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write discovery session: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to commit discovery session: %w", err)
+	}
+	return nil
+}
+
+// clone copies a session, so callers can read it after the lock is released
+// while later answers keep changing the original (caller holds the lock)
+func (s *DiscoverSession) clone() *DiscoverSession {
+	c := *s
+	c.Questions = append(make([]string, 0, len(s.Questions)), s.Questions...)
+	c.Planned = append([]string(nil), s.Planned...)
+	c.Answers = append(make([]string, 0, len(s.Answers)), s.Answers...)
+	return &c
+}
+
+// persist saves a session, logging failures; the session stays usable in memory
+func (dm *DiscoverManager) persist(session *DiscoverSession) {
+	if err := dm.save(session); err != nil {
+		log.Printf("Discovery: failed to save session %s: %v", session.ID, err)
+	}
+}
+
+// loadDiscoverSessions reads every session saved in dir
+// Unreadable files are logged and skipped, so one bad file doesn't lose the rest
+func loadDiscoverSessions(dir string) map[string]*DiscoverSession {
+	sessions := make(map[string]*DiscoverSession)
+	if dir == "" {
+		return sessions
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return sessions
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Discovery: failed to read session %s: %v", file, err)
+			continue
+		}
+
+		var session DiscoverSession
+		if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+			log.Printf("Discovery: failed to parse session %s: %v", file, err)
+			continue
+		}
+		sessions[session.ID] = &session
+	}
+
+	if len(sessions) > 0 {
+		log.Printf("Discovery: loaded %d sessions from %s", len(sessions), dir)
+	}
+	return sessions
+}
+
+// DeleteSession removes a session and its saved file
+func (dm *DiscoverManager) DeleteSession(sessionID string) error {
+	dm.sessionsMux.Lock()
+	defer dm.sessionsMux.Unlock()
+
+	if dm.sessions[sessionID] == nil {
+		return fmt.Errorf("session not found")
+	}
+
+	if dm.dir != "" {
+		path := filepath.Join(dm.dir, sessionID+".json")
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete discovery session: %w", err)
+		}
+	}
+
+	delete(dm.sessions, sessionID)
+	return nil
+}
+
+// ArchiveSession archives a session (or restores it), hiding it from the default listing
+func (dm *DiscoverManager) ArchiveSession(sessionID string, archived bool) (*DiscoverSession, error) {
+	dm.sessionsMux.Lock()
+	defer dm.sessionsMux.Unlock()
+
+	session := dm.sessions[sessionID]
+	if session == nil {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	session.Archived = archived
+	session.UpdatedAt = time.Now()
+	if err := dm.save(session); err != nil {
+		return nil, err
+	}
+	return session.clone(), nil
+}
+
+// ListSessions returns the sessions matching q, newest first
+func (dm *DiscoverManager) ListSessions(q DiscoverQuery) (DiscoverPage, error) {
+	dm.sessionsMux.RLock()
+	sessions := make([]DiscoverSession, 0, len(dm.sessions))
+	for _, session := range dm.sessions {
+		if q.matches(session) {
+			sessions = append(sessions, *session.clone())
+		}
+	}
+	dm.sessionsMux.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool { return sessionBefore(&sessions[i], &sessions[j]) })
+
+	// The cursor is the sort key of the last session on the previous page
+	start := 0
+	if q.Cursor != "" {
+		nanos, id, ok := strings.Cut(q.Cursor, ":")
+		n, err := strconv.ParseInt(nanos, 10, 64)
+		if !ok || err != nil {
+			return DiscoverPage{}, fmt.Errorf("invalid cursor %q", q.Cursor)
+		}
+		last := &DiscoverSession{ID: id, CreatedAt: time.Unix(0, n)}
+		start = sort.Search(len(sessions), func(i int) bool { return sessionBefore(last, &sessions[i]) })
+	}
+
+	page := DiscoverPage{Sessions: sessions[start:], Total: len(sessions)}
+	if q.Limit > 0 && len(page.Sessions) > q.Limit {
+		page.Sessions = page.Sessions[:q.Limit]
+		last := page.Sessions[q.Limit-1]
+		page.NextCursor = fmt.Sprintf("%d:%s", last.CreatedAt.UnixNano(), last.ID)
+	}
+	page.Count = len(page.Sessions)
+	return page, nil
+}
+
+// sessionBefore orders sessions newest first, by ID within the same instant
+func sessionBefore(a, b *DiscoverSession) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// matches applies the archive and status filters
+func (q DiscoverQuery) matches(s *DiscoverSession) bool {
+	switch q.Archived {
+	case "include":
+	case "only":
+		if !s.Archived {
+			return false
+		}
+	default:
+		if s.Archived {
+			return false
+		}
+	}
+	return q.Status == "" || s.Status == q.Status
+}
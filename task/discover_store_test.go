@@ -0,0 +1,99 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai-studio/orchestrator/llm"
+)
+
+// offlineProvider fails every call, so discovery falls back to its default questions and scoring
+type offlineProvider struct{}
+
+func (offlineProvider) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	return nil, errors.New("offline")
+}
+func (offlineProvider) ListModels() ([]string, error) { return nil, nil }
+func (offlineProvider) Ping() error                   { return nil }
+
+// TestDiscoverSessionsPersist tests resuming sessions after a restart, archiving, paginated listing
+// and that callers get copies of sessions
+func TestDiscoverSessionsPersist(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	dm := NewDiscoverManager(offlineProvider{}, dir)
+
+	ids := make(map[string]bool)
+	var first *DiscoverSession
+	for i := 0; i < 5; i++ {
+		session := dm.StartSession(ctx, "A co-op puzzle game")
+		if ids[session.ID] {
+			t.Fatalf("duplicate session ID %s", session.ID)
+		}
+		ids[session.ID] = true
+		if first == nil {
+			first = session
+		}
+	}
+	if _, err := dm.AddAnswer(ctx, first.ID, "Nobody else does asymmetric co-op"); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := dm.ArchiveSession(first.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Returned sessions are copies; changing them doesn't reach the manager
+	archived.Answers[0] = "changed"
+	archived.Archived = false
+	if stored := dm.GetSession(first.ID); stored.Answers[0] == "changed" || !stored.Archived {
+		t.Errorf("stored session changed through a returned copy: %+v", stored)
+	}
+
+	// A new manager (a restarted server) picks up where the old one left off
+	dm = NewDiscoverManager(offlineProvider{}, dir)
+	resumed := dm.GetSession(first.ID)
+	if resumed == nil || len(resumed.Answers) != 1 || !resumed.Archived {
+		t.Fatalf("resumed session = %+v", resumed)
+	}
+	if _, err := dm.AddAnswer(ctx, first.ID, "Players share one controller"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Archived sessions are left out unless asked for; pages don't overlap
+	seen := make(map[string]bool)
+	q := DiscoverQuery{Limit: 3}
+	for pages := 0; ; pages++ {
+		page, err := dm.ListSessions(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 4 {
+			t.Fatalf("total = %d, want 4", page.Total)
+		}
+		for _, s := range page.Sessions {
+			if seen[s.ID] || s.ID == first.ID {
+				t.Fatalf("unexpected session %s on page %d", s.ID, pages)
+			}
+			seen[s.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if len(seen) != 4 {
+		t.Errorf("listed %d sessions, want 4", len(seen))
+	}
+	if page, _ := dm.ListSessions(DiscoverQuery{Archived: "only"}); page.Count != 1 {
+		t.Errorf("archived listing has %d sessions, want 1", page.Count)
+	}
+
+	if err := dm.DeleteSession(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if NewDiscoverManager(offlineProvider{}, dir).GetSession(first.ID) != nil {
+		t.Error("deleted session came back after a restart")
+	}
+}
@@ -0,0 +1,411 @@
+package task
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Extractor finds files in model output in one format
+type Extractor interface {
+	Name() string
+	Extract(output string) []ExtractedFile
+}
+
+// ExtractedFile is a file found by an extractor
+type ExtractedFile struct {
+	Path    string
+	Content string
+	Start   int // Byte span of the block the file came from, so other extractors skip it
+	End     int
+}
+
+// ExtractionDiagnostic reports a file or block that was skipped or rejected
+type ExtractionDiagnostic struct {
+	Extractor string `json:"extractor"`
+	Path      string `json:"path,omitempty"`
+	Message   string `json:"message"`
+}
+
+// ExtractionReport is the outcome of running the extractor chain over model output
+type ExtractionReport struct {
+	Files       []FileContent          `json:"-"`
+	Paths       []string               `json:"paths"`      // Extracted files, in output order
+	Extractors  map[string]int         `json:"extractors"` // Extractor name -> files it found
+	Diagnostics []ExtractionDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// ExtractorChain runs extractors in order; a block claimed by an earlier extractor is not
+// extracted again, and a path that appears twice keeps its last occurrence
+type ExtractorChain struct {
+	extractors []Extractor
+}
+
+// NewExtractorChain creates a chain of extractors, tried in the given order
+func NewExtractorChain(extractors ...Extractor) *ExtractorChain {
+	return &ExtractorChain{extractors: extractors}
+}
+
+// DefaultExtractorChain recognizes <file> tags, fence titles, ### headings and "File:" comments
+func DefaultExtractorChain() *ExtractorChain {
+	return NewExtractorChain(TagExtractor{}, FenceTitleExtractor{}, HeadingExtractor{}, CommentExtractor{})
+}
+
+// Register appends an extractor to the chain; it runs after the existing ones
+func (c *ExtractorChain) Register(e Extractor) {
+	c.extractors = append(c.extractors, e)
+}
+
+// Extract runs the chain over output
+func (c *ExtractorChain) Extract(output string) *ExtractionReport {
+	report := &ExtractionReport{Paths: []string{}, Extractors: make(map[string]int)}
+
+	var claimed []claimedFile
+
+	for _, e := range c.extractors {
+		for _, f := range e.Extract(output) {
+			if owner := overlapping(claimed, f.Start, f.End); owner >= 0 {
+				if prev := claimed[owner]; cleanPath(prev.Path) != cleanPath(f.Path) {
+					report.diagnose(e.Name(), f.Path, fmt.Sprintf("block already extracted as %s by %s", prev.Path, prev.extractor))
+				}
+				continue
+			}
+
+			rel, err := SafeRelPath(f.Path)
+			if err != nil {
+				report.diagnose(e.Name(), f.Path, err.Error())
+				continue
+			}
+			if strings.TrimSpace(f.Content) == "" {
+				report.diagnose(e.Name(), rel, "no file content")
+				continue
+			}
+
+			f.Path = rel
+			claimed = append(claimed, claimedFile{ExtractedFile: f, extractor: e.Name()})
+		}
+	}
+
+	sort.SliceStable(claimed, func(i, j int) bool { return claimed[i].Start < claimed[j].Start })
+
+	// Later blocks for the same path replace earlier ones
+	last := make(map[string]int)
+	for i, f := range claimed {
+		if prev, ok := last[f.Path]; ok {
+			report.diagnose(f.extractor, f.Path, fmt.Sprintf("duplicate file; replaces the block found by %s", claimed[prev].extractor))
+		}
+		last[f.Path] = i
+	}
+	for i, f := range claimed {
+		if last[f.Path] != i {
+			continue
+		}
+		report.Files = append(report.Files, FileContent{Path: f.Path, Content: f.Content})
+		report.Paths = append(report.Paths, f.Path)
+		report.Extractors[f.extractor]++
+	}
+
+	return report
+}
+
+// diagnose records a skipped or rejected file
+func (r *ExtractionReport) diagnose(extractor, path, message string) {
+	r.Diagnostics = append(r.Diagnostics, ExtractionDiagnostic{Extractor: extractor, Path: path, Message: message})
+}
+
+// claimedFile is an extracted file and the extractor that found it
+type claimedFile struct {
+	ExtractedFile
+	extractor string
+}
+
+// overlapping returns the index of a claimed file whose block overlaps [start, end), or -1
+func overlapping(claimed []claimedFile, start, end int) int {
+	for i, c := range claimed {
+		if start < c.End && c.Start < end {
+			return i
+		}
+	}
+	return -1
+}
+
+// SafeRelPath normalizes a generated file path and rejects any that is absolute or
+// would escape the project directory
+func SafeRelPath(p string) (string, error) {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("path contains a NUL byte")
+	}
+
+	slashed := strings.ReplaceAll(p, "\\", "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(p) || filepath.VolumeName(p) != "" || hasDriveLetter(slashed) {
+		return "", fmt.Errorf("absolute path rejected")
+	}
+
+	clean := path.Clean(slashed)
+	if clean == "." {
+		return "", fmt.Errorf("path names the project directory itself")
+	}
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("path escapes the project directory")
+	}
+	return clean, nil
+}
+
+// hasDriveLetter reports whether p starts with a Windows drive such as "C:"
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && ((p[0] >= 'a' && p[0] <= 'z') || (p[0] >= 'A' && p[0] <= 'Z'))
+}
+
+// SafeJoin joins a generated file path onto dir, rejecting paths that escape it
+func SafeJoin(dir, p string) (string, error) {
+	rel, err := SafeRelPath(p)
+	if err != nil {
+		return "", fmt.Errorf("unsafe path %q: %w", p, err)
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), nil
+}
+
+// cleanPath normalizes a path for comparison, keeping unsafe paths as given
+func cleanPath(p string) string {
+	if rel, err := SafeRelPath(p); err == nil {
+		return rel
+	}
+	return p
+}
+
+// extensionlessFiles are file names recognized without an extension
+var extensionlessFiles = map[string]bool{
+	"Dockerfile": true, "Makefile": true, "GNUmakefile": true, "Procfile": true, "Gemfile": true,
+	"Rakefile": true, "Jenkinsfile": true, "Vagrantfile": true, "Brewfile": true, "Caddyfile": true,
+	"LICENSE": true, "README": true, "CODEOWNERS": true,
+}
+
+var fileExtensionRegex = regexp.MustCompile(`\.[A-Za-z0-9_-]+$`)
+
+// looksLikeFilePath reports whether s names a file: it has an extension, is a dotfile,
+// or is a well-known extensionless file such as Dockerfile
+func looksLikeFilePath(s string) bool {
+	if s == "" || strings.ContainsAny(s, " \t") {
+		return false
+	}
+	base := path.Base(strings.ReplaceAll(s, "\\", "/"))
+	return fileExtensionRegex.MatchString(base) || extensionlessFiles[base] || extensionlessFiles[strings.TrimSuffix(base, ".example")]
+}
+
+// fencedBlock is a ``` or ~~~ code block
+type fencedBlock struct {
+	Start   int    // Offset of the opening fence line
+	End     int    // Offset just past the closing fence line
+	Info    string // Text after the opening fence, e.g. `js title="app.js"`
+	Content string // Lines between the fences
+}
+
+var fenceLineRegex = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*(.*?)\\s*$")
+
+// fencedBlocks finds the code blocks in output
+// A fence with an info string inside a block opens a nested block (e.g. a README with
+// examples), so only the matching bare fence closes the outer one.
+func fencedBlocks(output string) []fencedBlock {
+	var blocks []fencedBlock
+	var current *fencedBlock
+	var fence string
+	depth := 0
+	contentStart := 0
+
+	offset := 0
+	for offset < len(output) {
+		lineEnd := strings.IndexByte(output[offset:], '\n')
+		next := len(output)
+		if lineEnd >= 0 {
+			next = offset + lineEnd + 1
+		}
+		line := strings.TrimRight(output[offset:next], "\r\n")
+
+		m := fenceLineRegex.FindStringSubmatch(line)
+		switch {
+		case m == nil:
+		case current == nil:
+			current = &fencedBlock{Start: offset, Info: m[2]}
+			fence = m[1][:1]
+			depth = 1
+			contentStart = next
+		case strings.HasPrefix(m[1], fence) && m[2] != "":
+			depth++
+		case strings.HasPrefix(m[1], fence):
+			depth--
+			if depth == 0 {
+				current.Content = output[contentStart:offset]
+				current.End = next
+				blocks = append(blocks, *current)
+				current = nil
+			}
+		}
+		offset = next
+	}
+
+	return blocks
+}
+
+// firstBlockIn returns the first code block starting in [from, to), if any
+func firstBlockIn(blocks []fencedBlock, from, to int) (fencedBlock, bool) {
+	for _, b := range blocks {
+		if b.Start >= from && b.Start < to {
+			return b, true
+		}
+	}
+	return fencedBlock{}, false
+}
+
+// HeadingExtractor reads "### path" headings followed by a code block
+type HeadingExtractor struct{}
+
+var headingRegex = regexp.MustCompile(`(?m)^[ \t]*#{3,}[ \t]+(.+?)[ \t]*$`)
+var headingPrefixRegex = regexp.MustCompile(`^(?i:(?:\d+[.)]\s*)?(?:file(?:name)?\s*:\s*)?)`)
+
+// Name returns "heading"
+func (HeadingExtractor) Name() string { return "heading" }
+
+// Extract finds each file heading and takes the first code block before the next one
+func (HeadingExtractor) Extract(output string) []ExtractedFile {
+	type heading struct {
+		path       string
+		start, end int
+	}
+	var headings []heading
+	for _, m := range headingRegex.FindAllStringSubmatchIndex(output, -1) {
+		if p := headingPath(output[m[2]:m[3]]); p != "" {
+			headings = append(headings, heading{path: p, start: m[0], end: m[1]})
+		}
+	}
+
+	blocks := fencedBlocks(output)
+	var files []ExtractedFile
+	for i, h := range headings {
+		sectionEnd := len(output)
+		if i+1 < len(headings) {
+			sectionEnd = headings[i+1].start
+		}
+		block, ok := firstBlockIn(blocks, h.end, sectionEnd)
+		if !ok {
+			// Reported by the chain as a file without content
+			files = append(files, ExtractedFile{Path: h.path, Start: h.start, End: h.end})
+			continue
+		}
+		files = append(files, ExtractedFile{
+			Path:    h.path,
+			Content: strings.TrimSpace(block.Content),
+			Start:   block.Start,
+			End:     block.End,
+		})
+	}
+	return files
+}
+
+// headingPath returns the file path named by a heading, or "" if it doesn't name one
+// Numbering, a "File:" label, backticks and bold markers are ignored, as is any
+// description after the path, e.g. "### 2. `src/app.js` (entry point)".
+func headingPath(text string) string {
+	text = strings.NewReplacer("`", "", "**", "", "__", "").Replace(text)
+	text = strings.TrimSpace(headingPrefixRegex.ReplaceAllString(strings.TrimSpace(text), ""))
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	p := strings.TrimRight(fields[0], ":")
+	if i := strings.IndexByte(p, '('); i >= 0 {
+		p = p[:i]
+	}
+	if !looksLikeFilePath(p) {
+		return ""
+	}
+	return p
+}
+
+// FenceTitleExtractor reads paths from code fence attributes such as ```js title="src/app.js"
+type FenceTitleExtractor struct{}
+
+var fenceTitleRegex = regexp.MustCompile(`(?i)\b(?:title|filename|file|path)\s*=\s*(?:"([^"]+)"|'([^']+)'|(\S+))`)
+
+// Name returns "fence_title"
+func (FenceTitleExtractor) Name() string { return "fence_title" }
+
+// Extract returns every code block whose info string names a file
+func (FenceTitleExtractor) Extract(output string) []ExtractedFile {
+	var files []ExtractedFile
+	for _, b := range fencedBlocks(output) {
+		m := fenceTitleRegex.FindStringSubmatch(b.Info)
+		if m == nil {
+			continue
+		}
+		files = append(files, ExtractedFile{
+			Path:    m[1] + m[2] + m[3],
+			Content: strings.TrimSpace(b.Content),
+			Start:   b.Start,
+			End:     b.End,
+		})
+	}
+	return files
+}
+
+// CommentExtractor reads paths from a "File: path" comment on a code block's first line,
+// e.g. "// File: src/app.js", "# file: app.py" or "<!-- File: index.html -->"
+type CommentExtractor struct{}
+
+var fileCommentRegex = regexp.MustCompile(`(?i)^\s*(?://|#|--|;|/\*|<!--)\s*(?:file(?:name)?|path)\s*:\s*(\S+?)\s*(?:\*/|-->)?\s*$`)
+
+// Name returns "comment"
+func (CommentExtractor) Name() string { return "comment" }
+
+// Extract returns every code block that starts with a file comment, without that line
+func (CommentExtractor) Extract(output string) []ExtractedFile {
+	var files []ExtractedFile
+	for _, b := range fencedBlocks(output) {
+		content := strings.TrimLeft(b.Content, "\r\n")
+		firstLine, rest, _ := strings.Cut(content, "\n")
+		m := fileCommentRegex.FindStringSubmatch(firstLine)
+		if m == nil {
+			continue
+		}
+		files = append(files, ExtractedFile{
+			Path:    m[1],
+			Content: strings.TrimSpace(rest),
+			Start:   b.Start,
+			End:     b.End,
+		})
+	}
+	return files
+}
+
+// TagExtractor reads <file path="...">...</file> tags; a code fence around the content is removed
+type TagExtractor struct{}
+
+var fileTagRegex = regexp.MustCompile(`(?s)<file\s+(?:path|name)\s*=\s*["']([^"']+)["']\s*>(.*?)</file>`)
+
+// Name returns "tag"
+func (TagExtractor) Name() string { return "tag" }
+
+// Extract returns the content of every <file> tag
+func (TagExtractor) Extract(output string) []ExtractedFile {
+	var files []ExtractedFile
+	for _, m := range fileTagRegex.FindAllStringSubmatchIndex(output, -1) {
+		content := strings.TrimSpace(output[m[4]:m[5]])
+		if blocks := fencedBlocks(content); len(blocks) == 1 && blocks[0].Start == 0 {
+			content = strings.TrimSpace(blocks[0].Content)
+		}
+		files = append(files, ExtractedFile{
+			Path:    output[m[2]:m[3]],
+			Content: content,
+			Start:   m[0],
+			End:     m[1],
+		})
+	}
+	return files
+}
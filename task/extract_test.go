@@ -0,0 +1,95 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExtractFileFormats tests each built-in extractor and that unsafe paths are rejected
+func TestExtractFileFormats(t *testing.T) {
+	fence := "```"
+	output := strings.Join([]string{
+		"## Implementation",
+		"### 1. `index.html` (entry point)",
+		fence + "html",
+		"<script src=\"src/app.js\"></script>",
+		fence,
+		"",
+		fence + `js title="src/app.js"`,
+		"console.log('hi');",
+		fence,
+		"",
+		"### Dockerfile",
+		fence + "dockerfile",
+		"FROM nginx:alpine",
+		fence,
+		"",
+		fence + "makefile",
+		"# File: Makefile",
+		"run:",
+		"\tdocker run app",
+		fence,
+		"",
+		`<file path="docs/README.md">`,
+		fence + "markdown",
+		"# App",
+		fence + "bash",
+		"make run",
+		fence,
+		fence,
+		"</file>",
+		"",
+		"### ../../etc/cron.d/job.sh",
+		fence + "sh",
+		"echo pwned",
+		fence,
+		"",
+		fence + `js filename="/tmp/abs.js"`,
+		"x()",
+		fence,
+	}, "\n")
+
+	report := DefaultExtractorChain().Extract(output)
+
+	wantPaths := []string{"index.html", "src/app.js", "Dockerfile", "Makefile", "docs/README.md"}
+	if !reflect.DeepEqual(report.Paths, wantPaths) {
+		t.Errorf("Paths = %v, want %v", report.Paths, wantPaths)
+	}
+
+	contents := make(map[string]string)
+	for _, f := range report.Files {
+		contents[f.Path] = f.Content
+	}
+	if contents["Makefile"] != "run:\n\tdocker run app" {
+		t.Errorf("Makefile content = %q, want the comment line dropped", contents["Makefile"])
+	}
+	if want := "# App\n```bash\nmake run\n```"; contents["docs/README.md"] != want {
+		t.Errorf("README content = %q, want %q", contents["docs/README.md"], want)
+	}
+
+	wantCounts := map[string]int{"heading": 2, "fence_title": 1, "comment": 1, "tag": 1}
+	if !reflect.DeepEqual(report.Extractors, wantCounts) {
+		t.Errorf("Extractors = %v, want %v", report.Extractors, wantCounts)
+	}
+
+	rejected := make(map[string]bool)
+	for _, d := range report.Diagnostics {
+		rejected[d.Path] = true
+	}
+	if !rejected["../../etc/cron.d/job.sh"] || !rejected["/tmp/abs.js"] || len(report.Diagnostics) != 2 {
+		t.Errorf("Diagnostics = %+v, want the two unsafe paths rejected", report.Diagnostics)
+	}
+}
+
+// TestSafeJoin tests that paths escaping the project directory are rejected
+func TestSafeJoin(t *testing.T) {
+	for _, p := range []string{"../x", "a/../../x", "/etc/passwd", `C:\Windows\x.dll`, `..\x`, ""} {
+		if _, err := SafeJoin("projects/demo", p); err == nil {
+			t.Errorf("SafeJoin(%q) should fail", p)
+		}
+	}
+	if got, err := SafeJoin("projects/demo", `./src\app.js`); err != nil || got != "projects/demo/src/app.js" {
+		t.Errorf("SafeJoin() = %q, %v", got, err)
+	}
+}
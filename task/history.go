@@ -0,0 +1,252 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page sizes for history queries
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+)
+
+// HistoryStore persists every task result to an append-only JSONL file.
+// Only the fields used for filtering and each record's file offset are kept in
+// memory; results are read back from disk when a query needs them.
+type HistoryStore struct {
+	path string
+
+	mu      sync.RWMutex
+	file    *os.File
+	size    int64
+	entries []historyEntry // In append order
+}
+
+// historyEntry indexes one stored result
+type historyEntry struct {
+	offset    int64
+	length    int
+	taskType  string
+	model     string
+	timestamp time.Time
+	failed    bool
+}
+
+// HistoryQuery selects stored results; zero fields match everything
+type HistoryQuery struct {
+	TaskType string    // "" or "all" for every type
+	Model    string    // Exact model name
+	Since    time.Time // Results at or after this time
+	Until    time.Time // Results before this time
+	Errors   string    // "only" for failed results, "none" for successful ones
+	Search   string    // Every word must appear in the input or output (case-insensitive)
+	Cursor   string    // NextCursor of the previous page
+	Limit    int       // Page size; 0 = no limit
+}
+
+// HistoryPage is one page of results, newest first
+type HistoryPage struct {
+	Results    []Result `json:"results"`
+	NextCursor string   `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// OpenHistoryStore opens (or creates) the history file at path and indexes its records
+// Lines that can't be parsed are skipped; a final record cut short by a crash is removed.
+func OpenHistoryStore(path string) (*HistoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	h := &HistoryStore{path: path, file: file}
+	if err := h.index(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// index scans the file, recording the offset and filter fields of every record
+func (h *HistoryStore) index() error {
+	reader := bufio.NewReader(h.file)
+	var offset int64
+	skipped := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A record without its newline was cut short; drop it so appends start on a fresh line
+			if len(line) > 0 {
+				skipped++
+				if err := h.file.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to repair history file: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read history file: %w", err)
+		}
+
+		var result Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			skipped++
+		} else {
+			h.entries = append(h.entries, newHistoryEntry(&result, offset, len(line)))
+		}
+		offset += int64(len(line))
+	}
+
+	if skipped > 0 {
+		log.Printf("Warning: skipped %d unreadable record(s) in task history %s", skipped, h.path)
+	}
+	h.size = offset
+	return nil
+}
+
+// newHistoryEntry indexes a result stored at offset
+func newHistoryEntry(result *Result, offset int64, length int) historyEntry {
+	return historyEntry{
+		offset:    offset,
+		length:    length,
+		taskType:  result.TaskType,
+		model:     result.Model,
+		timestamp: result.Timestamp,
+		failed:    result.Error != "",
+	}
+}
+
+// Append stores a result
+func (h *HistoryStore) Append(result *Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	data = append(data, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.file.WriteAt(data, h.size); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	h.entries = append(h.entries, newHistoryEntry(result, h.size, len(data)))
+	h.size += int64(len(data))
+	return nil
+}
+
+// Query returns the results matching q, newest first
+func (h *HistoryStore) Query(q HistoryQuery) (HistoryPage, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// The cursor is the append index to continue below
+	start := len(h.entries) - 1
+	if q.Cursor != "" {
+		n, err := strconv.Atoi(q.Cursor)
+		if err != nil || n < 0 || n > len(h.entries) {
+			return HistoryPage{}, fmt.Errorf("invalid cursor %q", q.Cursor)
+		}
+		start = n - 1
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Search))
+	page := HistoryPage{Results: []Result{}}
+
+	for i := start; i >= 0; i-- {
+		entry := h.entries[i]
+		if !q.matchesEntry(entry) {
+			continue
+		}
+
+		result, err := h.read(entry)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		if !matchesTerms(result, terms) {
+			continue
+		}
+
+		// Only hand out a cursor when another match is known to follow
+		if q.Limit > 0 && len(page.Results) == q.Limit {
+			page.NextCursor = strconv.Itoa(i + 1)
+			break
+		}
+		page.Results = append(page.Results, *result)
+	}
+
+	return page, nil
+}
+
+// read loads one stored result
+func (h *HistoryStore) read(entry historyEntry) (*Result, error) {
+	data := make([]byte, entry.length)
+	if _, err := h.file.ReadAt(data, entry.offset); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse history record: %w", err)
+	}
+	return &result, nil
+}
+
+// Close closes the history file
+func (h *HistoryStore) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.file.Close()
+}
+
+// matchesEntry applies the filters that don't need the stored record
+func (q HistoryQuery) matchesEntry(e historyEntry) bool {
+	if q.TaskType != "" && q.TaskType != "all" && e.taskType != q.TaskType {
+		return false
+	}
+	if q.Model != "" && e.model != q.Model {
+		return false
+	}
+	if !q.Since.IsZero() && e.timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.timestamp.Before(q.Until) {
+		return false
+	}
+	switch q.Errors {
+	case "only":
+		return e.failed
+	case "none":
+		return !e.failed
+	}
+	return true
+}
+
+// matchesTerms reports whether every search term appears in the result's input or output
+func matchesTerms(result *Result, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	input := strings.ToLower(result.Input)
+	output := strings.ToLower(result.Output)
+	for _, term := range terms {
+		if !strings.Contains(input, term) && !strings.Contains(output, term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestHistoryStoreQuery tests filtering, search and cursor paging, and that results survive a reopen
+func TestHistoryStoreQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	store, err := OpenHistoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore() error = %v", err)
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := []Result{
+		{TaskType: "code", Model: "deepseek-coder:6.7b-instruct", Input: "Snake game", Output: "<canvas>", Timestamp: base},
+		{TaskType: "review", Model: "llama3:8b", Input: "Review the API", Output: "Looks fine", Timestamp: base.Add(time.Hour)},
+		{TaskType: "code", Model: "deepseek-coder:6.7b-instruct", Input: "Todo app", Error: "context deadline exceeded", Timestamp: base.Add(2 * time.Hour)},
+		{TaskType: "code", Model: "llama3:8b", Input: "Pong game", Output: "<canvas id=pong>", Timestamp: base.Add(3 * time.Hour)},
+	}
+	for i := range results {
+		if err := store.Append(&results[i]); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	store.Close()
+
+	// A record cut short by a crash is dropped on reopen
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"task_type":"code","inp`)
+	f.Close()
+
+	store, err = OpenHistoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore() error = %v", err)
+	}
+	defer store.Close()
+
+	inputs := func(page HistoryPage) []string {
+		var got []string
+		for _, r := range page.Results {
+			got = append(got, r.Input)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+		want  []string
+	}{
+		{"all, newest first", HistoryQuery{TaskType: "all"}, []string{"Pong game", "Todo app", "Review the API", "Snake game"}},
+		{"type and model", HistoryQuery{TaskType: "code", Model: "deepseek-coder:6.7b-instruct"}, []string{"Todo app", "Snake game"}},
+		{"failed only", HistoryQuery{Errors: "only"}, []string{"Todo app"}},
+		{"date range", HistoryQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"Todo app", "Review the API"}},
+		{"search input and output", HistoryQuery{Search: "CANVAS game"}, []string{"Pong game", "Snake game"}},
+	}
+	for _, tt := range tests {
+		page, err := store.Query(tt.query)
+		if err != nil {
+			t.Fatalf("%s: Query() error = %v", tt.name, err)
+		}
+		if got := inputs(page); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Page through everything two at a time
+	var paged []string
+	q := HistoryQuery{Limit: 2}
+	for {
+		page, err := store.Query(q)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		paged = append(paged, inputs(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if want := tests[0].want; !reflect.DeepEqual(paged, want) {
+		t.Errorf("paged = %v, want %v", paged, want)
+	}
+
+	// A full page only gets a cursor when a later result also matches the search
+	page, err := store.Query(HistoryQuery{Search: "pong", Limit: 1})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := inputs(page); len(got) != 1 || page.NextCursor != "" {
+		t.Errorf("last search page = %v, cursor %q", got, page.NextCursor)
+	}
+	page, _ = store.Query(HistoryQuery{Search: "game", Limit: 1})
+	if page.NextCursor == "" {
+		t.Error("search page with a later match has no cursor")
+	}
+
+	// Appends after the repair start on a fresh line
+	if err := store.Append(&Result{TaskType: "code", Input: "Chess", Timestamp: base.Add(4 * time.Hour)}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	reopened, err := OpenHistoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore() error = %v", err)
+	}
+	defer reopened.Close()
+	if page, _ := reopened.Query(HistoryQuery{Limit: 1}); len(page.Results) != 1 || page.Results[0].Input != "Chess" {
+		t.Errorf("latest after reopen = %v", inputs(page))
+	}
+}
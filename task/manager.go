@@ -1,13 +1,14 @@
 package task
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"ai-studio/orchestrator/config"
@@ -17,41 +18,207 @@ import (
 // Manager handles task execution and routing
 type Manager struct {
 	cfg        *config.Config
-	client     *llm.Client
-	history    []Result
-	historyMux sync.RWMutex
-	maxHistory int
+	client     llm.Provider
+	history    *HistoryStore   // nil when the history file can't be opened
+	artifacts  *ArtifactStore  // nil when the artifacts directory can't be opened
+	extractors *ExtractorChain // Finds generated files in code task output
 }
 
 // Result represents the output of a task execution
 type Result struct {
-	TaskType     string    `json:"task_type"`
-	Input        string    `json:"input"`
-	Output       string    `json:"output"`
-	Model        string    `json:"model"`
-	ArtifactPath string    `json:"artifact_path"`
-	Duration     float64   `json:"duration_seconds"`
-	Timestamp    time.Time `json:"timestamp"`
-	Error        string    `json:"error,omitempty"`
+	TaskType     string                 `json:"task_type"`
+	Input        string                 `json:"input"`
+	Output       string                 `json:"output"`
+	Model        string                 `json:"model"`
+	ArtifactID   string                 `json:"artifact_id,omitempty"` // Artifact store ID of the saved result
+	ArtifactPath string                 `json:"artifact_path"`
+	ProjectDir   string                 `json:"project_dir,omitempty"` // Project a code task generated or patched
+	Duration     float64                `json:"duration_seconds"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Usage        *llm.Usage             `json:"usage,omitempty"`   // Tokens and timings of the model calls, retries included
+	Options      map[string]interface{} `json:"options,omitempty"` // Effective generation options (temperature, num_ctx, ...)
+	ThinkingMode string                 `json:"thinking_mode,omitempty"`
+	Thinking     string                 `json:"thinking,omitempty"`      // Model's reasoning trace, kept out of Output
+	ThinkingPath string                 `json:"thinking_path,omitempty"` // File the reasoning trace was saved to
+	Extraction   *ExtractionReport      `json:"extraction,omitempty"`    // Files found in code task output
+	Patch        *PatchReport           `json:"patch,omitempty"`         // Changes made to an existing project (incremental code tasks)
+	Coverage     *FileCoverage          `json:"coverage,omitempty"`      // Planned files generated (per-file code tasks)
+	Error        string                 `json:"error,omitempty"`
+
+	files []FileContent // Files of a per-file code task, saved as generated rather than extracted from Output
 }
 
 // NewManager creates a new task manager
-func NewManager(cfg *config.Config) *Manager {
+// It fails only if the configured LLM provider can't be built (a missing replay cassette).
+func NewManager(cfg *config.Config) (*Manager, error) {
+	client, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Manager{
 		cfg:        cfg,
-		client:     llm.NewClient(cfg.OllamaURL, cfg.Timeout),
-		history:    make([]Result, 0, 20),
-		maxHistory: 20,
+		client:     client,
+		history:    openHistory(cfg),
+		artifacts:  openArtifacts(cfg),
+		extractors: DefaultExtractorChain(),
+	}, nil
+}
+
+// openHistory opens the task history file, logging a warning and returning nil if it can't
+func openHistory(cfg *config.Config) *HistoryStore {
+	path := cfg.HistoryPath
+	if path == "" {
+		path = config.DefaultHistoryPath
 	}
+
+	history, err := OpenHistoryStore(path)
+	if err != nil {
+		log.Printf("Warning: task history disabled: %v", err)
+		return nil
+	}
+
+	log.Printf("✓ Task history: %s (%d results)", path, len(history.entries))
+	return history
+}
+
+// openArtifacts opens the artifact store, logging a warning and returning nil if it can't
+func openArtifacts(cfg *config.Config) *ArtifactStore {
+	dir := cfg.ArtifactsDir
+	if dir == "" {
+		dir = "./artifacts"
+	}
+
+	store, err := OpenArtifactStore(dir)
+	if err != nil {
+		log.Printf("Warning: artifacts will not be saved: %v", err)
+		return nil
+	}
+	return store
+}
+
+// newProvider builds the LLM provider from config
+// In cassette replay mode responses come from the cassette file only; otherwise the
+// provider router is wrapped in the response cache (if enabled) and then the recorder
+// (in record mode). Unknown provider types and routes are logged and skipped; a replay
+// cassette that can't be loaded is an error, since replay has nothing to fall back to.
+func newProvider(cfg *config.Config) (llm.Provider, error) {
+	cassettePath := cfg.Cassette.Path
+	if cassettePath == "" {
+		cassettePath = "./cassettes/llm.json"
+	}
+
+	switch cfg.Cassette.Mode {
+	case "":
+	case "replay":
+		replayer, err := llm.NewReplayer(cassettePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load LLM cassette: %w", err)
+		}
+		log.Printf("✓ LLM cassette replay enabled (cassette: %s)", cassettePath)
+		return replayer, nil
+	case "record":
+		log.Printf("✓ LLM cassette recording enabled (cassette: %s)", cassettePath)
+		return llm.NewRecorder(newLiveProvider(cfg), cassettePath), nil
+	default:
+		log.Printf("Warning: Unknown cassette mode %q (ignored)", cfg.Cassette.Mode)
+	}
+
+	return newLiveProvider(cfg), nil
+}
+
+// newLiveProvider builds the provider router, wrapped in the response cache if enabled
+func newLiveProvider(cfg *config.Config) llm.Provider {
+	router := llm.NewRouter(config.DefaultProvider, newOllamaProvider(cfg))
+
+	for name, pc := range cfg.Providers {
+		apiKey := pc.APIKey
+		if pc.APIKeyEnv != "" {
+			apiKey = os.Getenv(pc.APIKeyEnv)
+		}
+
+		switch pc.Type {
+		case "ollama":
+			router.Register(name, newOllamaHost(cfg, pc.BaseURL))
+		case "openai":
+			router.Register(name, llm.NewOpenAIClient(pc.BaseURL, apiKey, cfg.Timeout))
+		default:
+			log.Printf("Warning: Unknown provider type %q for provider %s (skipped)", pc.Type, name)
+		}
+	}
+
+	for model, name := range cfg.ModelProviders {
+		if err := router.Route(model, name); err != nil {
+			log.Printf("Warning: %v (using %s)", err, config.DefaultProvider)
+		}
+	}
+
+	if !cfg.Cache.Enabled {
+		return router
+	}
+
+	cacheDir := cfg.Cache.Dir
+	if cacheDir == "" {
+		cacheDir = "./cache/llm"
+	}
+
+	cached, err := llm.NewCachedProvider(router, llm.CacheConfig{
+		Dir:      cacheDir,
+		TTL:      time.Duration(cfg.Cache.TTLHours) * time.Hour,
+		MaxBytes: int64(cfg.Cache.MaxSizeMB) * 1024 * 1024,
+	})
+	if err != nil {
+		log.Printf("Warning: LLM cache disabled: %v", err)
+		return router
+	}
+
+	log.Printf("✓ LLM response cache enabled (dir: %s)", cacheDir)
+	return cached
+}
+
+// newOllamaProvider returns the client for OllamaURL, or a health-checked pool when OllamaHosts is set
+func newOllamaProvider(cfg *config.Config) llm.Provider {
+	log.Printf("✓ LLM scheduler: %d concurrent request(s) per Ollama host", cfg.HostConcurrency())
+
+	if len(cfg.OllamaHosts) == 0 {
+		return newOllamaHost(cfg, cfg.OllamaURL)
+	}
+
+	hosts := make(map[string]llm.Provider, len(cfg.OllamaHosts))
+	for _, url := range cfg.OllamaHosts {
+		hosts[url] = newOllamaHost(cfg, url)
+	}
+
+	interval := time.Duration(cfg.HealthCheckSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	pool := llm.NewPool(hosts)
+	pool.Start(interval)
+
+	log.Printf("✓ Ollama pool enabled (%d hosts, health check every %s)", len(hosts), interval)
+	return pool
+}
+
+// newOllamaHost returns the client for one Ollama host behind its request scheduler
+func newOllamaHost(cfg *config.Config, url string) llm.Provider {
+	return llm.NewScheduler(url, llm.NewClient(url, cfg.Timeout), cfg.HostConcurrency())
 }
 
 // ExecuteTask routes and executes a task
-func (m *Manager) ExecuteTask(taskType, input string) (interface{}, error) {
-	return m.ExecuteTaskWithThinking(taskType, input, "normal")
+func (m *Manager) ExecuteTask(ctx context.Context, taskType, input string) (interface{}, error) {
+	return m.ExecuteTaskWithThinking(ctx, taskType, input, "normal")
 }
 
 // ExecuteTaskWithThinking routes and executes a task with specified thinking mode
-func (m *Manager) ExecuteTaskWithThinking(taskType, input, thinkingMode string) (interface{}, error) {
+func (m *Manager) ExecuteTaskWithThinking(ctx context.Context, taskType, input, thinkingMode string) (interface{}, error) {
+	return m.ExecuteTaskStream(ctx, taskType, input, thinkingMode, nil)
+}
+
+// ExecuteTaskStream executes a task and forwards model output to onToken as it is generated
+// Cancelling ctx aborts the in-flight LLM request and skips any remaining retries
+func (m *Manager) ExecuteTaskStream(ctx context.Context, taskType, input, thinkingMode string, onToken llm.TokenHandler) (interface{}, error) {
 	start := time.Now()
 	result := &Result{
 		TaskType:  taskType,
@@ -60,52 +227,89 @@ func (m *Manager) ExecuteTaskWithThinking(taskType, input, thinkingMode string)
 	}
 
 	// Get model for this task type
-	model, ok := m.cfg.Models[taskType]
+	model, ok := m.cfg.TaskModel(taskType)
 	if !ok {
 		result.Error = fmt.Sprintf("unknown task type: %s", taskType)
 		return result, fmt.Errorf(result.Error)
 	}
 	result.Model = model
 
+	// A code task given a file manifest generates each file in its own call instead
+	manifest := fileManifestFrom(ctx)
+	if taskType != "code" || projectDirFrom(ctx) != "" {
+		manifest = nil
+	}
+
 	// Build prompt based on task type
-	prompt, err := m.buildPrompt(taskType, input)
-	if err != nil {
-		result.Error = err.Error()
-		return result, err
+	var prompt string
+	if manifest == nil {
+		var err error
+		prompt, err = m.buildPrompt(ctx, taskType, input)
+		if err != nil {
+			result.Error = err.Error()
+			return result, err
+		}
 	}
 
 	// Execute with retries and thinking mode
-	var output string
+	var output, thinking string
 	var lastErr error
 
+	meter := &llm.UsageMeter{}
+	ctx = llm.WithUsageMeter(ctx, meter)
+
+	// Sampling and context-window settings configured for this task type
+	ctx = llm.WithOptions(ctx, m.cfg.TaskOptions(taskType))
+	ctx = llm.WithThinkingProfiles(ctx, ThinkingProfiles(m.cfg))
+	result.Options = llm.ThinkingOptions(ctx, thinkingMode)
+	result.ThinkingMode = thinkingMode
+
 	log.Printf("Executing task with %s thinking mode", thinkingMode)
 
-	for attempt := 0; attempt <= m.cfg.MaxRetries; attempt++ {
-		output, lastErr = m.client.GenerateWithThinking(model, prompt, thinkingMode)
+	// Try the thinking mode's model (if it has one), the task's model, then its
+	// fallback chain, until one succeeds
+	models := append([]string{model}, m.cfg.ModelFallbacks[taskType]...)
+	if modeModel := llm.ThinkingModel(ctx, thinkingMode, model); modeModel != model {
+		models = append([]string{modeModel}, models...)
+	}
+	if manifest != nil {
+		// One call per planned file
+		var run *fileRun
+		run, lastErr = m.generateFiles(ctx, models, input, manifest, thinkingMode, onToken)
+		output, thinking = run.Output, run.Thinking
+		result.files = run.Files
+		result.Coverage = run.Coverage
 		if lastErr == nil {
-			break
+			result.Model = run.Model
 		}
-
-		if attempt < m.cfg.MaxRetries {
-			time.Sleep(time.Second * time.Duration(attempt+1)) // Exponential backoff
+	} else {
+		var used string
+		output, thinking, used, lastErr = m.generate(ctx, models, taskType, prompt, thinkingMode, onToken)
+		if lastErr == nil {
+			result.Model = used
 		}
 	}
 
 	if lastErr != nil {
 		result.Error = lastErr.Error()
+		result.Duration = time.Since(start).Seconds()
+		usage := meter.Total()
+		result.Usage = &usage
+		m.addToHistory(result)
 		return result, lastErr
 	}
 
 	result.Output = output
+	result.Thinking = thinking
 	result.Duration = time.Since(start).Seconds()
+	usage := meter.Total()
+	result.Usage = &usage
 
 	// Save artifact
-	artifactPath, err := m.saveArtifact(result)
-	if err != nil {
+	ReportProgress(ctx, "saving", "")
+	if err := m.saveArtifact(ctx, result, projectDirFrom(ctx)); err != nil {
 		// Non-fatal - still return the result
 		result.Error = fmt.Sprintf("artifact save failed: %v", err)
-	} else {
-		result.ArtifactPath = artifactPath
 	}
 
 	// Add to history
@@ -114,719 +318,51 @@ func (m *Manager) ExecuteTaskWithThinking(taskType, input, thinkingMode string)
 	return result, nil
 }
 
-// buildPrompt constructs the prompt for each task type
-func (m *Manager) buildPrompt(taskType, input string) (string, error) {
-	switch taskType {
-	case "validate":
-		return m.buildValidationPrompt(input), nil
-	case "review":
-		return m.buildReviewPrompt(input), nil
-	case "code":
-		return m.buildCodePrompt(input), nil
-	default:
-		return "", fmt.Errorf("unknown task type: %s", taskType)
+// generate runs a prompt on each model in turn until one succeeds, returning the output,
+// reasoning trace and the model that produced them
+func (m *Manager) generate(ctx context.Context, models []string, taskType, prompt, thinkingMode string, onToken llm.TokenHandler) (string, string, string, error) {
+	stream := llm.NewRetryStream(ctx, onToken)
+	var lastErr error
+	for i, candidate := range models {
+		ReportProgress(ctx, "generating", candidate)
+		output, thinking, err := m.generateWithRetries(ctx, candidate, prompt, thinkingMode, stream)
+		if err == nil {
+			return output, thinking, candidate, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return "", "", "", ctx.Err()
+		}
+		if i+1 < len(models) {
+			log.Printf("Model %s failed for %s task (%v), falling back to %s", candidate, taskType, err, models[i+1])
+		}
 	}
+	return "", "", "", lastErr
 }
 
-// buildValidationPrompt creates prompt for idea validation
-func (m *Manager) buildValidationPrompt(input string) string {
-	return fmt.Sprintf(`You are a game design consultant. Analyze the following game idea and provide structured feedback.
-
-Game Idea:
-%s
-
-Provide your analysis in the following format:
-
-## Core Concept
-[1-2 sentence summary of the idea]
-
-## Strengths
-- [List 2-3 key strengths]
-
-## Potential Issues
-- [List 2-3 concerns or challenges]
-
-## Market Viability
-[Brief assessment of target audience and market fit]
-
-## Recommendation
-[Clear recommendation: Proceed, Revise, or Reconsider]
-
-## Next Steps
-[2-3 concrete next steps if proceeding]
-
-Be direct and honest. Focus on actionable insights.`, input)
-}
-
-// buildReviewPrompt creates prompt for architecture review
-func (m *Manager) buildReviewPrompt(input string) string {
-	return fmt.Sprintf(`You are a senior software architect and technical reviewer. Review the following architecture, code, or technical proposal.
-
-Document to Review:
-%s
-
-Provide your review in the following format:
-
-## Summary
-[1-2 sentence overview of what's being reviewed]
-
-## Tech Stack Analysis
-- [Evaluate technology choices - are they appropriate?]
-- [Are there better alternatives for this use case?]
-- [Consider: performance, maintainability, ecosystem, learning curve]
-
-## Architecture Assessment
-- [Evaluate overall design and structure]
-- [Identify architectural strengths]
-
-## Risk Assessment
-- [Technical risks, performance concerns, scalability issues]
-- [Security considerations]
-- [Maintenance and long-term viability]
-
-## Code Quality (if applicable)
-- [Code structure, readability, best practices]
-- [Potential bugs or issues]
-
-## Recommendations
-- [Specific improvements with rationale]
-- [Alternative approaches to consider]
-
-## Standards Compliance
-- [Does this meet production standards?]
-- [What needs to change before approval?]
-
-## Verdict
-[Overall assessment: Approved, Approved with Changes, Needs Revision, or Rejected]
-
-Be specific and technical. Focus on practical implications and real-world viability.`, input)
-}
-
-// buildCodePrompt creates prompt for code generation with intelligent tech stack selection
-func (m *Manager) buildCodePrompt(input string) string {
-	return fmt.Sprintf(`You are an expert full-stack software architect with deep knowledge across multiple domains:
-
-**Game Development:**
-- Unity/C#, Godot/GDScript, Unreal/C++
-- 2D/3D engines, game mechanics, physics
-
-**Mobile Development:**
-- Flutter/Dart (cross-platform)
-- React Native, Swift (iOS), Kotlin (Android)
-
-**Web Development:**
-- React/TypeScript, Vue, Next.js
-- Node.js, Python FastAPI, Go backends
+// generateWithRetries runs a prompt on one model, retrying with backoff
+// A model the backend doesn't have is not retried. Each attempt streams through
+// stream, which resets clients that saw output from an earlier attempt.
+func (m *Manager) generateWithRetries(ctx context.Context, model, prompt, thinkingMode string, stream *llm.RetryStream) (string, string, error) {
+	var output, thinking string
+	var err error
 
-**Desktop Applications:**
-- Electron, Python/Tkinter, C#/.NET, Rust
-
-**Backend Services:**
-- Go, Python, Node.js
-- REST APIs, databases, authentication
-
-Project Request:
-%s
-
-Your task:
-1. **Analyze the request** to determine:
-   - Project type (game, mobile app, web app, backend, desktop tool, etc.)
-   - Complexity level (prototype, MVP, production)
-   - Target platform(s)
-   - Key requirements
-
-2. **Select optimal tech stack:**
-   - Choose the BEST language and framework for this specific use case
-   - Prioritize: solo developer friendliness, modern ecosystem, cross-platform when beneficial
-   - Consider: performance needs, learning curve, maintenance
-
-3. **Generate production-quality code:**
-   - Follow best practices for chosen language
-   - Include comments explaining key decisions
-   - Structure code clearly and maintainably
-   - Include error handling where appropriate
-
-**CRITICAL REQUIREMENTS - READ CAREFULLY:**
-1. DO NOT generate a README template with placeholders like "Give examples" or "Add examples"
-2. DO NOT output generic instructions or placeholder text
-3. YOU MUST generate ACTUAL, COMPLETE, RUNNABLE source code
-4. Every file must contain real implementation code, NOT TODOs or placeholders
-5. The code must be production-quality and immediately executable
-6. If you generate a README, it must have ACTUAL setup instructions, not placeholder text
-
-4. **Provide complete output in this format:**
-
-## Tech Stack Decision
-**Project Type:** [Game/Mobile/Web/Backend/Desktop/etc.]
-**Language:** [Chosen language]
-**Framework/Engine:** [Chosen framework]
-**Rationale:** [2-3 sentences explaining why this stack is optimal for this request]
-
-## Implementation
-
-**CRITICAL: You MUST use this EXACT format for EVERY file:**
-
-### filename.ext
-` + "```" + `[language]
-[COMPLETE file content - NO PLACEHOLDERS, NO TODOS, ACTUAL WORKING CODE]
-` + "```" + `
-
-**REQUIREMENTS FOR EVERY FILE:**
-- Use ### followed by the filename with extension (e.g., ### src/App.jsx)
-- Wrap code in triple backticks with language specified
-- Include COMPLETE, WORKING code - not comments like "// Add implementation here"
-- Every function must have a real implementation, not just a comment
-- If generating React components, write the FULL component with actual JSX and logic
-
-**For web projects, you MUST create separate files:**
-- index.html (main HTML structure)
-- css/styles.css (all styling)
-- js/app.js (all JavaScript logic)
-- README.md (setup instructions)
-
-**For full-stack web projects, ALSO include backend:**
-- backend/server.js (or server.py, main.go) - Main server file
-- backend/routes/ - API route handlers
-- backend/models/ - Data models (if using database)
-- backend/.env.example - Environment variable template
-- backend/package.json (or requirements.txt, go.mod) - Dependencies
-
-**For backend/API projects:**
-- server.js (or main.go, app.py) - Main entry point
-- routes/ - API endpoints
-- controllers/ - Business logic
-- models/ - Data models
-- middleware/ - Authentication, error handling
-- config/ - Configuration files
-- .env.example - Environment variables
-- README.md - Setup and API documentation
-
-**For projects requiring a database, ALSO include:**
-- database/schema.sql (or schema.prisma) - Database schema definition
-- database/migrations/ - Migration files for schema changes
-- models/ - ORM models (Sequelize, Prisma, TypeORM, GORM)
-- database/seeds/ - Initial data/fixtures (optional)
-- database/connection.js (or db.js, database.go) - Database connection setup
-- Include database URL in .env.example
-
-**For React/Vite projects (MUST include all these files with REAL code):**
-- package.json (with vite, react, vitest dependencies)
-- vite.config.js (Vite configuration)
-- index.html (entry HTML file)
-- src/main.jsx (React entry point)
-- src/App.jsx (main App component with REAL functionality)
-- src/App.css (actual styles)
-- src/index.css (global styles)
-- src/App.test.jsx (Vitest tests)
-- README.md (ACTUAL setup instructions, not placeholders)
-
-**For other projects, organize logically:**
-- Separate concerns (UI, logic, data, config)
-- Follow the chosen framework's best practices
-- Include README.md with setup instructions
-
-**Example multi-file output:**
-
-### index.html
-` + "```" + `html
-<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>App Name</title>
-    <link rel="stylesheet" href="css/styles.css">
-</head>
-<body>
-    <!-- HTML content -->
-    <script src="js/app.js"></script>
-</body>
-</html>
-` + "```" + `
-
-### css/styles.css
-` + "```" + `css
-/* Stylesheet content */
-body {
-    margin: 0;
-    padding: 0;
-}
-` + "```" + `
-
-### js/app.js
-` + "```" + `javascript
-// JavaScript logic
-console.log('App initialized');
-` + "```" + `
-
-### README.md
-` + "```" + `markdown
-# Project Name
-
-## Setup Instructions
-1. [Steps to run]
-2. [Dependencies needed]
-
-## Usage
-[How to use the application]
-` + "```" + `
-
-**COMPLETE React/Vite Project Example (use this structure for React projects):**
-
-### package.json
-` + "```" + `json
-{
-  "name": "react-app",
-  "version": "1.0.0",
-  "type": "module",
-  "scripts": {
-    "dev": "vite",
-    "build": "vite build",
-    "preview": "vite preview",
-    "test": "vitest"
-  },
-  "dependencies": {
-    "react": "^18.2.0",
-    "react-dom": "^18.2.0"
-  },
-  "devDependencies": {
-    "@vitejs/plugin-react": "^4.0.0",
-    "vite": "^4.3.9",
-    "vitest": "^0.32.0",
-    "@testing-library/react": "^14.0.0",
-    "@testing-library/jest-dom": "^6.1.0"
-  }
-}
-` + "```" + `
-
-### vite.config.js
-` + "```" + `javascript
-import { defineConfig } from 'vite'
-import react from '@vitejs/plugin-react'
-
-export default defineConfig({
-  plugins: [react()],
-  server: { port: 5173 }
-})
-` + "```" + `
-
-### index.html
-` + "```" + `html
-<!DOCTYPE html>
-<html lang="en">
-  <head>
-    <meta charset="UTF-8" />
-    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
-    <title>React App</title>
-  </head>
-  <body>
-    <div id="root"></div>
-    <script type="module" src="/src/main.jsx"></script>
-  </body>
-</html>
-` + "```" + `
-
-### src/main.jsx
-` + "```" + `javascript
-import React from 'react'
-import ReactDOM from 'react-dom/client'
-import App from './App'
-import './index.css'
-
-ReactDOM.createRoot(document.getElementById('root')).render(
-  <React.StrictMode>
-    <App />
-  </React.StrictMode>
-)
-` + "```" + `
-
-### src/App.jsx
-` + "```" + `javascript
-import { useState } from 'react'
-import './App.css'
-
-function App() {
-  const [count, setCount] = useState(0)
-
-  return (
-    <div className="App">
-      <h1>React App</h1>
-      <button onClick={() => setCount(count + 1)}>
-        Count: {count}
-      </button>
-    </div>
-  )
-}
-
-export default App
-` + "```" + `
-
-### src/App.test.jsx
-` + "```" + `javascript
-import { describe, it, expect } from 'vitest'
-import { render, screen } from '@testing-library/react'
-import '@testing-library/jest-dom'
-import App from './App'
-
-describe('App', () => {
-  it('renders without crashing', () => {
-    render(<App />)
-    expect(screen.getByText('React App')).toBeInTheDocument()
-  })
-
-  it('displays count button', () => {
-    render(<App />)
-    expect(screen.getByRole('button')).toBeInTheDocument()
-  })
-})
-` + "```" + `
-
-### src/App.css
-` + "```" + `css
-.App {
-  text-align: center;
-  padding: 2rem;
-}
-
-button {
-  padding: 0.5rem 1rem;
-  font-size: 1rem;
-  cursor: pointer;
-}
-` + "```" + `
-
-### src/index.css
-` + "```" + `css
-body {
-  margin: 0;
-  padding: 0;
-  font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', 'Roboto', sans-serif;
-}
-
-#root {
-  min-height: 100vh;
-}
-` + "```" + `
-
-**For projects requiring a backend, also include backend files:**
-
-### backend/server.js
-` + "```" + `javascript
-const express = require('express');
-const app = express();
-
-app.use(express.json());
-
-app.get('/api/data', (req, res) => {
-    res.json({ message: 'API response' });
-});
-
-const PORT = process.env.PORT || 3000;
-app.listen(PORT, () => console.log(\` + "`" + `Server running on port ${PORT}\` + "`" + `));
-` + "```" + `
-
-### backend/package.json
-` + "```" + `json
-{
-  "name": "backend",
-  "version": "1.0.0",
-  "main": "server.js",
-  "dependencies": {
-    "express": "^4.18.0"
-  }
-}
-` + "```" + `
-
-### backend/.env.example
-` + "```" + `
-PORT=3000
-DATABASE_URL=your_database_url_here
-` + "```" + `
-
-**For projects with databases, also include schema and models:**
-
-### database/schema.sql
-` + "```" + `sql
-CREATE TABLE IF NOT EXISTS users (
-    id SERIAL PRIMARY KEY,
-    username VARCHAR(255) NOT NULL UNIQUE,
-    email VARCHAR(255) NOT NULL UNIQUE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS posts (
-    id SERIAL PRIMARY KEY,
-    user_id INTEGER REFERENCES users(id),
-    title VARCHAR(255) NOT NULL,
-    content TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-` + "```" + `
-
-### models/User.js
-` + "```" + `javascript
-const { DataTypes } = require('sequelize');
-
-module.exports = (sequelize) => {
-    return sequelize.define('User', {
-        username: {
-            type: DataTypes.STRING,
-            allowNull: false,
-            unique: true
-        },
-        email: {
-            type: DataTypes.STRING,
-            allowNull: false,
-            unique: true
-        }
-    });
-};
-` + "```" + `
-
-### database/connection.js
-` + "```" + `javascript
-const { Sequelize } = require('sequelize');
-require('dotenv').config();
-
-const sequelize = new Sequelize(process.env.DATABASE_URL, {
-    dialect: 'postgres',
-    logging: false
-});
-
-module.exports = sequelize;
-` + "```" + `
-
-**For projects requiring authentication, ALSO include:**
-
-### middleware/auth.js
-` + "```" + `javascript
-const jwt = require('jsonwebtoken');
-
-module.exports = (req, res, next) => {
-    const token = req.header('Authorization')?.replace('Bearer ', '');
-
-    if (!token) {
-        return res.status(401).json({ error: 'Access denied' });
-    }
-
-    try {
-        const verified = jwt.verify(token, process.env.JWT_SECRET);
-        req.user = verified;
-        next();
-    } catch (err) {
-        res.status(400).json({ error: 'Invalid token' });
-    }
-};
-` + "```" + `
-
-### routes/auth.js
-` + "```" + `javascript
-const express = require('express');
-const bcrypt = require('bcryptjs');
-const jwt = require('jsonwebtoken');
-const User = require('../models/User');
-
-const router = express.Router();
-
-router.post('/register', async (req, res) => {
-    try {
-        const { username, email, password } = req.body;
-
-        const hashedPassword = await bcrypt.hash(password, 10);
-        const user = await User.create({
-            username,
-            email,
-            password: hashedPassword
-        });
-
-        res.status(201).json({ message: 'User created', userId: user.id });
-    } catch (err) {
-        res.status(400).json({ error: err.message });
-    }
-});
-
-router.post('/login', async (req, res) => {
-    try {
-        const { email, password } = req.body;
-        const user = await User.findOne({ where: { email } });
-
-        if (!user) {
-            return res.status(400).json({ error: 'Invalid credentials' });
-        }
-
-        const validPassword = await bcrypt.compare(password, user.password);
-        if (!validPassword) {
-            return res.status(400).json({ error: 'Invalid credentials' });
-        }
-
-        const token = jwt.sign({ id: user.id }, process.env.JWT_SECRET);
-        res.json({ token });
-    } catch (err) {
-        res.status(500).json({ error: err.message });
-    }
-});
-
-module.exports = router;
-` + "```" + `
-
-**For production deployment, ALSO include:**
-
-### Dockerfile
-` + "```" + `dockerfile
-FROM node:18-alpine
-WORKDIR /app
-COPY package*.json ./
-RUN npm ci --only=production
-COPY . .
-EXPOSE 3000
-CMD ["node", "server.js"]
-` + "```" + `
-
-### docker-compose.yml
-` + "```" + `yaml
-version: '3.8'
-services:
-  app:
-    build: .
-    ports:
-      - "3000:3000"
-    environment:
-      - NODE_ENV=production
-      - DATABASE_URL=postgresql://user:password@db:5432/dbname
-      - JWT_SECRET=your-secret-key
-    depends_on:
-      - db
-
-  db:
-    image: postgres:15-alpine
-    environment:
-      - POSTGRES_USER=user
-      - POSTGRES_PASSWORD=password
-      - POSTGRES_DB=dbname
-    volumes:
-      - postgres_data:/var/lib/postgresql/data
-
-volumes:
-  postgres_data:
-` + "```" + `
-
-### .dockerignore
-` + "```" + `
-node_modules
-npm-debug.log
-.env
-.git
-.gitignore
-` + "```" + `
-
-### DEPLOYMENT.md
-` + "```" + `markdown
-# Deployment Guide
-
-## Option 1: Docker (Recommended)
-
-1. Build and run with Docker Compose:
-   \` + "`" + `\` + "`" + `\` + "`" + `bash
-   docker-compose up -d
-   \` + "`" + `\` + "`" + `\` + "`" + `
-
-2. Check logs:
-   \` + "`" + `\` + "`" + `\` + "`" + `bash
-   docker-compose logs -f
-   \` + "`" + `\` + "`" + `\` + "`" + `
-
-## Option 2: Railway
-
-1. Install Railway CLI: \` + "`" + `npm i -g @railway/cli\` + "`" + `
-2. Login: \` + "`" + `railway login\` + "`" + `
-3. Initialize: \` + "`" + `railway init\` + "`" + `
-4. Add PostgreSQL: \` + "`" + `railway add\` + "`" + `
-5. Deploy: \` + "`" + `railway up\` + "`" + `
-
-## Option 3: Vercel (Frontend) + Railway (Backend)
-
-**Frontend (Vercel):**
-1. Push to GitHub
-2. Import project on vercel.com
-3. Set environment variables
-
-**Backend (Railway):**
-1. Connect GitHub repo
-2. Add PostgreSQL database
-3. Set environment variables
-4. Deploy automatically on push
-
-## Environment Variables
-
-Required variables:
-- \` + "`" + `PORT\` + "`" + ` - Server port (default: 3000)
-- \` + "`" + `DATABASE_URL\` + "`" + ` - PostgreSQL connection string
-- \` + "`" + `JWT_SECRET\` + "`" + ` - Secret key for JWT tokens
-- \` + "`" + `NODE_ENV\` + "`" + ` - Environment (production/development)
-` + "```" + `
-
-**For Python/FastAPI projects, use similar structure:**
-
-### main.py
-` + "```" + `python
-from fastapi import FastAPI, HTTPException
-from pydantic import BaseModel
-
-app = FastAPI()
-
-class Task(BaseModel):
-    title: str
-    description: str
-
-@app.get("/api/tasks")
-async def get_tasks():
-    return {"tasks": []}
-
-@app.post("/api/tasks")
-async def create_task(task: Task):
-    return {"id": 1, **task.dict()}
-
-if __name__ == "__main__":
-    import uvicorn
-    uvicorn.run(app, host="0.0.0.0", port=8000)
-` + "```" + `
-
-**For Go projects, use similar structure:**
-
-### main.go
-` + "```" + `go
-package main
-
-import (
-    "encoding/json"
-    "net/http"
-    "github.com/gorilla/mux"
-)
-
-type Task struct {
-    ID          int    \` + "`" + `json:"id"\` + "`" + `
-    Title       string \` + "`" + `json:"title"\` + "`" + `
-    Description string \` + "`" + `json:"description"\` + "`" + `
-}
-
-func getTasks(w http.ResponseWriter, r *http.Request) {
-    json.NewEncoder(w).Encode([]Task{})
-}
-
-func main() {
-    r := mux.NewRouter()
-    r.HandleFunc("/api/tasks", getTasks).Methods("GET")
-    http.ListenAndServe(":8000", r)
-}
-` + "```" + `
-
-## Setup Instructions
-[Brief summary - detailed instructions should be in README.md]
+	for attempt := 0; attempt <= m.cfg.MaxRetries; attempt++ {
+		output, thinking, err = llm.GenerateWithThinkingStream(ctx, m.client, model, prompt, thinkingMode, stream.Attempt(model))
+		if err == nil || ctx.Err() != nil || errors.Is(err, llm.ErrModelNotFound) {
+			break
+		}
 
-## Next Steps
-[What to implement next to expand this project]
+		if attempt < m.cfg.MaxRetries {
+			// Exponential backoff
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second * time.Duration(attempt+1)):
+			}
+		}
+	}
 
-Focus on practical, working code with proper file organization that a solo developer can immediately use and understand. Always separate HTML, CSS, and JavaScript into different files for web projects. For Python projects, use virtual environments. For Go projects, use Go modules.`, input)
+	return output, thinking, err
 }
 
 // FileContent represents a parsed file from LLM output
@@ -835,71 +371,43 @@ type FileContent struct {
 	Content string
 }
 
-// ParseFilesFromOutput extracts multiple files from LLM output
-// Looks for patterns like "### filename.ext" or "#### filename.ext" followed by code blocks
+// ParseFilesFromOutput extracts generated files from model output with the default extractor chain
 func ParseFilesFromOutput(output string) []FileContent {
-	files := make([]FileContent, 0)
-
-	// Pattern: ### filename.ext or #### filename.ext
-	// We want to be strict to avoid matching generic headers. 
-	// We look for a filename that HAS an extension.
-	fileMarkerRegex := regexp.MustCompile(`###+\s+([^\s\n(]+\.[a-zA-Z0-9]+).*\n`)
-	// Use (?s) flag for DOTALL mode - allows . to match newlines
-	codeBlockRegex := regexp.MustCompile("(?s)```[a-zA-Z0-9]*\\n(.*?)```")
+	return DefaultExtractorChain().Extract(output).Files
+}
 
-	// Find all file markers
-	fileMarkers := fileMarkerRegex.FindAllStringSubmatchIndex(output, -1)
+// RegisterExtractor adds a file extractor to the chain used for code task output
+func (m *Manager) RegisterExtractor(e Extractor) {
+	m.extractors.Register(e)
+}
 
-	if len(fileMarkers) == 0 {
-		// No multi-file format found, return empty
-		return files
+// saveArtifact records the task result in the artifact store
+// For code generation tasks, it detects multi-file projects and saves them properly;
+// given a projectDir, the output is applied to that project as a patch instead.
+// The result's ArtifactID, ArtifactPath and ProjectDir are set from the saved artifact.
+func (m *Manager) saveArtifact(ctx context.Context, result *Result, projectDir string) error {
+	if m.artifacts == nil {
+		return fmt.Errorf("artifact store unavailable")
 	}
 
-	for i, match := range fileMarkers {
-		// Extract filename from capture group
-		filenameStart := match[2]
-		filenameEnd := match[3]
-		filename := output[filenameStart:filenameEnd]
-
-		// Find the content after this marker (until next marker or end)
-		contentStart := match[1] // End of the marker line
-		contentEnd := len(output)
-
-		// If there's a next marker, content ends there
-		if i+1 < len(fileMarkers) {
-			contentEnd = fileMarkers[i+1][0]
-		}
-
-		contentSection := output[contentStart:contentEnd]
-
-		// Extract code from code block
-		codeMatch := codeBlockRegex.FindStringSubmatch(contentSection)
-		if len(codeMatch) > 1 {
-			content := strings.TrimSpace(codeMatch[1])
-			files = append(files, FileContent{
-				Path:    filename,
-				Content: content,
-			})
-		}
+	artifact, err := m.artifacts.Create(result.TaskType, result.Timestamp)
+	if err != nil {
+		return err
 	}
-
-	return files
-}
-
-// saveArtifact saves the task result to a file
-// For code generation tasks, it detects multi-file projects and saves them properly
-func (m *Manager) saveArtifact(result *Result) (string, error) {
-	filename := fmt.Sprintf("%s_%d.md",
-		result.TaskType,
-		result.Timestamp.Unix())
-
-	path := m.cfg.GetArtifactPath(filename)
+	artifact.Model = result.Model
+	artifact.ProjectID = llm.ProjectFrom(ctx)
+	artifact.InputHash = hashString(result.Input)
+	artifact.OutputHash = hashString(result.Output)
+	path := artifact.Path
 
 	// Create artifact content
 	content := fmt.Sprintf(`# Task Result: %s
 
+**Artifact:** %s
 **Timestamp:** %s
 **Model:** %s
+**Options:** %s
+**Thinking mode:** %s
 **Duration:** %.2fs
 
 ## Input
@@ -909,79 +417,144 @@ func (m *Manager) saveArtifact(result *Result) (string, error) {
 %s
 `,
 		result.TaskType,
+		artifact.ID,
 		result.Timestamp.Format(time.RFC3339),
 		result.Model,
+		formatOptions(result.Options),
+		result.ThinkingMode,
 		result.Duration,
 		result.Input,
 		result.Output,
 	)
 
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
 	// Write artifact file
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write artifact: %w", err)
+		os.Remove(path)
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	result.ArtifactID = artifact.ID
+	result.ArtifactPath = path
+
+	// The reasoning trace goes next to the artifact, not into it
+	if result.Thinking != "" {
+		thinkingPath := strings.TrimSuffix(path, ".md") + ".thinking.md"
+		trace := fmt.Sprintf("# Reasoning: %s\n\n**Model:** %s\n\n%s\n", result.TaskType, result.Model, result.Thinking)
+		if err := os.WriteFile(thinkingPath, []byte(trace), 0644); err != nil {
+			log.Printf("Failed to save reasoning trace: %v", err)
+		} else {
+			result.ThinkingPath = thinkingPath
+			artifact.ThinkingPath = thinkingPath
+		}
 	}
 
-	// For code generation tasks, check if multi-file format exists
 	if result.TaskType == "code" {
-		files := ParseFilesFromOutput(result.Output)
+		m.saveGeneratedFiles(result, artifact, projectDir)
+		result.ProjectDir = artifact.GeneratedDir
+	}
 
-		// Validate parsed files - detect README template errors
-		if len(files) == 0 {
-			log.Printf("[WARN] No files parsed from output. First 500 chars: %s",
-				truncateString(result.Output, 500))
-		} else {
-			// Check for README template indicators
-			templateIndicators := []string{
-				"Give examples",
-				"Add examples",
-				"Add_Names",
-				"Add_inspiration",
-				"your-repo-link",
-				"your-directory-name",
-			}
+	return m.artifacts.Save(artifact)
+}
 
-			hasTemplateError := false
-			for _, file := range files {
-				for _, indicator := range templateIndicators {
-					if strings.Contains(file.Content, indicator) {
-						hasTemplateError = true
-						log.Printf("[ERROR] Detected README template in file %s: contains '%s'",
-							file.Path, indicator)
-					}
+// saveGeneratedFiles writes the files of a code task to a new project directory, or
+// patches projectDir, recording the directory and files written in the artifact
+func (m *Manager) saveGeneratedFiles(result *Result, artifact *Artifact, projectDir string) {
+	// Incremental code generation: apply the changed files to the existing project
+	if projectDir != "" {
+		report, err := m.applyPatchOutput(projectDir, result.Output)
+		result.Patch = report
+		for _, d := range report.Diagnostics {
+			log.Printf("[WARN] File extraction (%s): %s: %s", d.Extractor, d.Path, d.Message)
+		}
+		if err != nil {
+			report.Error = err.Error()
+			artifact.Note = fmt.Sprintf("patch failed, project %s unchanged: %v", projectDir, err)
+			log.Printf("[WARN] Patch of %s failed: %v", projectDir, err)
+			return
+		}
+		log.Printf("✓ Patched %s: %d file(s) changed, %d hunk(s) failed", projectDir, report.Changed(), len(report.FailedHunks))
+
+		artifact.GeneratedDir = projectDir
+		artifact.Patched = true
+		var changed []FileContent
+		for _, paths := range [][]string{report.Modified, report.Replaced, report.Created} {
+			for _, p := range paths {
+				if data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(p))); err == nil {
+					changed = append(changed, FileContent{Path: p, Content: string(data)})
 				}
 			}
+		}
+		artifact.Files = artifactFiles(changed)
+		return
+	}
 
-			if hasTemplateError {
-				log.Printf("[ERROR] Code generation produced README template instead of actual code")
-				// Clear files array to prevent saving template fragments
-				files = []FileContent{}
-			}
+	// Per-file tasks already have their files; otherwise find them in the output
+	files := result.files
+	if files == nil {
+		report := m.extractors.Extract(result.Output)
+		result.Extraction = report
+		for _, d := range report.Diagnostics {
+			log.Printf("[WARN] File extraction (%s): %s: %s", d.Extractor, d.Path, d.Message)
 		}
+		files = report.Files
+	}
 
-		if len(files) > 0 {
-			// Multi-file project detected - save to projects directory
-			projectDir := filepath.Join("projects", fmt.Sprintf("generated_%d", result.Timestamp.Unix()))
+	// Validate parsed files - detect README template errors
+	if len(files) == 0 {
+		log.Printf("[WARN] No files parsed from output. First 500 chars: %s",
+			truncateString(result.Output, 500))
+	} else {
+		// Check for README template indicators
+		templateIndicators := []string{
+			"Give examples",
+			"Add examples",
+			"Add_Names",
+			"Add_inspiration",
+			"your-repo-link",
+			"your-directory-name",
+		}
 
-			if err := m.saveMultiFileProject(projectDir, files); err != nil {
-				// Non-fatal - artifact is already saved
-				return path + fmt.Sprintf(" (multi-file save failed: %v)", err), nil
+		hasTemplateError := false
+		for _, file := range files {
+			for _, indicator := range templateIndicators {
+				if strings.Contains(file.Content, indicator) {
+					hasTemplateError = true
+					log.Printf("[ERROR] Detected README template in file %s: contains '%s'",
+						file.Path, indicator)
+				}
 			}
+		}
 
-			// Return path indicating multi-file project was created
-			return fmt.Sprintf("%s (project: %s)", path, projectDir), nil
-		} else if result.TaskType == "code" {
-			// No files extracted - likely a template or error
-			return path + " (no files extracted - check artifact for template errors)", nil
+		if hasTemplateError {
+			log.Printf("[ERROR] Code generation produced README template instead of actual code")
+			// Clear files array to prevent saving template fragments
+			files = []FileContent{}
 		}
 	}
 
-	return path, nil
+	if len(files) == 0 {
+		// No files extracted - likely a template or error
+		artifact.Note = "no files extracted - check artifact for template errors"
+		return
+	}
+
+	// Multi-file project detected - save to projects directory
+	dir := GeneratedDirFor(artifact)
+	if err := m.saveMultiFileProject(dir, files); err != nil {
+		// Non-fatal - artifact is already saved
+		artifact.Note = fmt.Sprintf("multi-file save failed: %v", err)
+		log.Printf("[WARN] Failed to save generated project: %v", err)
+		return
+	}
+	artifact.GeneratedDir = dir
+	artifact.Files = artifactFiles(files)
+}
+
+// QueryArtifacts returns the saved artifacts matching q, newest first
+func (m *Manager) QueryArtifacts(q ArtifactQuery) []Artifact {
+	if m.artifacts == nil {
+		return []Artifact{}
+	}
+	return m.artifacts.Query(q)
 }
 
 // saveMultiFileProject saves parsed files to a project directory
@@ -993,7 +566,10 @@ func (m *Manager) saveMultiFileProject(projectDir string, files []FileContent) e
 
 	// Save each file
 	for _, file := range files {
-		fullPath := filepath.Join(projectDir, file.Path)
+		fullPath, err := SafeJoin(projectDir, file.Path)
+		if err != nil {
+			return err
+		}
 
 		// Create subdirectories if needed (e.g., css/, js/)
 		fileDir := filepath.Dir(fullPath)
@@ -1010,6 +586,36 @@ func (m *Manager) saveMultiFileProject(projectDir string, files []FileContent) e
 	return nil
 }
 
+// ThinkingProfiles converts the configured thinking modes to LLM profiles
+func ThinkingProfiles(cfg *config.Config) map[string]llm.ThinkingProfile {
+	if len(cfg.ThinkingModes) == 0 {
+		return nil
+	}
+
+	profiles := make(map[string]llm.ThinkingProfile, len(cfg.ThinkingModes))
+	for mode, mc := range cfg.ThinkingModes {
+		profiles[mode] = llm.ThinkingProfile{
+			Think:   mc.Think,
+			Model:   mc.Model,
+			Options: mc.Options.Map(),
+		}
+	}
+	return profiles
+}
+
+// formatOptions renders generation options for an artifact header
+// json.Marshal sorts the keys, so identical options always read the same
+func formatOptions(options map[string]interface{}) string {
+	if len(options) == 0 {
+		return "model defaults"
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Sprintf("%v", options)
+	}
+	return "`" + string(data) + "`"
+}
+
 // truncateString truncates string to maxLen characters
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -1023,38 +629,34 @@ func (m *Manager) Ping() error {
 	return m.client.Ping()
 }
 
-// addToHistory adds a task result to the history (ring buffer)
+// addToHistory persists a task result to the history file
 func (m *Manager) addToHistory(result *Result) {
-	m.historyMux.Lock()
-	defer m.historyMux.Unlock()
-
-	m.history = append(m.history, *result)
-	if len(m.history) > m.maxHistory {
-		m.history = m.history[1:] // Remove oldest
+	if m.history == nil {
+		return
+	}
+	if err := m.history.Append(result); err != nil {
+		log.Printf("Warning: failed to record task history: %v", err)
 	}
 }
 
-// GetHistory returns task history, optionally filtered by task type
-func (m *Manager) GetHistory(taskType string) []Result {
-	m.historyMux.RLock()
-	defer m.historyMux.RUnlock()
-
-	// Return in reverse chronological order
-	results := make([]Result, 0, len(m.history))
-	for i := len(m.history) - 1; i >= 0; i-- {
-		result := m.history[i]
-		if taskType == "all" || result.TaskType == taskType {
-			results = append(results, result)
-		}
+// QueryHistory returns stored task results matching q, newest first
+func (m *Manager) QueryHistory(q HistoryQuery) (HistoryPage, error) {
+	if m.history == nil {
+		return HistoryPage{}, fmt.Errorf("task history is not available")
 	}
-	return results
+	return m.history.Query(q)
 }
 
-// GetClient returns the LLM client (for chat functionality)
-func (m *Manager) GetClient() *llm.Client {
+// GetClient returns the LLM provider (for chat functionality)
+func (m *Manager) GetClient() llm.Provider {
 	return m.client
 }
 
+// GetConfig returns the orchestrator configuration
+func (m *Manager) GetConfig() *config.Config {
+	return m.cfg
+}
+
 // GetWebSocketHub returns nil (Manager doesn't use WebSocket)
 func (m *Manager) GetWebSocketHub() interface{} {
 	return nil
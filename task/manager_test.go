@@ -0,0 +1,80 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+)
+
+// TestNewManagerMissingCassette tests that a replay cassette that can't be loaded is reported, not fatal
+func TestNewManagerMissingCassette(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		ArtifactsDir: filepath.Join(dir, "artifacts"),
+		HistoryPath:  filepath.Join(dir, "history.jsonl"),
+		Cassette:     config.CassetteConfig{Mode: "replay", Path: filepath.Join(dir, "missing.json")},
+	}
+
+	mgr, err := NewManager(cfg)
+	if err == nil || mgr != nil {
+		t.Fatalf("NewManager() = %v, %v; want an error", mgr, err)
+	}
+}
+
+// flakyProvider streams "partial " and fails for the first failures calls, then streams a full reply
+type flakyProvider struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyProvider) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		req.OnToken("partial ")
+		return nil, errors.New("model runner stopped")
+	}
+	req.OnToken("done")
+	return &llm.Response{Text: "done", Model: req.Model}, nil
+}
+
+func (p *flakyProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *flakyProvider) Ping() error                   { return nil }
+
+// TestGenerateResetsStream tests that retries and model fallbacks reset what the failed attempt streamed
+func TestGenerateResetsStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   int
+		want       string
+	}{
+		{"retry", 1, 1, "partial |reset:a|done"},
+		{"fallback", 0, 1, "partial |reset:b|done"},
+		{"first attempt", 1, 0, "done"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &flakyProvider{failures: tt.failures}
+			m := &Manager{cfg: &config.Config{MaxRetries: tt.maxRetries}, client: provider}
+
+			var events []string
+			ctx := llm.WithTokenReset(context.Background(), func(model string) { events = append(events, "reset:"+model) })
+			output, _, used, err := m.generate(ctx, []string{"a", "b"}, "code", "p", "", func(chunk string) { events = append(events, chunk) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output != "done" || (tt.name == "fallback") != (used == "b") {
+				t.Errorf("output %q from %s", output, used)
+			}
+			if got := strings.Join(events, "|"); got != tt.want {
+				t.Errorf("stream = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ai-studio/orchestrator/llm"
+)
+
+type fileManifestKey struct{}
+
+// WithFileManifest returns a context whose code tasks generate the listed files one call at a
+// time, each shown the interfaces of the files written before it, instead of the whole project at once
+func WithFileManifest(ctx context.Context, files []string) context.Context {
+	return context.WithValue(ctx, fileManifestKey{}, files)
+}
+
+// fileManifestFrom returns the file manifest attached to ctx (nil if none)
+func fileManifestFrom(ctx context.Context) []string {
+	files, _ := ctx.Value(fileManifestKey{}).([]string)
+	if len(files) == 0 {
+		return nil
+	}
+	return files
+}
+
+// FileCoverage compares the files of a per-file code task with its manifest
+type FileCoverage struct {
+	Manifest  []string `json:"manifest"` // Planned files, in generation order
+	Generated []string `json:"generated"`
+	Missing   []string `json:"missing,omitempty"`  // Planned files that could not be generated
+	Rejected  []string `json:"rejected,omitempty"` // Plan entries that are not file paths inside the project
+}
+
+// Complete reports whether every planned file was generated
+func (c *FileCoverage) Complete() bool {
+	return len(c.Missing) == 0
+}
+
+// FileInterface is the public surface of a generated file, shown to the calls that follow it
+type FileInterface struct {
+	Path    string
+	Summary string
+}
+
+// fileRun is the outcome of generating a manifest file by file
+type fileRun struct {
+	Output   string // Every file as a "### path" section, as a single-call task would return them
+	Thinking string
+	Model    string // Model of the last file generated
+	Files    []FileContent
+	Coverage *FileCoverage
+}
+
+// generateFiles generates each file of a manifest in its own call
+// A file that fails is recorded as missing and the rest still run; the task fails
+// only if no file could be generated or ctx is cancelled.
+func (m *Manager) generateFiles(ctx context.Context, models []string, input string, manifest []string, thinkingMode string, onToken llm.TokenHandler) (*fileRun, error) {
+	paths, rejected := fixManifest(manifest)
+	run := &fileRun{Coverage: &FileCoverage{Manifest: paths, Generated: []string{}, Rejected: rejected}}
+	for _, p := range rejected {
+		log.Printf("[WARN] Skipping planned file %q: not a file path inside the project", p)
+	}
+	if len(paths) == 0 {
+		return run, fmt.Errorf("file manifest has no usable paths")
+	}
+
+	var output, traces strings.Builder
+	var interfaces []FileInterface
+	for i, p := range paths {
+		// Progress of the model calls names the file they are for
+		fileCtx := WithProgress(ctx, func(stage, detail string) {
+			ReportProgress(ctx, stage, fmt.Sprintf("%s (%d/%d) %s", p, i+1, len(paths), detail))
+		})
+
+		prompt, err := m.renderPrompt(ctx, "code_file", PromptData{
+			TaskType:   "code",
+			Input:      input,
+			File:       p,
+			Manifest:   paths,
+			Interfaces: interfaces,
+		})
+		if err != nil {
+			return run, err
+		}
+
+		log.Printf("Generating file %d/%d: %s", i+1, len(paths), p)
+		text, thinking, model, err := m.generate(fileCtx, models, "code", prompt, thinkingMode, onToken)
+		if err != nil {
+			if ctx.Err() != nil {
+				return run, ctx.Err()
+			}
+			log.Printf("[WARN] Failed to generate %s: %v", p, err)
+			run.Coverage.Missing = append(run.Coverage.Missing, p)
+			continue
+		}
+
+		content := m.fileFromOutput(p, text)
+		if content == "" {
+			log.Printf("[WARN] Model returned no content for %s", p)
+			run.Coverage.Missing = append(run.Coverage.Missing, p)
+			continue
+		}
+
+		run.Model = model
+		run.Files = append(run.Files, FileContent{Path: p, Content: content})
+		run.Coverage.Generated = append(run.Coverage.Generated, p)
+		interfaces = append(interfaces, FileInterface{Path: p, Summary: fileInterface(p, content)})
+
+		fence := fenceFor(content)
+		fmt.Fprintf(&output, "### %s\n%s%s\n%s\n%s\n\n", p, fence, strings.TrimPrefix(path.Ext(p), "."), content, fence)
+		if thinking != "" {
+			fmt.Fprintf(&traces, "### %s\n\n%s\n\n", p, thinking)
+		}
+	}
+
+	run.Output = strings.TrimSpace(output.String())
+	run.Thinking = strings.TrimSpace(traces.String())
+
+	if len(run.Files) == 0 {
+		return run, fmt.Errorf("none of the %d planned files could be generated", len(paths))
+	}
+	if !run.Coverage.Complete() {
+		log.Printf("[WARN] Generated %d of %d planned files; missing: %s",
+			len(run.Coverage.Generated), len(paths), strings.Join(run.Coverage.Missing, ", "))
+	}
+	return run, nil
+}
+
+// fixManifest turns plan entries into the list of files to generate: cleaned, deduplicated,
+// without entries that aren't safe relative file paths, and with tests and docs last so they
+// see the interfaces of the code they cover
+func fixManifest(entries []string) ([]string, []string) {
+	var paths, rejected []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.Trim(strings.TrimSpace(entry), "`")
+		if entry == "" {
+			continue
+		}
+		p, err := SafeRelPath(entry)
+		if err != nil || strings.HasSuffix(entry, "/") {
+			rejected = append(rejected, entry)
+			continue
+		}
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool { return manifestRank(paths[i]) < manifestRank(paths[j]) })
+	return paths, rejected
+}
+
+// manifestRank orders source files before tests, and tests before docs
+func manifestRank(p string) int {
+	base := path.Base(p)
+	switch {
+	case strings.EqualFold(path.Ext(p), ".md"):
+		return 2
+	case strings.Contains(base, "_test.") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") || strings.HasPrefix(p, "tests/") || strings.HasPrefix(p, "test/") ||
+		strings.Contains(p, "/tests/") || strings.Contains(p, "__tests__/"):
+		return 1
+	}
+	return 0
+}
+
+// fileFromOutput returns the content of one file from a per-file response: the block labelled
+// with the file, else the first code block, else the whole response
+func (m *Manager) fileFromOutput(p, output string) string {
+	for _, f := range m.extractors.Extract(output).Files {
+		if f.Path == p {
+			return f.Content
+		}
+	}
+	if blocks := fencedBlocks(output); len(blocks) > 0 {
+		return strings.TrimSpace(blocks[0].Content)
+	}
+	return strings.TrimSpace(output)
+}
+
+// maxInterfaceLines bounds the summary of one file passed to later calls
+const maxInterfaceLines = 40
+
+var (
+	// declarationRegex matches the lines that make up a source file's public surface
+	declarationRegex = regexp.MustCompile(`^\s*(export\s|module\.exports|exports\.\w+\s*=|(async\s+)?function\s|class\s|interface\s|type\s|func\s|(async\s+)?def\s|pub\s|public\s|package\s|import\s|from\s+\S+\s+import\s|@(app|router)\.|(app|router)\.(get|post|put|patch|delete|use)\(|(?i:create\s+table))`)
+	cssSelectorRegex = regexp.MustCompile(`^\s*[.#:@a-zA-Z][^{]*\{`)
+	htmlHookRegex    = regexp.MustCompile(`\b(id|class)\s*=|<script|<link`)
+)
+
+// dataFileExts are summarised by their content rather than their declarations
+var dataFileExts = map[string]bool{
+	".json": true, ".yaml": true, ".yml": true, ".toml": true, ".mod": true, ".txt": true, ".env": true, ".example": true, ".ini": true, ".cfg": true,
+}
+
+// fileInterface summarises a generated file for the calls that follow it: its declarations,
+// selectors or element hooks, or the start of the file for data files and short files
+func fileInterface(p, content string) string {
+	lines := strings.Split(content, "\n")
+	ext := strings.ToLower(path.Ext(p))
+
+	var matcher *regexp.Regexp
+	switch {
+	case dataFileExts[ext] || len(lines) <= 15:
+	case ext == ".css" || ext == ".scss":
+		matcher = cssSelectorRegex
+	case ext == ".html" || ext == ".htm":
+		matcher = htmlHookRegex
+	default:
+		matcher = declarationRegex
+	}
+
+	summary := lines
+	if matcher != nil {
+		summary = nil
+		for _, line := range lines {
+			if matcher.MatchString(line) {
+				summary = append(summary, strings.TrimRight(line, " \t{"))
+			}
+		}
+		if len(summary) == 0 {
+			summary = lines[:min(len(lines), 15)]
+		}
+	}
+
+	if len(summary) > maxInterfaceLines {
+		summary = append(summary[:maxInterfaceLines], "...")
+	}
+	return strings.Join(summary, "\n")
+}
+
+// fenceFor returns a code fence longer than any backtick run in content
+func fenceFor(content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence
+}
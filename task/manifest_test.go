@@ -0,0 +1,83 @@
+package task
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"ai-studio/orchestrator/config"
+	"ai-studio/orchestrator/llm"
+)
+
+// fileProvider answers per-file prompts from a map of file contents; other files are unknown models
+type fileProvider struct {
+	mu      sync.Mutex
+	files   map[string]string
+	prompts map[string]string
+}
+
+var promptFileRegex = regexp.MustCompile(`Write the COMPLETE content of \*\*(.+?)\*\*`)
+
+func (p *fileProvider) Complete(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	file := promptFileRegex.FindStringSubmatch(req.Prompt)[1]
+	p.mu.Lock()
+	p.prompts[file] = req.Prompt
+	p.mu.Unlock()
+
+	content, ok := p.files[file]
+	if !ok {
+		return nil, llm.ErrModelNotFound
+	}
+	return &llm.Response{Text: "Here it is:\n\n```js\n" + content + "\n```\n", Model: req.Model}, nil
+}
+
+func (p *fileProvider) ListModels() ([]string, error) { return nil, nil }
+func (p *fileProvider) Ping() error                   { return nil }
+
+// TestGenerateFiles tests manifest ordering, interfaces passed to later files and the coverage check
+func TestGenerateFiles(t *testing.T) {
+	provider := &fileProvider{
+		files: map[string]string{
+			"src/math.js":      "export function add(a, b) {\n  return a + b;\n}\n" + strings.Repeat("\n// helper\n", 10) + "const internal = 1;",
+			"src/math.test.js": "import { add } from './math.js';\ntest('add', () => expect(add(1, 2)).toBe(3));",
+		},
+		prompts: make(map[string]string),
+	}
+	m := &Manager{cfg: &config.Config{}, client: provider, extractors: DefaultExtractorChain()}
+
+	var progress []string
+	ctx := WithProgress(context.Background(), func(stage, detail string) { progress = append(progress, detail) })
+
+	manifest := []string{"README.md", "src/math.test.js", "src/math.js", "./src/math.js", "../outside.js", "src/"}
+	run, err := m.generateFiles(ctx, []string{"coder"}, "A math library", manifest, llm.ThinkingNormal, nil)
+	if err != nil {
+		t.Fatalf("generateFiles: %v", err)
+	}
+
+	coverage := run.Coverage
+	if want := []string{"src/math.js", "src/math.test.js", "README.md"}; !reflect.DeepEqual(coverage.Manifest, want) {
+		t.Errorf("manifest = %v, want %v", coverage.Manifest, want)
+	}
+	if !reflect.DeepEqual(coverage.Rejected, []string{"../outside.js", "src/"}) {
+		t.Errorf("rejected = %v", coverage.Rejected)
+	}
+	if coverage.Complete() || !reflect.DeepEqual(coverage.Missing, []string{"README.md"}) {
+		t.Errorf("missing = %v, want [README.md]", coverage.Missing)
+	}
+	if len(run.Files) != 2 || run.Files[0].Content != provider.files["src/math.js"] {
+		t.Errorf("files = %+v", run.Files)
+	}
+
+	// The test file sees the declarations of math.js, not its body
+	testPrompt := provider.prompts["src/math.test.js"]
+	if !strings.Contains(testPrompt, "### src/math.js") || !strings.Contains(testPrompt, "export function add(a, b)") ||
+		strings.Contains(testPrompt, "return a + b") {
+		t.Errorf("test file prompt lacks the interface of math.js:\n%s", testPrompt)
+	}
+	if len(progress) == 0 || !strings.HasPrefix(progress[0], "src/math.js (1/3)") {
+		t.Errorf("progress = %v", progress)
+	}
+}
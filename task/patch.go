@@ -0,0 +1,579 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on the current files sent to the model in patch mode
+const (
+	maxContextFileBytes  = 32 * 1024
+	maxContextTotalBytes = 96 * 1024
+)
+
+// contextSkipDirs are never sent to the model as project context
+var contextSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "__pycache__": true, "dist": true, "build": true, "target": true, "venv": true,
+}
+
+type projectDirKey struct{}
+
+// WithProjectDir returns a context whose code tasks patch the project in dir instead of
+// generating a new one: the model sees the current files and returns only the changes
+func WithProjectDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, projectDirKey{}, dir)
+}
+
+// projectDirFrom returns the project directory attached to ctx ("" if none)
+func projectDirFrom(ctx context.Context) string {
+	dir, _ := ctx.Value(projectDirKey{}).(string)
+	return dir
+}
+
+// ProjectFile is a current project file shown to the model in patch mode
+type ProjectFile struct {
+	Path    string
+	Content string // Without its final newline
+	Omitted bool   // Too large or binary; listed without content
+}
+
+// readProjectFiles loads the text files of a project directory, sorted by path
+// Hidden and dependency directories are skipped; large files are listed without content.
+func readProjectFiles(dir string) ([]ProjectFile, error) {
+	var files []ProjectFile
+	total := 0
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || contextSkipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(d.Name(), patchBackupSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file := ProjectFile{Path: filepath.ToSlash(rel)}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(data) > maxContextFileBytes || total+len(data) > maxContextTotalBytes || strings.ContainsRune(string(data), 0) {
+			file.Omitted = true
+		} else {
+			file.Content = strings.TrimSuffix(string(data), "\n")
+			total += len(data)
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project files: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// PatchReport describes the changes a patch-mode response made to a project directory
+type PatchReport struct {
+	ProjectDir  string                 `json:"project_dir"`
+	Modified    []string               `json:"modified,omitempty"` // Patched with a diff
+	Replaced    []string               `json:"replaced,omitempty"` // Existing files given in full
+	Created     []string               `json:"created,omitempty"`
+	Deleted     []string               `json:"deleted,omitempty"`
+	FailedHunks []FailedHunk           `json:"failed_hunks,omitempty"` // The files they belong to are left unchanged
+	Diagnostics []ExtractionDiagnostic `json:"diagnostics,omitempty"`
+	Diff        string                 `json:"diff,omitempty"`  // Unified diff of every change written
+	Error       string                 `json:"error,omitempty"` // Why the changes could not be written; the project is unchanged
+}
+
+// FailedHunk is a diff hunk that could not be applied
+type FailedHunk struct {
+	Path   string `json:"path"`
+	Header string `json:"header"` // The hunk's @@ line
+	Reason string `json:"reason"`
+	Hunk   string `json:"hunk"`
+}
+
+// Changed counts the files written or deleted
+func (r *PatchReport) Changed() int {
+	return len(r.Modified) + len(r.Replaced) + len(r.Created) + len(r.Deleted)
+}
+
+// filePatch is the unified diff of one file
+type filePatch struct {
+	OldPath string // "" for a new file
+	NewPath string // "" for a deleted file
+	Hunks   []hunk
+}
+
+// hunk is one @@ section of a unified diff
+type hunk struct {
+	Header   string
+	OldStart int      // 1-based; 0 when the header has no line numbers
+	Lines    []string // Each starts with ' ', '-' or '+'
+}
+
+var (
+	hunkHeaderRegex = regexp.MustCompile(`^@@\s*(?:-(\d+)(?:,\d+)?\s+\+\d+(?:,\d+)?)?\s*@@`)
+	diffInfoRegex   = regexp.MustCompile(`(?i)^(diff|patch|udiff)\b`)
+)
+
+// isDiffBlock reports whether a code block holds a unified diff
+func isDiffBlock(b fencedBlock) bool {
+	if diffInfoRegex.MatchString(b.Info) {
+		return true
+	}
+	content := strings.TrimLeft(b.Content, "\r\n")
+	return strings.HasPrefix(content, "--- ") || strings.HasPrefix(content, "diff --git ")
+}
+
+// parseUnifiedDiff parses the file diffs of a diff block
+// Hunk line counts are not trusted (models often get them wrong); a hunk runs until
+// the next hunk or file header. defaultPath names the file when the block has no
+// ---/+++ headers.
+func parseUnifiedDiff(text, defaultPath string) []filePatch {
+	var patches []filePatch
+	var current *filePatch
+	var h *hunk
+
+	flushHunk := func() {
+		if current != nil && h != nil {
+			for len(h.Lines) > 0 && strings.TrimSpace(h.Lines[len(h.Lines)-1]) == "" {
+				h.Lines = h.Lines[:len(h.Lines)-1]
+			}
+			current.Hunks = append(current.Hunks, *h)
+		}
+		h = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if current != nil {
+			patches = append(patches, *current)
+		}
+		current = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "index "):
+			continue
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flushFile()
+			current = &filePatch{OldPath: diffPath(line[4:]), NewPath: diffPath(lines[i+1][4:])}
+			i++
+		case hunkHeaderRegex.MatchString(line):
+			flushHunk()
+			if current == nil {
+				current = &filePatch{OldPath: defaultPath, NewPath: defaultPath}
+			}
+			m := hunkHeaderRegex.FindStringSubmatch(line)
+			h = &hunk{Header: line}
+			if m[1] != "" {
+				h.OldStart, _ = strconv.Atoi(m[1])
+			}
+		case h != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		case h != nil && line == "":
+			h.Lines = append(h.Lines, " ")
+		case h != nil && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			h.Lines = append(h.Lines, line)
+		}
+	}
+	flushFile()
+
+	return patches
+}
+
+// diffPath reads the path of a ---/+++ line, dropping a/ b/ prefixes and timestamps
+func diffPath(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// applyHunks applies a file's hunks to its content, returning the new content and the hunks that failed
+func applyHunks(path, content string, hunks []hunk) (string, []FailedHunk) {
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	lines := []string{}
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var failed []FailedHunk
+	delta := 0
+	for _, h := range hunks {
+		var oldLines, newLines []string
+		for _, l := range h.Lines {
+			switch l[0] {
+			case ' ':
+				oldLines = append(oldLines, l[1:])
+				newLines = append(newLines, l[1:])
+			case '-':
+				oldLines = append(oldLines, l[1:])
+			case '+':
+				newLines = append(newLines, l[1:])
+			}
+		}
+
+		expected := h.OldStart - 1 + delta
+		if h.OldStart == 0 {
+			expected = 0
+		}
+		pos := findLines(lines, oldLines, expected)
+		if pos < 0 {
+			failed = append(failed, FailedHunk{
+				Path:   path,
+				Header: h.Header,
+				Reason: "context and removed lines not found in the current file",
+				Hunk:   strings.Join(h.Lines, "\n"),
+			})
+			continue
+		}
+
+		updated := append([]string{}, lines[:pos]...)
+		updated = append(updated, newLines...)
+		lines = append(updated, lines[pos+len(oldLines):]...)
+		delta += len(newLines) - len(oldLines)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		result += "\n"
+	}
+	return result, failed
+}
+
+// findLines returns where want occurs in lines, preferring the position nearest expected;
+// trailing whitespace is ignored if there is no exact match. It returns -1 if want is absent.
+func findLines(lines, want []string, expected int) int {
+	if len(want) == 0 {
+		if expected < 0 || expected > len(lines) {
+			return len(lines)
+		}
+		return expected
+	}
+
+	exact := func(a, b string) bool { return a == b }
+	loose := func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") }
+
+	for _, equal := range []func(a, b string) bool{exact, loose} {
+		best := -1
+		for pos := 0; pos+len(want) <= len(lines); pos++ {
+			match := true
+			for i := range want {
+				if !equal(lines[pos+i], want[i]) {
+					match = false
+					break
+				}
+			}
+			if match && (best < 0 || abs(pos-expected) < abs(best-expected)) {
+				best = pos
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// applyPatchOutput applies a patch-mode response to the project in dir
+// Diff blocks are applied hunk by hunk; other code blocks replace or create whole files.
+// A file with a failed hunk is left unchanged. Everything else is written as one
+// set: if any write fails, every file is restored.
+func (m *Manager) applyPatchOutput(dir, output string) (*PatchReport, error) {
+	report := &PatchReport{ProjectDir: dir}
+
+	writes := make(map[string]string) // relative path -> new content
+	var deletes []string
+
+	// Unified diffs, and the masked output the whole-file extractors see
+	masked := output
+	blocks := fencedBlocks(output)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		if !isDiffBlock(b) {
+			continue
+		}
+		// Drop the block and its "### path" heading so the extractors don't read either
+		start := b.Start
+		path, loc := precedingHeading(output[:b.Start])
+		if loc >= 0 {
+			start = loc
+		}
+		masked = masked[:start] + masked[b.End:]
+
+		for _, fp := range parseUnifiedDiff(b.Content, path) {
+			m.stageFilePatch(dir, fp, writes, &deletes, report)
+		}
+	}
+
+	// Whole files
+	extraction := m.extractors.Extract(masked)
+	report.Diagnostics = append(report.Diagnostics, extraction.Diagnostics...)
+	for _, f := range extraction.Files {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Path))); err == nil {
+			report.Replaced = append(report.Replaced, f.Path)
+		} else {
+			report.Created = append(report.Created, f.Path)
+		}
+		writes[f.Path] = f.Content
+	}
+
+	diff := patchDiff(dir, writes, deletes)
+	if err := writeFilesAtomically(dir, writes, deletes); err != nil {
+		return report, err
+	}
+	report.Diff = diff
+
+	for _, f := range report.FailedHunks {
+		log.Printf("[WARN] Patch: hunk %s of %s failed: %s", f.Header, f.Path, f.Reason)
+	}
+	return report, nil
+}
+
+// stageFilePatch applies one file's diff in memory, recording the outcome in report
+func (m *Manager) stageFilePatch(dir string, fp filePatch, writes map[string]string, deletes *[]string, report *PatchReport) {
+	target := fp.NewPath
+	if target == "" {
+		target = fp.OldPath
+	}
+	rel, err := SafeRelPath(target)
+	if err != nil {
+		report.Diagnostics = append(report.Diagnostics, ExtractionDiagnostic{Extractor: "diff", Path: target, Message: err.Error()})
+		return
+	}
+
+	if fp.NewPath == "" {
+		*deletes = append(*deletes, rel)
+		report.Deleted = append(report.Deleted, rel)
+		return
+	}
+
+	current := ""
+	if fp.OldPath != "" {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			for _, h := range fp.Hunks {
+				report.FailedHunks = append(report.FailedHunks, FailedHunk{
+					Path:   rel,
+					Header: h.Header,
+					Reason: "file does not exist in the project",
+					Hunk:   strings.Join(h.Lines, "\n"),
+				})
+			}
+			return
+		}
+		current = string(data)
+	}
+
+	updated, failed := applyHunks(rel, current, fp.Hunks)
+	if len(failed) > 0 {
+		report.FailedHunks = append(report.FailedHunks, failed...)
+		return
+	}
+
+	writes[rel] = updated
+	if fp.OldPath == "" {
+		report.Created = append(report.Created, rel)
+	} else {
+		report.Modified = append(report.Modified, rel)
+	}
+}
+
+// precedingHeading finds a "### path" heading on the last non-blank line of text,
+// returning the path and the heading's offset, or "" and -1 if there is none
+func precedingHeading(text string) (string, int) {
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	start := strings.LastIndexByte(trimmed, '\n') + 1
+	m := headingRegex.FindStringSubmatch(trimmed[start:])
+	if m == nil {
+		return "", -1
+	}
+	if path := headingPath(m[1]); path != "" {
+		return path, start
+	}
+	return "", -1
+}
+
+// patchDiff returns the unified diff of a patch set against the files currently in dir
+func patchDiff(dir string, writes map[string]string, deletes []string) string {
+	changes := make(map[string]*string, len(writes)+len(deletes))
+	for p := range writes {
+		content := writes[p]
+		changes[p] = &content
+	}
+	for _, p := range deletes {
+		changes[p] = nil
+	}
+
+	paths := make([]string, 0, len(changes))
+	for p := range changes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var diff strings.Builder
+	for _, p := range paths {
+		before, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		after := ""
+		if changes[p] != nil {
+			after = *changes[p]
+		}
+		diff.WriteString(unifiedDiff(p, string(before), after, err == nil, changes[p] != nil))
+	}
+	return diff.String()
+}
+
+// patchBackupSuffix marks the copies of replaced files kept until a patch set is committed
+const patchBackupSuffix = ".patch-bak"
+
+// writeFilesAtomically writes and deletes files in dir as one set
+// New contents are staged in temporary files first; originals are moved aside and
+// restored if any step fails, then removed once every file is in place.
+func writeFilesAtomically(dir string, writes map[string]string, deletes []string) error {
+	type op struct {
+		target  string
+		temp    string // "" for a delete
+		backup  string // "" if the target didn't exist
+		written bool   // The new content is in place at target
+	}
+	var ops []op
+
+	cleanup := func() {
+		for _, o := range ops {
+			if o.temp != "" {
+				os.Remove(o.temp)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(writes))
+	for p := range writes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// Stage new contents
+	for _, p := range paths {
+		target, err := SafeJoin(dir, p)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to create directory for %s: %w", p, err)
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".patch-*")
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to stage %s: %w", p, err)
+		}
+		_, writeErr := tmp.WriteString(writes[p])
+		closeErr := tmp.Close()
+		ops = append(ops, op{target: target, temp: tmp.Name()})
+		if writeErr != nil || closeErr != nil {
+			cleanup()
+			return fmt.Errorf("failed to stage %s: %v", p, firstErr(writeErr, closeErr))
+		}
+	}
+	for _, p := range deletes {
+		target, err := SafeJoin(dir, p)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		ops = append(ops, op{target: target})
+	}
+
+	// Swap them in, keeping the originals until every file is in place
+	done := 0
+	rollback := func() {
+		for i := done - 1; i >= 0; i-- {
+			o := ops[i]
+			if o.written {
+				os.Remove(o.target) // Includes files the set created
+			}
+			if o.backup != "" {
+				os.Rename(o.backup, o.target)
+			}
+		}
+		cleanup()
+	}
+
+	for i := range ops {
+		o := &ops[i]
+		if _, err := os.Stat(o.target); err == nil {
+			o.backup = o.target + patchBackupSuffix
+			if err := os.Rename(o.target, o.backup); err != nil {
+				o.backup = ""
+				rollback()
+				return fmt.Errorf("failed to replace %s: %w", o.target, err)
+			}
+		}
+		if o.temp != "" {
+			if err := os.Rename(o.temp, o.target); err != nil {
+				done = i + 1
+				rollback()
+				return fmt.Errorf("failed to write %s: %w", o.target, err)
+			}
+			o.temp = ""
+			o.written = true
+		}
+		done = i + 1
+	}
+
+	for _, o := range ops {
+		if o.backup != "" {
+			os.Remove(o.backup)
+		}
+	}
+	return nil
+}
+
+// firstErr returns the first non-nil error
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestApplyPatchOutput tests diffs, whole-file replacements, deletes and that a file with a failed hunk is left unchanged
+func TestApplyPatchOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/app.js": "const a = 1;\nconst b = 2;\n\nfunction main() {\n  console.log(a + b);\n}\n\nmain();\n",
+		"style.css":  "body { margin: 0; }\n",
+		"old.txt":    "remove me\n",
+		"README.md":  "# App\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fence := "```"
+	output := strings.Join([]string{
+		"### src/app.js",
+		fence + "diff",
+		"--- a/src/app.js",
+		"+++ b/src/app.js",
+		"@@ -3,4 +3,4 @@",
+		"",
+		" function main() {",
+		"-  console.log(a + b);",
+		"+  console.log(`sum: ${a + b}`);",
+		" }",
+		fence,
+		"",
+		fence + "diff",
+		"--- a/README.md",
+		"+++ b/README.md",
+		"@@ -1 +1 @@",
+		"-# Wrong title",
+		"+# App v2",
+		"--- a/old.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-remove me",
+		fence,
+		"",
+		"### style.css",
+		fence + "css",
+		"body { margin: 0; padding: 1rem; }",
+		fence,
+		"",
+		"### ../escape.txt",
+		fence,
+		"nope",
+		fence,
+	}, "\n")
+
+	m := &Manager{extractors: DefaultExtractorChain()}
+	report, err := m.applyPatchOutput(dir, output)
+	if err != nil {
+		t.Fatalf("applyPatchOutput: %v", err)
+	}
+
+	if !reflect.DeepEqual(report.Modified, []string{"src/app.js"}) || !reflect.DeepEqual(report.Replaced, []string{"style.css"}) ||
+		!reflect.DeepEqual(report.Deleted, []string{"old.txt"}) || len(report.Created) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.FailedHunks) != 1 || report.FailedHunks[0].Path != "README.md" {
+		t.Errorf("failed hunks = %+v, want one for README.md", report.FailedHunks)
+	}
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].Path != "../escape.txt" {
+		t.Errorf("diagnostics = %+v, want the unsafe path rejected", report.Diagnostics)
+	}
+
+	read := func(path string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		return string(data)
+	}
+	if got := read("src/app.js"); got != strings.Replace(files["src/app.js"], "console.log(a + b)", "console.log(`sum: ${a + b}`)", 1) {
+		t.Errorf("src/app.js = %q", got)
+	}
+	if got := read("README.md"); got != files["README.md"] {
+		t.Errorf("README.md changed despite its failed hunk: %q", got)
+	}
+	if got := read("style.css"); got != "body { margin: 0; padding: 1rem; }" {
+		t.Errorf("style.css = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt was not deleted")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the project")
+	}
+	if !strings.Contains(report.Diff, "--- a/old.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-remove me\n") ||
+		!strings.Contains(report.Diff, "+  console.log(`sum: ${a + b}`);") || strings.Contains(report.Diff, "README.md") {
+		t.Errorf("diff = %s", report.Diff)
+	}
+}
+
+// TestUnifiedDiffRoundTrip tests that generated diffs apply back to the original content
+func TestUnifiedDiffRoundTrip(t *testing.T) {
+	var before, after []string
+	for i := 0; i < 40; i++ {
+		before = append(before, strings.Repeat("x", i%7)+" line")
+		if i%13 == 5 {
+			after = append(after, "changed line")
+		} else if i%17 != 3 {
+			after = append(after, before[i])
+		}
+	}
+	after = append(after, "appended")
+	oldContent, newContent := strings.Join(before, "\n")+"\n", strings.Join(after, "\n")+"\n"
+
+	diff := unifiedDiff("f.txt", oldContent, newContent, true, true)
+	patches := parseUnifiedDiff(diff, "")
+	if len(patches) != 1 || len(patches[0].Hunks) < 2 {
+		t.Fatalf("diff parsed into %+v:\n%s", patches, diff)
+	}
+	got, failed := applyHunks("f.txt", oldContent, patches[0].Hunks)
+	if len(failed) != 0 || got != newContent {
+		t.Errorf("round trip = %q, failed %+v\ndiff:\n%s", got, failed, diff)
+	}
+}
+
+// TestApplyHunksOffset tests that hunks apply when their line numbers are off
+func TestApplyHunksOffset(t *testing.T) {
+	content := "a\nb\nc\nd\ne\nf\n"
+	hunks := []hunk{
+		{Header: "@@ -1,2 +1,2 @@", OldStart: 1, Lines: []string{"-a", "+A", " b"}},
+		{Header: "@@ -9,2 +9,3 @@", OldStart: 9, Lines: []string{" e", "+e2", " f"}},
+	}
+	got, failed := applyHunks("x", content, hunks)
+	if len(failed) != 0 || got != "A\nb\nc\nd\ne\ne2\nf\n" {
+		t.Errorf("got %q, failed %+v", got, failed)
+	}
+}
+
+// TestWriteFilesAtomicallyRollback tests that a failure partway through the set leaves the directory unchanged
+func TestWriteFilesAtomicallyRollback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "x.txt.patch-bak", "blocker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "x.txt"), []byte("original\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := snapshotDir(t, dir)
+
+	// a.txt is new and written first; backing up sub/x.txt then fails on the non-empty directory in the way
+	err := writeFilesAtomically(dir, map[string]string{"a.txt": "new\n", "sub/x.txt": "changed\n"}, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if after := snapshotDir(t, dir); !reflect.DeepEqual(before, after) {
+		t.Errorf("directory changed after a failed write:\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+// snapshotDir maps every file and directory under dir to its content
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	snapshot := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if info.IsDir() {
+			snapshot[rel] = "<dir>"
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		snapshot[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
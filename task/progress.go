@@ -0,0 +1,20 @@
+package task
+
+import "context"
+
+// ProgressFunc receives the stages a task moves through, e.g. ("generating", "llama3:8b")
+type ProgressFunc func(stage, detail string)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose task execution reports its stages to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress tells the ProgressFunc attached to ctx (if any) that a task entered stage
+func ReportProgress(ctx context.Context, stage, detail string) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(stage, detail)
+	}
+}
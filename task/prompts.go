@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// builtinPrompts holds the default prompt of each built-in task type, as prompts/<task_type>.tmpl
+//
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// PromptData is what a prompt template is executed with
+type PromptData struct {
+	TaskType string
+	Input    string
+	Vars     map[string]string // Task type vars from config, overridden by vars sent with the request
+	Files    []ProjectFile     // Current project files, for code tasks patching an existing project
+
+	// Per-file code tasks
+	File       string          // The file to write
+	Manifest   []string        // Every file of the plan
+	Interfaces []FileInterface // Public interfaces of the files written so far
+}
+
+type promptVarsKey struct{}
+
+// WithPromptVars returns a context whose tasks render their prompt templates with vars
+func WithPromptVars(ctx context.Context, vars map[string]string) context.Context {
+	if len(vars) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, promptVarsKey{}, vars)
+}
+
+// buildPrompt renders the prompt template of a task type
+// A code task given a project directory uses the code_patch template instead, with the current files.
+func (m *Manager) buildPrompt(ctx context.Context, taskType, input string) (string, error) {
+	templateName := taskType
+	data := PromptData{TaskType: taskType, Input: input}
+	if dir := projectDirFrom(ctx); dir != "" && taskType == "code" {
+		templateName = "code_patch"
+		files, err := readProjectFiles(dir)
+		if err != nil {
+			return "", err
+		}
+		data.Files = files
+	}
+	return m.renderPrompt(ctx, templateName, data)
+}
+
+// renderPrompt executes a prompt template with data, adding the vars of data's task type
+func (m *Manager) renderPrompt(ctx context.Context, templateName string, data PromptData) (string, error) {
+	text, source, err := m.promptTemplate(templateName)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(templateName).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template %s: %w", source, err)
+	}
+
+	vars := make(map[string]string)
+	for name, value := range m.cfg.TaskTypes[data.TaskType].Vars {
+		vars[name] = value
+	}
+	if requestVars, ok := ctx.Value(promptVarsKey{}).(map[string]string); ok {
+		for name, value := range requestVars {
+			vars[name] = value
+		}
+	}
+	data.Vars = vars
+
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", source, err)
+	}
+	return prompt.String(), nil
+}
+
+// promptTemplate returns the template text of a task type and where it came from:
+// the task type's configured template file, <prompts_dir>/<task_type>.tmpl, or the built-in prompt
+// Templates are read on every task, so edits apply without a restart.
+func (m *Manager) promptTemplate(taskType string) (string, string, error) {
+	if path := m.cfg.TaskTypes[taskType].Template; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read prompt template for %s: %w", taskType, err)
+		}
+		return trimTemplate(data), path, nil
+	}
+
+	if m.cfg.PromptsDir != "" {
+		path := filepath.Join(m.cfg.PromptsDir, taskType+".tmpl")
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return trimTemplate(data), path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", fmt.Errorf("failed to read prompt template for %s: %w", taskType, err)
+		}
+	}
+
+	data, err := builtinPrompts.ReadFile("prompts/" + taskType + ".tmpl")
+	if err != nil {
+		return "", "", fmt.Errorf("unknown task type: %s (no prompt template)", taskType)
+	}
+	return trimTemplate(data), "built-in " + taskType, nil
+}
+
+// trimTemplate drops the newline that ends a template file
+func trimTemplate(data []byte) string {
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+}
@@ -0,0 +1,45 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-studio/orchestrator/config"
+)
+
+// TestBuildPromptTemplates tests built-in, overridden and user-defined prompt templates
+func TestBuildPromptTemplates(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "review.tmpl"), []byte("Review carefully:\n{{.Input}}\n"), 0644)
+	apiDesign := filepath.Join(dir, "api-design.tmpl")
+	os.WriteFile(apiDesign, []byte("Design a {{.Vars.style}} API for {{.Vars.team}}: {{.Input}}"), 0644)
+
+	m := &Manager{cfg: &config.Config{
+		Models:     map[string]string{"code": "deepseek-coder:6.7b-instruct", "review": "llama3:8b"},
+		PromptsDir: dir,
+		TaskTypes: map[string]config.TaskTypeConfig{
+			"api-design": {Model: "llama3:8b", Template: apiDesign, Vars: map[string]string{"style": "REST", "team": "billing"}},
+		},
+	}}
+
+	prompt, err := m.buildPrompt(context.Background(), "code", "Snake game")
+	if err != nil || !strings.Contains(prompt, "Project Request:\nSnake game\n") {
+		t.Errorf("built-in code prompt = %q, %v", prompt, err)
+	}
+
+	if prompt, _ := m.buildPrompt(context.Background(), "review", "main.go"); prompt != "Review carefully:\nmain.go" {
+		t.Errorf("overridden review prompt = %q", prompt)
+	}
+
+	ctx := WithPromptVars(context.Background(), map[string]string{"style": "gRPC"})
+	if prompt, _ := m.buildPrompt(ctx, "api-design", "invoices"); prompt != "Design a gRPC API for billing: invoices" {
+		t.Errorf("api-design prompt = %q", prompt)
+	}
+
+	if _, err := m.buildPrompt(context.Background(), "migration-plan", "x"); err == nil {
+		t.Errorf("a task type without a template should fail")
+	}
+}
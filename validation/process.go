@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// CommandContext creates a command that runs in its own process group.
+// When ctx is cancelled or times out the whole process tree is killed,
+// not just the direct child (npm and go run spawn grandchildren that would
+// otherwise keep running and holding ports).
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return KillProcessTree(cmd)
+	}
+	// Don't block forever on pipes held open by orphaned grandchildren
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+// KillProcessTree kills a started command and all of its children
+func KillProcessTree(cmd *exec.Cmd) error {
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+	return killProcessGroup(cmd)
+}
+
+// StopProcess kills a long-running command started with CommandContext and reaps it
+func StopProcess(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	KillProcessTree(cmd)
+	cmd.Wait()
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
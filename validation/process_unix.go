@@ -0,0 +1,22 @@
+//go:build !windows
+
+package validation
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command as the leader of a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup sends SIGKILL to the command's whole process group
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		// Fall back to the direct child if the group is already gone
+		return cmd.Process.Kill()
+	}
+	return nil
+}
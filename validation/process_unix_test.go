@@ -0,0 +1,73 @@
+//go:build !windows
+
+package validation
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestCommandContextKillsProcessGroup tests that cancelling a command kills its grandchildren too
+func TestCommandContextKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell prints the PID of a grandchild that outlives a kill of the shell alone
+	cmd := CommandContext(ctx, "sh", "-c", "sleep 60 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	sleepPID, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("bad PID %q", line)
+	}
+
+	start := time.Now()
+	cancel()
+	cmd.Wait()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Wait took %v after cancel", elapsed)
+	}
+
+	// Without an init that reaps orphans the grandchild may stay a zombie, which is dead too
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if !alive(sleepPID) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("grandchild %d still running after cancel", sleepPID)
+		}
+	}
+	// The group held only the shell and the sleep; anything left in it is a zombie
+	if err := syscall.Kill(-cmd.Process.Pid, 0); err == nil && alive(cmd.Process.Pid) {
+		t.Error("process group still has live members")
+	}
+}
+
+// alive reports whether pid is a running (not zombie) process
+func alive(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		// No procfs (macOS): fall back to signal 0, which also succeeds for zombies
+		return syscall.Kill(pid, 0) == nil
+	}
+	// The state follows the parenthesized command name
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	return len(fields) > 0 && fields[0] != "Z"
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -36,7 +37,8 @@ type RuntimeResult struct {
 }
 
 // ValidateRuntime validates that a project runs without crashing
-func (rv *RuntimeValidator) ValidateRuntime(projectPath string, projectType string) (*RuntimeResult, error) {
+// Cancelling ctx stops the server process tree and returns ctx.Err()
+func (rv *RuntimeValidator) ValidateRuntime(ctx context.Context, projectPath string, projectType string) (*RuntimeResult, error) {
 	result := &RuntimeResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -44,11 +46,11 @@ func (rv *RuntimeValidator) ValidateRuntime(projectPath string, projectType stri
 
 	switch projectType {
 	case "nodejs", "vite":
-		return rv.validateNodeJS(projectPath)
+		return rv.validateNodeJS(ctx, projectPath)
 	case "python":
-		return rv.validatePython(projectPath)
+		return rv.validatePython(ctx, projectPath)
 	case "go":
-		return rv.validateGo(projectPath)
+		return rv.validateGo(ctx, projectPath)
 	case "frontend":
 		return rv.validateFrontend(projectPath)
 	default:
@@ -58,7 +60,7 @@ func (rv *RuntimeValidator) ValidateRuntime(projectPath string, projectType stri
 }
 
 // validateNodeJS validates Node.js runtime
-func (rv *RuntimeValidator) validateNodeJS(projectPath string) (*RuntimeResult, error) {
+func (rv *RuntimeValidator) validateNodeJS(ctx context.Context, projectPath string) (*RuntimeResult, error) {
 	result := &RuntimeResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -104,10 +106,10 @@ func (rv *RuntimeValidator) validateNodeJS(projectPath string) (*RuntimeResult,
 	if isVite {
 		// Vite dev server
 		result.Port = 5173 // Vite default port
-		cmd = exec.Command("npm", "run", "dev")
+		cmd = CommandContext(ctx, "npm", "run", "dev")
 	} else {
 		// Traditional Node.js server
-		cmd = exec.Command("node", "server.js")
+		cmd = CommandContext(ctx, "node", "server.js")
 	}
 	cmd.Dir = projectPath
 
@@ -156,6 +158,9 @@ func (rv *RuntimeValidator) validateNodeJS(projectPath string) (*RuntimeResult,
 		select {
 		case <-ready:
 			// Vite is ready
+		case <-ctx.Done():
+			StopProcess(cmd)
+			return result, ctx.Err()
 		case <-time.After(10 * time.Second):
 			result.Warnings = append(result.Warnings, "Vite server did not report ready status within 10 seconds")
 		}
@@ -174,7 +179,10 @@ func (rv *RuntimeValidator) validateNodeJS(projectPath string) (*RuntimeResult,
 		result.ApplicationStarts = true
 
 		// Wait for server to start
-		time.Sleep(3 * time.Second)
+		if err := Sleep(ctx, 3*time.Second); err != nil {
+			StopProcess(cmd)
+			return result, err
+		}
 	}
 
 	// Check if process is still running
@@ -210,17 +218,15 @@ func (rv *RuntimeValidator) validateNodeJS(projectPath string) (*RuntimeResult,
 		result.Warnings = append(result.Warnings, "Server started but health check failed")
 	}
 
-	// Kill the server
-	if cmd.Process != nil {
-		cmd.Process.Kill()
-	}
+	// Kill the server (and anything it spawned)
+	StopProcess(cmd)
 
 	result.RuntimeLog = outputBuilder.String()
 	return result, nil
 }
 
 // validatePython validates Python runtime
-func (rv *RuntimeValidator) validatePython(projectPath string) (*RuntimeResult, error) {
+func (rv *RuntimeValidator) validatePython(ctx context.Context, projectPath string) (*RuntimeResult, error) {
 	result := &RuntimeResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -250,7 +256,7 @@ func (rv *RuntimeValidator) validatePython(projectPath string) (*RuntimeResult,
 	}
 
 	// Start the server
-	cmd := exec.Command("python", entryPoint)
+	cmd := CommandContext(ctx, "python", entryPoint)
 	cmd.Dir = workDir
 
 	// Capture output
@@ -268,7 +274,10 @@ func (rv *RuntimeValidator) validatePython(projectPath string) (*RuntimeResult,
 	result.ApplicationStarts = true
 
 	// Wait for server to start
-	time.Sleep(3 * time.Second)
+	if err := Sleep(ctx, 3*time.Second); err != nil {
+		StopProcess(cmd)
+		return result, err
+	}
 
 	// Check if process is still running
 	if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
@@ -299,17 +308,15 @@ func (rv *RuntimeValidator) validatePython(projectPath string) (*RuntimeResult,
 
 	result.HealthCheckPassed = healthCheckPassed
 
-	// Kill the server
-	if cmd.Process != nil {
-		cmd.Process.Kill()
-	}
+	// Kill the server (and anything it spawned)
+	StopProcess(cmd)
 
 	result.RuntimeLog = outputBuilder.String()
 	return result, nil
 }
 
 // validateGo validates Go runtime
-func (rv *RuntimeValidator) validateGo(projectPath string) (*RuntimeResult, error) {
+func (rv *RuntimeValidator) validateGo(ctx context.Context, projectPath string) (*RuntimeResult, error) {
 	result := &RuntimeResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -328,7 +335,7 @@ func (rv *RuntimeValidator) validateGo(projectPath string) (*RuntimeResult, erro
 	}
 
 	// Start the server
-	cmd := exec.Command("go", "run", ".")
+	cmd := CommandContext(ctx, "go", "run", ".")
 	cmd.Dir = workDir
 
 	// Capture output
@@ -346,7 +353,10 @@ func (rv *RuntimeValidator) validateGo(projectPath string) (*RuntimeResult, erro
 	result.ApplicationStarts = true
 
 	// Wait for server to start
-	time.Sleep(3 * time.Second)
+	if err := Sleep(ctx, 3*time.Second); err != nil {
+		StopProcess(cmd)
+		return result, err
+	}
 
 	// Check if process is still running
 	if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
@@ -377,10 +387,8 @@ func (rv *RuntimeValidator) validateGo(projectPath string) (*RuntimeResult, erro
 
 	result.HealthCheckPassed = healthCheckPassed
 
-	// Kill the server
-	if cmd.Process != nil {
-		cmd.Process.Kill()
-	}
+	// Kill the server (and anything it spawned)
+	StopProcess(cmd)
 
 	result.RuntimeLog = outputBuilder.String()
 	return result, nil
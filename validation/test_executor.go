@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -40,7 +39,8 @@ type TestResult struct {
 }
 
 // ExecuteTests runs tests and returns parsed results
-func (te *TestExecutor) ExecuteTests(projectPath string, projectType string) (*TestResult, error) {
+// Cancelling ctx kills the running test process tree
+func (te *TestExecutor) ExecuteTests(ctx context.Context, projectPath string, projectType string) (*TestResult, error) {
 	result := &TestResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -48,11 +48,11 @@ func (te *TestExecutor) ExecuteTests(projectPath string, projectType string) (*T
 
 	switch projectType {
 	case "nodejs":
-		return te.executeNodeJSTests(projectPath)
+		return te.executeNodeJSTests(ctx, projectPath)
 	case "python":
-		return te.executePythonTests(projectPath)
+		return te.executePythonTests(ctx, projectPath)
 	case "go":
-		return te.executeGoTests(projectPath)
+		return te.executeGoTests(ctx, projectPath)
 	case "frontend":
 		result.Warnings = append(result.Warnings, "Frontend projects typically don't have backend tests")
 		return result, nil
@@ -63,7 +63,7 @@ func (te *TestExecutor) ExecuteTests(projectPath string, projectType string) (*T
 }
 
 // executeNodeJSTests runs Node.js tests
-func (te *TestExecutor) executeNodeJSTests(projectPath string) (*TestResult, error) {
+func (te *TestExecutor) executeNodeJSTests(parent context.Context, projectPath string) (*TestResult, error) {
 	result := &TestResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
@@ -125,10 +125,10 @@ func (te *TestExecutor) executeNodeJSTests(projectPath string) (*TestResult, err
 	result.TestFramework = framework
 
 	// Run npm test
-	ctx, cancel := context.WithTimeout(context.Background(), te.timeout)
+	ctx, cancel := context.WithTimeout(parent, te.timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "npm", "test")
+	cmd := CommandContext(ctx, "npm", "test")
 	cmd.Dir = projectPath
 
 	var outputBuf bytes.Buffer
@@ -159,6 +159,9 @@ func (te *TestExecutor) executeNodeJSTests(projectPath string) (*TestResult, err
 	}
 
 	// If command failed due to test failures, that's not an error (tests just failed)
+	if parent.Err() != nil {
+		return result, parent.Err()
+	}
 	if err != nil && ctx.Err() == context.DeadlineExceeded {
 		result.Errors = append(result.Errors, "Test execution timed out after 30 seconds")
 	} else if err != nil && result.TotalTests == 0 {
@@ -169,18 +172,18 @@ func (te *TestExecutor) executeNodeJSTests(projectPath string) (*TestResult, err
 }
 
 // executePythonTests runs Python tests
-func (te *TestExecutor) executePythonTests(projectPath string) (*TestResult, error) {
+func (te *TestExecutor) executePythonTests(parent context.Context, projectPath string) (*TestResult, error) {
 	result := &TestResult{
 		Errors:   make([]string, 0),
 		Warnings: make([]string, 0),
 	}
 
 	// Check for pytest
-	ctx, cancel := context.WithTimeout(context.Background(), te.timeout)
+	ctx, cancel := context.WithTimeout(parent, te.timeout)
 	defer cancel()
 
 	// Try pytest first
-	cmd := exec.CommandContext(ctx, "pytest", "--tb=short", "-v")
+	cmd := CommandContext(ctx, "pytest", "--tb=short", "-v")
 	cmd.Dir = projectPath
 
 	var outputBuf bytes.Buffer
@@ -193,7 +196,7 @@ func (te *TestExecutor) executePythonTests(projectPath string) (*TestResult, err
 
 	if err != nil && strings.Contains(output, "no tests ran") {
 		// Try unittest instead
-		cmd = exec.CommandContext(ctx, "python", "-m", "unittest", "discover", "-v")
+		cmd = CommandContext(ctx, "python", "-m", "unittest", "discover", "-v")
 		cmd.Dir = projectPath
 
 		outputBuf.Reset()
@@ -217,6 +220,9 @@ func (te *TestExecutor) executePythonTests(projectPath string) (*TestResult, err
 		result.Warnings = append(result.Warnings, "Tests ran but no test results detected")
 	}
 
+	if parent.Err() != nil {
+		return result, parent.Err()
+	}
 	if err != nil && ctx.Err() == context.DeadlineExceeded {
 		result.Errors = append(result.Errors, "Test execution timed out after 30 seconds")
 	} else if err != nil && result.TotalTests == 0 {
@@ -227,7 +233,7 @@ func (te *TestExecutor) executePythonTests(projectPath string) (*TestResult, err
 }
 
 // executeGoTests runs Go tests
-func (te *TestExecutor) executeGoTests(projectPath string) (*TestResult, error) {
+func (te *TestExecutor) executeGoTests(parent context.Context, projectPath string) (*TestResult, error) {
 	result := &TestResult{
 		Errors:       make([]string, 0),
 		Warnings:     make([]string, 0),
@@ -246,10 +252,10 @@ func (te *TestExecutor) executeGoTests(projectPath string) (*TestResult, error)
 	}
 
 	// Run go test
-	ctx, cancel := context.WithTimeout(context.Background(), te.timeout)
+	ctx, cancel := context.WithTimeout(parent, te.timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "go", "test", "-v", "./...")
+	cmd := CommandContext(ctx, "go", "test", "-v", "./...")
 	cmd.Dir = workDir
 
 	var outputBuf bytes.Buffer
@@ -270,6 +276,9 @@ func (te *TestExecutor) executeGoTests(projectPath string) (*TestResult, error)
 		result.Warnings = append(result.Warnings, "Tests ran but no test results detected")
 	}
 
+	if parent.Err() != nil {
+		return result, parent.Err()
+	}
 	if err != nil && ctx.Err() == context.DeadlineExceeded {
 		result.Errors = append(result.Errors, "Test execution timed out after 30 seconds")
 	} else if err != nil && result.TotalTests == 0 {
@@ -77,6 +77,17 @@ func (h *Hub) Broadcast(event *Event) {
 	h.broadcast <- event
 }
 
+// Publish broadcasts an event tagged with a project and phase
+func (h *Hub) Publish(eventType, projectID, phase, data string) {
+	h.Broadcast(&Event{
+		Type:      eventType,
+		ProjectID: projectID,
+		Phase:     phase,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
 // BroadcastJSON broadcasts a JSON-serializable object with the given event type
 func (h *Hub) BroadcastJSON(eventType string, data interface{}) {
 	jsonData, err := json.Marshal(data)
@@ -161,9 +172,10 @@ func (c *Client) writePump() {
 
 // Event represents a WebSocket event
 type Event struct {
-	Type      string    `json:"type"`
-	ProjectID string    `json:"project_id,omitempty"`
-	Phase     string    `json:"phase,omitempty"`
-	Data      string    `json:"data"`
-	Timestamp time.Time `json:"timestamp"`
+	Type           string    `json:"type"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	Phase          string    `json:"phase,omitempty"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Data           string    `json:"data"`
+	Timestamp      time.Time `json:"timestamp"`
 }